@@ -3,14 +3,15 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"log"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"github.com/spelens-gud/assert"
 	"github.com/spelens-gud/logger"
+	"github.com/spelens-gud/trunk/internal/config"
+	"github.com/spelens-gud/trunk/internal/registry"
+	"github.com/spelens-gud/trunk/internal/server"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -20,6 +21,12 @@ var (
 	centerConfigFile string
 	// centerViper Center 服务专用的 viper 实例
 	centerViper *viper.Viper
+	// centerConfigCenter Center 服务的动态配置, 服务通过 config.OnChange 订阅配置变化
+	centerConfigCenter *config.Manager
+	// centerRegistry Center 服务的注册中心, 配置文件中没有 Registry 段时为空
+	centerRegistry registry.Registry
+	// centerDrainer Center 服务的优雅下线, 服务端启动后加入
+	centerDrainer *server.Drainer
 )
 
 var centerCmd = &cobra.Command{
@@ -41,6 +48,27 @@ var centerCmd = &cobra.Command{
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
+		// 按 Registry 配置段创建注册中心
+		centerRegistry = assert.MustCall0RE(func() (registry.Registry, error) {
+			return createRegistry(centerViper, log)
+		}, "创建注册中心失败")
+		defer assert.MayTrue(centerRegistry != nil, func() {
+			centerRegistry.Close()
+		})
+
+		// 启动动态配置, 叠加注册中心中的远程配置
+		centerConfigCenter = assert.MustCall0RE(func() (*config.Manager, error) {
+			return startConfigCenter(ctx, centerViper, log, centerRegistry)
+		}, "启动动态配置失败")
+
+		// 创建优雅下线, 收到 SIGTERM 后标记为下线中并等待会话结束
+		centerDrainer = assert.MustCall0RE(func() (*server.Drainer, error) {
+			return newDrainer(centerViper, log, centerRegistry, registry.KindCenter, "grpc")
+		}, "创建优雅下线失败")
+
+		// 发布注册记录, 服务端启动后改为 serving; 没有注册中心时跳过
+		centerDrainer.Publish(registry.StateStarting)
+
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
@@ -49,6 +77,7 @@ var centerCmd = &cobra.Command{
 			// TODO
 			// 1. 初始化服务
 			// 2. 启动服务
+			// 3. centerDrainer.AddServer 加入服务端, centerDrainer.Publish(registry.StateServing) 标记为可用
 		}()
 
 		select {
@@ -59,7 +88,7 @@ var centerCmd = &cobra.Command{
 			log.Infof("客户端上下文已取消")
 		}
 
-		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), centerDrainer.Timeout())
 		defer shutdownCancel()
 
 		assert.ShouldCall1E(gracefulShutdownCenterServer, shutdownCtx, "客户端关闭失败")
@@ -101,17 +130,7 @@ func initCenterConfig() error {
 	return nil
 }
 
-// gracefulShutdownCenterServer 优雅关闭中心服
+// gracefulShutdownCenterServer 优雅下线 Center 服务
 func gracefulShutdownCenterServer(ctx context.Context) error {
-	log.Println("开始优雅关闭客户端...")
-
-	// TODO: 实现客户端关闭资源释放
-
-	select {
-	case <-time.After(1 * time.Second):
-		log.Println("客户端关闭完成")
-		return nil
-	case <-ctx.Done():
-		return ctx.Err()
-	}
+	return centerDrainer.Drain(ctx)
 }
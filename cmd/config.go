@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/spelens-gud/logger"
+	"github.com/spelens-gud/trunk/internal/config"
+	"github.com/spelens-gud/trunk/internal/registry"
+	"github.com/spelens-gud/trunk/internal/server"
+	"github.com/spf13/viper"
+)
+
+// createRegistry 按 Registry 配置段中的 type 创建注册中心, 没有 Registry 段时返回空
+func createRegistry(v *viper.Viper, log logger.ILogger) (registry.Registry, error) {
+	if !v.IsSet("Registry") {
+		return nil, nil
+	}
+	return registry.NewRegistryFactory(log).CreateFromViper(v, "Registry")
+}
+
+// startConfigCenter 启动动态配置, 叠加 ConfigCenter.files 中的本地文件, reg 不为空时叠加注册中心中 ConfigCenter.key 下的配置
+//
+// 服务通过 config.OnChange 订阅配置变化, 如限流参数、日志级别
+func startConfigCenter(ctx context.Context, v *viper.Viper, log logger.ILogger, reg registry.Registry) (*config.Manager, error) {
+	var sources []config.Source
+	for _, file := range v.GetStringSlice("ConfigCenter.files") {
+		sources = append(sources, config.NewFileSource(file, log))
+	}
+
+	if key := v.GetString("ConfigCenter.key"); reg != nil && key != "" {
+		source, err := registry.NewConfigSource(reg, key)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+
+	m := config.NewManager(v, log, sources...)
+	if err := m.Start(ctx); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// newDrainer 按 Drain 配置段创建优雅下线协调器, 有注册中心且配置了 Servers 时以第一个服务的地址创建注册记录
+//
+// 启动时以 starting 发布注册记录, 服务端启动后通过 AddServer 加入并 Publish(registry.StateServing), 收到 SIGTERM 后由 Drain 下线
+func newDrainer(v *viper.Viper, log logger.ILogger, reg registry.Registry, kind registry.ServiceKind, protocol string) (*server.Drainer, error) {
+	drainConfig := &server.DrainConfig{}
+	if err := v.UnmarshalKey("Drain", drainConfig, func(dc *mapstructure.DecoderConfig) {
+		dc.TagName = "yaml"
+	}); err != nil {
+		return nil, fmt.Errorf("解析优雅下线配置失败: %w", err)
+	}
+
+	var registration *registry.Registration
+	if ip, port := v.GetString("Servers.0.ip"), v.GetInt("Servers.0.port"); reg != nil && port > 0 {
+		id := fmt.Sprintf("%s-%s-%d", kind, ip, port)
+		registration = registry.NewRegistration(kind, id, ip, registry.Transport{Protocol: protocol, Port: port})
+	}
+
+	return server.NewDrainer(drainConfig, reg, registration, log)
+}
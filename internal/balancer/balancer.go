@@ -0,0 +1,366 @@
+package balancer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spelens-gud/assert"
+	"github.com/spelens-gud/logger"
+	"github.com/spelens-gud/trunk/internal/registry"
+)
+
+var (
+	// ErrNoInstance 没有可用的服务实例
+	ErrNoInstance = errors.New("没有可用的服务实例")
+	// ErrClosed 负载均衡器已关闭
+	ErrClosed = errors.New("负载均衡器已关闭")
+	// ErrUnsubscribed 服务的订阅已结束, 下次选择时重新订阅
+	ErrUnsubscribed = errors.New("服务订阅已结束")
+)
+
+// Result 选择结果
+type Result struct {
+	Instance registry.ServiceInstance // 选中的实例
+	done     func(err error)
+}
+
+// Done 请求结束时调用, err 不为空时计为一次失败; 最少连接策略依赖该调用统计进行中的请求数
+func (r Result) Done(err error) {
+	if r.done != nil {
+		r.done(err)
+	}
+}
+
+// defaultBalancer 默认负载均衡器
+var defaultBalancer atomic.Pointer[Balancer]
+
+// SetDefault 设置默认负载均衡器
+func SetDefault(b *Balancer) {
+	defaultBalancer.Store(b)
+}
+
+// Pick 使用默认负载均衡器选择服务实例
+func Pick(ctx context.Context, name, key string) (Result, error) {
+	b := defaultBalancer.Load()
+	if b == nil {
+		return Result{}, ErrClosed
+	}
+	return b.Pick(ctx, name, key)
+}
+
+// Balancer 客户端负载均衡, 基于注册中心的订阅实时更新实例列表
+type Balancer struct {
+	discovery registry.Discovery
+	cnf       *Config
+	log       logger.ILogger
+	services  map[string]*service
+	mu        sync.Mutex
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+// service 单个服务的实例和选择算法
+type service struct {
+	name       string
+	picker     Picker
+	instances  []registry.ServiceInstance // 注册中心的全部实例
+	endpoints  map[string]*Endpoint       // 实例标识 -> 实例, 保留进行中的请求数
+	outliers   map[string]*outlier        // 实例标识 -> 失败记录
+	available  int                        // 可选实例数
+	nextExpiry time.Time                  // 最早的剔除到期时间
+	ready      chan struct{}              // 收到第一次实例列表后关闭
+	done       chan struct{}              // 订阅失败或结束后关闭
+	err        error                      // 订阅失败或结束的原因, done 关闭后可读
+	mu         sync.RWMutex
+}
+
+// outlier 实例的失败记录
+type outlier struct {
+	failures     int       // 连续失败次数
+	ejectedUntil time.Time // 剔除到期时间
+}
+
+// NewBalancer 创建负载均衡器
+func NewBalancer(discovery registry.Discovery, cnf *Config, log logger.ILogger) (*Balancer, error) {
+	if err := cnf.Validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Balancer{
+		discovery: discovery,
+		cnf:       cnf,
+		log:       log,
+		services:  make(map[string]*service),
+		ctx:       ctx,
+		cancel:    cancel,
+	}, nil
+}
+
+// Pick 为 key 选择服务实例, 首次选择某个服务时订阅其实例变化
+//
+// key 仅用于一致性哈希(如玩家ID), 其他策略忽略
+func (b *Balancer) Pick(ctx context.Context, name, key string) (Result, error) {
+	svc, err := b.getService(name)
+	if err != nil {
+		return Result{}, err
+	}
+
+	select {
+	case <-svc.ready:
+	case <-svc.done:
+		return Result{}, svc.err
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	case <-b.ctx.Done():
+		return Result{}, ErrClosed
+	}
+
+	endpoint, instance := svc.pick(key)
+	if endpoint == nil {
+		return Result{}, fmt.Errorf("%w: %s", ErrNoInstance, name)
+	}
+
+	var once sync.Once
+	id := endpointID(instance)
+	return Result{
+		Instance: instance,
+		done: func(err error) {
+			once.Do(func() {
+				endpoint.active.Add(-1)
+				if err != nil {
+					b.MarkFailed(name, id)
+				} else {
+					svc.markSucceeded(id)
+				}
+			})
+		},
+	}, nil
+}
+
+// MarkFailed 记录实例失败, 连续失败达到阈值后在剔除时长内不再选择该实例
+func (b *Balancer) MarkFailed(name, id string) {
+	b.mu.Lock()
+	svc, ok := b.services[name]
+	b.mu.Unlock()
+
+	if ok && svc.markFailed(b.cnf, id) {
+		b.log.Warnf("剔除服务实例，服务: %s, 实例: %s, 时长: %v", name, id, b.cnf.GetEjectionTime())
+	}
+}
+
+// Close 关闭负载均衡器, 停止全部订阅
+func (b *Balancer) Close() {
+	b.cancel()
+}
+
+// getService 获取服务, 不存在时订阅
+//
+// 订阅在锁外进行, 不阻塞其他服务的选择; 同一服务的并发调用共用一次订阅
+func (b *Balancer) getService(name string) (*service, error) {
+	b.mu.Lock()
+	if b.ctx.Err() != nil {
+		b.mu.Unlock()
+		return nil, ErrClosed
+	}
+	if svc, ok := b.services[name]; ok {
+		b.mu.Unlock()
+		return svc, nil
+	}
+
+	svc := &service{
+		name:      name,
+		picker:    newPicker(b.cnf.GetStrategy(name), b.cnf.GetReplicas()),
+		endpoints: make(map[string]*Endpoint),
+		outliers:  make(map[string]*outlier),
+		ready:     make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	b.services[name] = svc
+	b.mu.Unlock()
+
+	ch, err := b.discovery.Subscribe(b.ctx, name)
+	if err != nil {
+		b.evict(svc, fmt.Errorf("订阅服务失败: %w", err))
+		return nil, svc.err
+	}
+
+	go logger.WithRecover(b.log, func() {
+		first := true
+		for instances := range ch {
+			svc.update(instances)
+			assert.MayTrue(first, func() {
+				close(svc.ready)
+			})
+			first = false
+		}
+
+		// 订阅结束(如 etcd 压缩、consul 查询失败), 移除服务, 下次选择时重新订阅
+		err := fmt.Errorf("%w: %s", ErrUnsubscribed, name)
+		if b.ctx.Err() != nil {
+			err = ErrClosed
+		}
+		b.evict(svc, err)
+		b.log.Infof("停止订阅服务: %s", name)
+	})
+
+	return svc, nil
+}
+
+// evict 移除服务并通知等待中的选择
+func (b *Balancer) evict(svc *service, err error) {
+	b.mu.Lock()
+	if b.services[svc.name] == svc {
+		delete(b.services, svc.name)
+	}
+	b.mu.Unlock()
+
+	svc.err = err
+	close(svc.done)
+}
+
+// update 更新实例列表
+func (s *service) update(instances []registry.ServiceInstance) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.instances = instances
+
+	// 清理已下线实例的记录
+	alive := make(map[string]struct{}, len(instances))
+	for _, instance := range instances {
+		alive[endpointID(instance)] = struct{}{}
+	}
+	for id := range s.endpoints {
+		if _, ok := alive[id]; !ok {
+			delete(s.endpoints, id)
+		}
+	}
+	for id := range s.outliers {
+		if _, ok := alive[id]; !ok {
+			delete(s.outliers, id)
+		}
+	}
+
+	s.rebuild(time.Now())
+}
+
+// rebuild 根据健康状态和剔除记录重建可选实例, 调用方需持有写锁
+func (s *service) rebuild(now time.Time) {
+	endpoints := make([]*Endpoint, 0, len(s.instances))
+	s.nextExpiry = time.Time{}
+
+	for _, instance := range s.instances {
+		// 跳过不健康和正在下线的实例
+		if !instance.Healthy || instance.IsDraining() {
+			continue
+		}
+
+		id := endpointID(instance)
+		if o, ok := s.outliers[id]; ok && now.Before(o.ejectedUntil) {
+			if s.nextExpiry.IsZero() || o.ejectedUntil.Before(s.nextExpiry) {
+				s.nextExpiry = o.ejectedUntil
+			}
+			continue
+		}
+
+		endpoint, ok := s.endpoints[id]
+		if !ok {
+			endpoint = &Endpoint{}
+			s.endpoints[id] = endpoint
+		}
+		endpoint.Instance = instance
+		endpoints = append(endpoints, endpoint)
+	}
+
+	sort.Slice(endpoints, func(i, j int) bool { return endpoints[i].ID() < endpoints[j].ID() })
+
+	s.available = len(endpoints)
+	if s.available > 0 {
+		s.picker.Update(endpoints)
+	}
+}
+
+// pick 选择实例并计入进行中的请求, 剔除到期时先恢复实例
+func (s *service) pick(key string) (*Endpoint, registry.ServiceInstance) {
+	now := time.Now()
+
+	s.mu.RLock()
+	expired := !s.nextExpiry.IsZero() && !now.Before(s.nextExpiry)
+	s.mu.RUnlock()
+
+	if expired {
+		s.mu.Lock()
+		if !s.nextExpiry.IsZero() && !now.Before(s.nextExpiry) {
+			s.rebuild(now)
+		}
+		s.mu.Unlock()
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.available == 0 {
+		return nil, registry.ServiceInstance{}
+	}
+	endpoint := s.picker.Pick(key)
+	endpoint.active.Add(1)
+	return endpoint, endpoint.Instance
+}
+
+// markFailed 记录失败, 返回是否剔除了实例
+func (s *service) markFailed(cnf *Config, id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.endpoints[id]; !ok {
+		return false
+	}
+
+	o, ok := s.outliers[id]
+	if !ok {
+		o = &outlier{}
+		s.outliers[id] = o
+	}
+
+	now := time.Now()
+	if now.Before(o.ejectedUntil) {
+		return false
+	}
+
+	o.failures++
+	if o.failures < cnf.GetMaxFailures() {
+		return false
+	}
+
+	// 剔除比例达到上限时不再剔除, 避免全部实例被剔除
+	ejected := 0
+	for _, other := range s.outliers {
+		if now.Before(other.ejectedUntil) {
+			ejected++
+		}
+	}
+	if (ejected+1)*100 > len(s.instances)*cnf.GetMaxEjectionPercent() {
+		return false
+	}
+
+	o.failures = 0
+	o.ejectedUntil = now.Add(cnf.GetEjectionTime())
+	s.rebuild(now)
+	return true
+}
+
+// markSucceeded 请求成功, 清空连续失败次数
+func (s *service) markSucceeded(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if o, ok := s.outliers[id]; ok {
+		o.failures = 0
+	}
+}
@@ -0,0 +1,367 @@
+package balancer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/spelens-gud/assert"
+	"github.com/spelens-gud/logger"
+	"github.com/spelens-gud/trunk/internal/registry"
+)
+
+// newTestRegistry 创建测试用的内存注册中心
+func newTestRegistry(t *testing.T, store *registry.MemoryStore, key string) registry.Registry {
+	t.Helper()
+
+	log, err := logger.NewLogger(logger.DefaultConfig())
+	if err != nil {
+		t.Fatalf("创建 logger 失败: %v", err)
+	}
+	assert.SetLogger(log)
+
+	reg, err := registry.NewRegistryFactory(log).CreateMemoryRegistry(&registry.MemoryConfig{Key: key, Store: store})
+	if err != nil {
+		t.Fatalf("创建注册中心失败: %v", err)
+	}
+	t.Cleanup(reg.Close)
+	return reg
+}
+
+// putInstance 写入服务实例
+func putInstance(reg registry.Registry, service, id string, weight float64, healthy bool) {
+	value := fmt.Sprintf(`{"id":%q,"address":"10.0.0.1","port":9000,"weight":%v,"healthy":%v}`, id, weight, healthy)
+	reg.Put(context.Background(), service+"/"+id, value)
+}
+
+// newTestBalancer 创建测试用的负载均衡器
+func newTestBalancer(t *testing.T, discovery registry.Discovery, cnf *Config) *Balancer {
+	t.Helper()
+
+	log, _ := logger.NewLogger(logger.DefaultConfig())
+	b, err := NewBalancer(discovery, cnf, log)
+	if err != nil {
+		t.Fatalf("创建负载均衡器失败: %v", err)
+	}
+	t.Cleanup(b.Close)
+	return b
+}
+
+// pickN 选择 n 次并统计每个实例被选中的次数
+func pickN(t *testing.T, b *Balancer, service string, n int, key func(i int) string) map[string]int {
+	t.Helper()
+
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		result, err := b.Pick(context.Background(), service, key(i))
+		if err != nil {
+			t.Fatalf("选择实例失败: %v", err)
+		}
+		counts[result.Instance.ID]++
+		result.Done(nil)
+	}
+	return counts
+}
+
+// noKey 不使用 key
+func noKey(int) string { return "" }
+
+// eventually 等待条件成立
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("超时：条件未满足")
+}
+
+// TestBalancer_RoundRobin 测试轮询均匀分布并跳过不健康和下线中的实例
+func TestBalancer_RoundRobin(t *testing.T) {
+	reg := newTestRegistry(t, registry.NewMemoryStore(), "")
+	putInstance(reg, "/services/fight", "a", 1, true)
+	putInstance(reg, "/services/fight", "b", 1, true)
+	putInstance(reg, "/services/fight", "c", 1, true)
+	putInstance(reg, "/services/fight", "d", 1, false)
+
+	draining := registry.NewRegistration(registry.KindFight, "e", "10.0.0.1", registry.Transport{Protocol: "ws", Port: 9000})
+	draining.State = registry.StateDraining
+	reg.Put(context.Background(), "/services/fight/e", draining.Encode())
+
+	b := newTestBalancer(t, reg, &Config{Strategy: RoundRobin})
+	counts := pickN(t, b, "/services/fight", 300, noKey)
+
+	if counts["a"] != 100 || counts["b"] != 100 || counts["c"] != 100 || counts["d"] != 0 || counts["e"] != 0 {
+		t.Errorf("轮询分布错误: %v", counts)
+	}
+}
+
+// TestBalancer_WeightedRandom 测试按权重随机
+func TestBalancer_WeightedRandom(t *testing.T) {
+	reg := newTestRegistry(t, registry.NewMemoryStore(), "")
+	putInstance(reg, "/services/node", "light", 1, true)
+	putInstance(reg, "/services/node", "heavy", 3, true)
+
+	b := newTestBalancer(t, reg, &Config{Strategy: WeightedRandom})
+	counts := pickN(t, b, "/services/node", 8000, noKey)
+
+	ratio := float64(counts["heavy"]) / float64(counts["light"])
+	if ratio < 2.5 || ratio > 3.5 {
+		t.Errorf("期望权重比约为 3, 实际 %.2f (%v)", ratio, counts)
+	}
+}
+
+// TestBalancer_LeastConn 测试最少连接
+func TestBalancer_LeastConn(t *testing.T) {
+	reg := newTestRegistry(t, registry.NewMemoryStore(), "")
+	putInstance(reg, "/services/node", "a", 1, true)
+	putInstance(reg, "/services/node", "b", 1, true)
+
+	b := newTestBalancer(t, reg, &Config{Strategy: LeastConn})
+	ctx := context.Background()
+
+	first, err := b.Pick(ctx, "/services/node", "")
+	if err != nil {
+		t.Fatalf("选择实例失败: %v", err)
+	}
+
+	// 第一个请求未结束, 后续请求都应落到另一个实例
+	for i := 0; i < 10; i++ {
+		result, _ := b.Pick(ctx, "/services/node", "")
+		if result.Instance.ID == first.Instance.ID {
+			t.Fatalf("应选择进行中请求更少的实例, 实际 %s", result.Instance.ID)
+		}
+		result.Done(nil)
+	}
+
+	// Done 重复调用只计一次
+	first.Done(nil)
+	first.Done(nil)
+	counts := pickN(t, b, "/services/node", 100, noKey)
+	if counts["a"] == 0 || counts["b"] == 0 {
+		t.Errorf("请求结束后应重新分散: %v", counts)
+	}
+}
+
+// TestBalancer_ConsistentHash 测试一致性哈希: 同一 key 固定实例, 新增实例只迁移少量 key
+func TestBalancer_ConsistentHash(t *testing.T) {
+	reg := newTestRegistry(t, registry.NewMemoryStore(), "")
+	for _, id := range []string{"a", "b", "c"} {
+		putInstance(reg, "/services/fight", id, 1, true)
+	}
+
+	b := newTestBalancer(t, reg, &Config{Strategy: ConsistentHash})
+	ctx := context.Background()
+
+	const players = 1000
+	before := make(map[string]string, players)
+	for i := 0; i < players; i++ {
+		key := fmt.Sprintf("player-%d", i)
+		result, err := b.Pick(ctx, "/services/fight", key)
+		if err != nil {
+			t.Fatalf("选择实例失败: %v", err)
+		}
+		before[key] = result.Instance.ID
+
+		again, _ := b.Pick(ctx, "/services/fight", key)
+		if again.Instance.ID != result.Instance.ID {
+			t.Fatalf("同一 key 应选择同一实例: %s != %s", again.Instance.ID, result.Instance.ID)
+		}
+	}
+
+	putInstance(reg, "/services/fight", "d", 1, true)
+	eventually(t, func() bool {
+		counts := pickN(t, b, "/services/fight", 100, func(i int) string { return fmt.Sprintf("player-%d", i) })
+		return counts["d"] > 0
+	})
+
+	moved := 0
+	for key, id := range before {
+		result, _ := b.Pick(ctx, "/services/fight", key)
+		if result.Instance.ID != id {
+			if result.Instance.ID != "d" {
+				t.Fatalf("key %s 应迁移到新实例, 实际 %s -> %s", key, id, result.Instance.ID)
+			}
+			moved++
+		}
+	}
+	if moved == 0 || moved > players/2 {
+		t.Errorf("新增实例后迁移的 key 数不合理: %d", moved)
+	}
+}
+
+// TestBalancer_LiveUpdate 测试随注册中心上下线实时更新
+func TestBalancer_LiveUpdate(t *testing.T) {
+	store := registry.NewMemoryStore()
+	client := newTestRegistry(t, store, "")
+
+	b := newTestBalancer(t, client, &Config{})
+	ctx := context.Background()
+
+	if _, err := b.Pick(ctx, "/services/gate", ""); !errors.Is(err, ErrNoInstance) {
+		t.Fatalf("没有实例时期望 ErrNoInstance, 实际 %v", err)
+	}
+
+	gate := newTestRegistry(t, store, "/services/gate")
+	gate.Publisher("127.0.0.1:9100")
+	eventually(t, func() bool {
+		result, err := b.Pick(ctx, "/services/gate", "")
+		return err == nil && result.Instance.Port == 9100
+	})
+
+	gate.Close()
+	eventually(t, func() bool {
+		_, err := b.Pick(ctx, "/services/gate", "")
+		return errors.Is(err, ErrNoInstance)
+	})
+}
+
+// TestBalancer_OutlierEjection 测试连续失败后剔除, 到期后恢复
+func TestBalancer_OutlierEjection(t *testing.T) {
+	reg := newTestRegistry(t, registry.NewMemoryStore(), "")
+	putInstance(reg, "/services/node", "a", 1, true)
+	putInstance(reg, "/services/node", "b", 1, true)
+	putInstance(reg, "/services/node", "c", 1, true)
+
+	b := newTestBalancer(t, reg, &Config{MaxFailures: 2, EjectionTime: 100 * time.Millisecond})
+	ctx := context.Background()
+
+	// 确保已订阅
+	pickN(t, b, "/services/node", 3, noKey)
+
+	// 未达到失败阈值不剔除(不调用 Done, 避免成功请求清空失败次数)
+	b.MarkFailed("/services/node", "a")
+	seen := false
+	for i := 0; i < 3; i++ {
+		result, _ := b.Pick(ctx, "/services/node", "")
+		seen = seen || result.Instance.ID == "a"
+	}
+	if !seen {
+		t.Fatal("未达到失败阈值不应剔除")
+	}
+
+	b.MarkFailed("/services/node", "a")
+	if counts := pickN(t, b, "/services/node", 30, noKey); counts["a"] != 0 {
+		t.Fatalf("剔除后不应再选择: %v", counts)
+	}
+
+	// 剔除比例上限 50%: 3 个实例最多剔除 1 个
+	b.MarkFailed("/services/node", "b")
+	b.MarkFailed("/services/node", "b")
+	if counts := pickN(t, b, "/services/node", 30, noKey); counts["b"] == 0 {
+		t.Error("超过剔除比例上限时不应继续剔除")
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if counts := pickN(t, b, "/services/node", 30, noKey); counts["a"] == 0 {
+		t.Error("剔除到期后应恢复")
+	}
+
+	// 通过 Done 上报失败
+	for i := 0; i < 2; i++ {
+		for {
+			result, err := b.Pick(ctx, "/services/node", "")
+			if err != nil {
+				t.Fatalf("选择实例失败: %v", err)
+			}
+			if result.Instance.ID == "c" {
+				result.Done(errors.New("timeout"))
+				break
+			}
+			result.Done(nil)
+		}
+	}
+	if counts := pickN(t, b, "/services/node", 30, noKey); counts["c"] != 0 {
+		t.Errorf("Done 上报失败后应剔除: %v", counts)
+	}
+}
+
+// fakeDiscovery 每次订阅返回测试控制的通道
+type fakeDiscovery struct {
+	registry.Discovery
+	subs chan chan []registry.ServiceInstance // 每次订阅时推送新通道
+	err  error                                // 订阅失败时的错误
+}
+
+func (f *fakeDiscovery) Subscribe(ctx context.Context, name string) (<-chan []registry.ServiceInstance, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	ch := make(chan []registry.ServiceInstance, 1)
+	f.subs <- ch
+	return ch, nil
+}
+
+// TestBalancer_SubscriptionEnded 测试订阅失败或结束时不阻塞选择, 并在下次选择时重新订阅
+func TestBalancer_SubscriptionEnded(t *testing.T) {
+	discovery := &fakeDiscovery{subs: make(chan chan []registry.ServiceInstance, 1), err: errors.New("注册中心不可用")}
+	b := newTestBalancer(t, discovery, &Config{})
+
+	if _, err := b.Pick(context.Background(), "/services/fight", ""); err == nil {
+		t.Fatal("订阅失败时应返回错误")
+	}
+
+	// 收到实例列表前订阅结束
+	discovery.err = nil
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := b.Pick(context.Background(), "/services/fight", "")
+		errCh <- err
+	}()
+	close(<-discovery.subs)
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, ErrUnsubscribed) {
+			t.Fatalf("期望 ErrUnsubscribed, 实际 %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("订阅结束后选择不应阻塞")
+	}
+
+	// 重新订阅
+	go func() {
+		(<-discovery.subs) <- []registry.ServiceInstance{{ID: "a", Address: "10.0.0.1", Port: 9000, Healthy: true}}
+	}()
+	result, err := b.Pick(context.Background(), "/services/fight", "")
+	if err != nil || result.Instance.ID != "a" {
+		t.Fatalf("重新订阅后选择错误: %+v %v", result.Instance, err)
+	}
+}
+
+// TestPick_Default 测试默认负载均衡器
+func TestPick_Default(t *testing.T) {
+	reg := newTestRegistry(t, registry.NewMemoryStore(), "")
+	putInstance(reg, "/services/fight", "a", 1, true)
+
+	SetDefault(nil)
+	if _, err := Pick(context.Background(), "/services/fight", "1"); !errors.Is(err, ErrClosed) {
+		t.Errorf("未设置默认负载均衡器时期望 ErrClosed, 实际 %v", err)
+	}
+
+	SetDefault(newTestBalancer(t, reg, &Config{Strategies: map[string]Strategy{"/services/fight": ConsistentHash}}))
+	defer SetDefault(nil)
+
+	result, err := Pick(context.Background(), "/services/fight", "1")
+	if err != nil || result.Instance.ID != "a" {
+		t.Errorf("选择实例错误: %+v %v", result.Instance, err)
+	}
+}
+
+// TestConfig_Validate 测试配置验证
+func TestConfig_Validate(t *testing.T) {
+	if err := (&Config{Strategy: "random"}).Validate(); err == nil {
+		t.Error("未知策略应返回错误")
+	}
+	if err := (&Config{Strategies: map[string]Strategy{"gate": "x"}}).Validate(); err == nil {
+		t.Error("服务的未知策略应返回错误")
+	}
+	if got := (&Config{}).GetStrategy("gate"); got != RoundRobin {
+		t.Errorf("默认策略应为轮询, 实际 %s", got)
+	}
+}
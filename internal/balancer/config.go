@@ -0,0 +1,99 @@
+package balancer
+
+import (
+	"fmt"
+	"time"
+)
+
+// Strategy 负载均衡策略
+type Strategy string
+
+const (
+	// RoundRobin 轮询
+	RoundRobin Strategy = "round_robin"
+	// WeightedRandom 按权重随机(使用注册中心的实例权重)
+	WeightedRandom Strategy = "weighted_random"
+	// LeastConn 最少连接(进行中的请求数最少)
+	LeastConn Strategy = "least_conn"
+	// ConsistentHash 按 key 一致性哈希, 同一 key 固定落在同一实例
+	ConsistentHash Strategy = "consistent_hash"
+)
+
+// Config 负载均衡配置
+type Config struct {
+	Strategy           Strategy            `yaml:"strategy"`           // 默认策略，默认轮询
+	Strategies         map[string]Strategy `yaml:"strategies"`         // 按服务指定策略
+	MaxFailures        int                 `yaml:"maxFailures"`        // 连续失败多少次后剔除实例，默认3
+	EjectionTime       time.Duration       `yaml:"ejectionTime"`       // 剔除时长，默认30秒
+	MaxEjectionPercent int                 `yaml:"maxEjectionPercent"` // 最多剔除的实例比例，默认50
+	Replicas           int                 `yaml:"replicas"`           // 一致性哈希每个实例的虚拟节点数，默认100
+}
+
+// Validate 验证配置
+func (c *Config) Validate() error {
+	if err := validateStrategy(c.Strategy); err != nil {
+		return err
+	}
+	for service, strategy := range c.Strategies {
+		if err := validateStrategy(strategy); err != nil {
+			return fmt.Errorf("服务 %s: %w", service, err)
+		}
+	}
+	if c.MaxEjectionPercent < 0 || c.MaxEjectionPercent > 100 {
+		return fmt.Errorf("剔除比例必须在0-100之间: %d", c.MaxEjectionPercent)
+	}
+	return nil
+}
+
+// validateStrategy 验证策略
+func validateStrategy(strategy Strategy) error {
+	switch strategy {
+	case "", RoundRobin, WeightedRandom, LeastConn, ConsistentHash:
+		return nil
+	default:
+		return fmt.Errorf("未知的负载均衡策略: %s", strategy)
+	}
+}
+
+// GetStrategy 获取服务的策略
+func (c *Config) GetStrategy(service string) Strategy {
+	if strategy, ok := c.Strategies[service]; ok && strategy != "" {
+		return strategy
+	}
+	if c.Strategy == "" {
+		return RoundRobin
+	}
+	return c.Strategy
+}
+
+// GetMaxFailures 获取剔除前允许的连续失败次数，默认3
+func (c *Config) GetMaxFailures() int {
+	if c.MaxFailures <= 0 {
+		return 3
+	}
+	return c.MaxFailures
+}
+
+// GetEjectionTime 获取剔除时长，默认30秒
+func (c *Config) GetEjectionTime() time.Duration {
+	if c.EjectionTime <= 0 {
+		return 30 * time.Second
+	}
+	return c.EjectionTime
+}
+
+// GetMaxEjectionPercent 获取最多剔除的实例比例，默认50
+func (c *Config) GetMaxEjectionPercent() int {
+	if c.MaxEjectionPercent <= 0 {
+		return 50
+	}
+	return c.MaxEjectionPercent
+}
+
+// GetReplicas 获取一致性哈希虚拟节点数，默认100
+func (c *Config) GetReplicas() int {
+	if c.Replicas <= 0 {
+		return 100
+	}
+	return c.Replicas
+}
@@ -0,0 +1,177 @@
+package balancer
+
+import (
+	"hash/crc32"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/spelens-gud/trunk/internal/registry"
+)
+
+// Endpoint 可选择的服务实例
+type Endpoint struct {
+	Instance registry.ServiceInstance // 服务实例
+	active   atomic.Int64             // 进行中的请求数
+}
+
+// ID 实例标识, 实例ID为空时使用地址
+func (e *Endpoint) ID() string {
+	return endpointID(e.Instance)
+}
+
+// Active 进行中的请求数
+func (e *Endpoint) Active() int64 {
+	return e.active.Load()
+}
+
+// endpointID 实例标识
+func endpointID(instance registry.ServiceInstance) string {
+	if instance.ID != "" {
+		return instance.ID
+	}
+	return instance.Endpoint()
+}
+
+// Picker 选择算法, 只会收到健康且未被剔除的实例
+type Picker interface {
+	// Update 更新可选实例, 列表按实例标识排序且不为空
+	Update(endpoints []*Endpoint)
+	// Pick 选择实例
+	Pick(key string) *Endpoint
+}
+
+// newPicker 根据策略创建选择算法
+func newPicker(strategy Strategy, replicas int) Picker {
+	switch strategy {
+	case WeightedRandom:
+		return &weightedRandomPicker{}
+	case LeastConn:
+		return &leastConnPicker{}
+	case ConsistentHash:
+		return &consistentHashPicker{replicas: replicas}
+	default:
+		return &roundRobinPicker{}
+	}
+}
+
+// roundRobinPicker 轮询
+type roundRobinPicker struct {
+	endpoints []*Endpoint
+	next      atomic.Uint64
+}
+
+func (p *roundRobinPicker) Update(endpoints []*Endpoint) {
+	p.endpoints = endpoints
+}
+
+func (p *roundRobinPicker) Pick(string) *Endpoint {
+	n := p.next.Add(1) - 1
+	return p.endpoints[n%uint64(len(p.endpoints))]
+}
+
+// weightedRandomPicker 按权重随机
+type weightedRandomPicker struct {
+	endpoints  []*Endpoint
+	cumulative []float64 // 权重前缀和
+}
+
+func (p *weightedRandomPicker) Update(endpoints []*Endpoint) {
+	p.endpoints = endpoints
+	p.cumulative = make([]float64, len(endpoints))
+
+	total := 0.0
+	for i, endpoint := range endpoints {
+		weight := endpoint.Instance.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		total += weight
+		p.cumulative[i] = total
+	}
+}
+
+func (p *weightedRandomPicker) Pick(string) *Endpoint {
+	r := rand.Float64() * p.cumulative[len(p.cumulative)-1]
+	i := sort.SearchFloat64s(p.cumulative, r)
+	// r 恰好等于某个前缀和时落在下一个实例
+	if i < len(p.cumulative) && p.cumulative[i] == r {
+		i++
+	}
+	if i >= len(p.endpoints) {
+		i = len(p.endpoints) - 1
+	}
+	return p.endpoints[i]
+}
+
+// leastConnPicker 最少连接
+type leastConnPicker struct {
+	endpoints []*Endpoint
+}
+
+func (p *leastConnPicker) Update(endpoints []*Endpoint) {
+	p.endpoints = endpoints
+}
+
+func (p *leastConnPicker) Pick(string) *Endpoint {
+	// 从随机位置开始扫描, 请求数相同时分散到不同实例
+	n := len(p.endpoints)
+	start := rand.IntN(n)
+
+	best := p.endpoints[start]
+	for i := 1; i < n; i++ {
+		endpoint := p.endpoints[(start+i)%n]
+		if endpoint.Active() < best.Active() {
+			best = endpoint
+		}
+	}
+	return best
+}
+
+// consistentHashPicker 一致性哈希, 实例变化时只影响该实例上的 key
+type consistentHashPicker struct {
+	replicas int
+	ring     []uint32             // 排序后的虚拟节点哈希
+	nodes    map[uint32]*Endpoint // 虚拟节点对应的实例
+	fallback roundRobinPicker     // key 为空时轮询
+}
+
+func (p *consistentHashPicker) Update(endpoints []*Endpoint) {
+	p.ring = make([]uint32, 0, len(endpoints)*p.replicas)
+	p.nodes = make(map[uint32]*Endpoint, len(endpoints)*p.replicas)
+
+	for _, endpoint := range endpoints {
+		id := endpoint.ID()
+		for i := 0; i < p.replicas; i++ {
+			h := hashKey(id + "#" + strconv.Itoa(i))
+			// 哈希冲突时保留先加入的实例, 列表有序保证结果确定
+			if _, ok := p.nodes[h]; ok {
+				continue
+			}
+			p.nodes[h] = endpoint
+			p.ring = append(p.ring, h)
+		}
+	}
+	sort.Slice(p.ring, func(i, j int) bool { return p.ring[i] < p.ring[j] })
+
+	p.fallback.Update(endpoints)
+}
+
+func (p *consistentHashPicker) Pick(key string) *Endpoint {
+	if key == "" {
+		return p.fallback.Pick(key)
+	}
+
+	h := hashKey(key)
+	i := sort.Search(len(p.ring), func(i int) bool { return p.ring[i] >= h })
+	if i == len(p.ring) {
+		i = 0
+	}
+	return p.nodes[p.ring[i]]
+}
+
+// hashKey 计算哈希
+func hashKey(key string) uint32 {
+	return crc32.ChecksumIEEE([]byte(key))
+}
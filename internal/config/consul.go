@@ -0,0 +1,100 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/spelens-gud/logger"
+)
+
+// ConsulSource consul 键值配置源, 可复用 ConsulRegistry.GetClient() 返回的客户端
+type ConsulSource struct {
+	client *api.Client
+	key    string
+	log    logger.ILogger
+}
+
+// NewConsulSource 创建 consul 配置源, key 如 /wsh/moba/common, consul 的键不能以 / 开头, 前导 / 会被去掉
+func NewConsulSource(client *api.Client, key string, log logger.ILogger) *ConsulSource {
+	return &ConsulSource{client: client, key: strings.TrimPrefix(key, "/"), log: log}
+}
+
+// String 配置源名称
+func (s *ConsulSource) String() string {
+	return "consul:" + s.key
+}
+
+// Load 读取 key 及其子键
+func (s *ConsulSource) Load(ctx context.Context) (map[string]any, error) {
+	pairs, _, err := s.list(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	kvs := make(map[string][]byte, len(pairs))
+	for _, pair := range pairs {
+		if isChildKey(s.key, pair.Key) {
+			kvs[pair.Key] = pair.Value
+		}
+	}
+	return decodeKVs(s.key, kvs)
+}
+
+// list 查询前缀, waitIndex 不为 0 时为阻塞查询
+func (s *ConsulSource) list(ctx context.Context, waitIndex uint64) (api.KVPairs, uint64, error) {
+	queryOpts := (&api.QueryOptions{
+		WaitIndex: waitIndex,
+		WaitTime:  time.Minute,
+	}).WithContext(ctx)
+	pairs, meta, err := s.client.KV().List(s.key, queryOpts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("读取consul配置失败: %w", err)
+	}
+	return pairs, meta.LastIndex, nil
+}
+
+// Watch 基于阻塞查询监听 key 及其子键
+func (s *ConsulSource) Watch(ctx context.Context) (<-chan struct{}, error) {
+	_, lastIndex, err := s.list(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan struct{}, 1)
+	go logger.WithRecover(s.log, func() {
+		defer close(ch)
+
+		for {
+			_, index, err := s.list(ctx, lastIndex)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				s.log.Warnf("监听consul配置失败，键: %s, 错误: %v", s.key, err)
+				select {
+				case <-time.After(time.Second):
+				case <-ctx.Done():
+					return
+				}
+				continue
+			}
+
+			// 阻塞查询超时返回时索引不变
+			if index == lastIndex {
+				continue
+			}
+			// 索引回退时重置, 避免阻塞查询立即返回导致空转
+			if index < lastIndex {
+				lastIndex = 0
+				continue
+			}
+			lastIndex = index
+			notify(ch)
+		}
+	})
+
+	return ch, nil
+}
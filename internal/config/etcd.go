@@ -0,0 +1,128 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spelens-gud/logger"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+var (
+	// minEtcdWatchBackoff 重新监听的最小间隔
+	minEtcdWatchBackoff = 100 * time.Millisecond
+	// maxEtcdWatchBackoff 重新监听的最大间隔
+	maxEtcdWatchBackoff = 30 * time.Second
+)
+
+// EtcdKV etcd 配置源使用的读取和监听接口, *clientv3.Client 已实现, 内存注册中心通过适配实现
+type EtcdKV interface {
+	Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error)
+	Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan
+}
+
+// EtcdSource etcd 键值配置源, 可复用 EtcdRegistry.GetCacheClient() 返回的客户端
+type EtcdSource struct {
+	cli EtcdKV
+	key string
+	log logger.ILogger
+}
+
+// NewEtcdSource 创建 etcd 配置源, key 如 /wsh/moba/common
+func NewEtcdSource(cli EtcdKV, key string, log logger.ILogger) *EtcdSource {
+	return &EtcdSource{cli: cli, key: key, log: log}
+}
+
+// String 配置源名称
+func (s *EtcdSource) String() string {
+	return "etcd:" + s.key
+}
+
+// Load 读取 key 及其子键
+func (s *EtcdSource) Load(ctx context.Context) (map[string]any, error) {
+	resp, err := s.cli.Get(ctx, s.key, clientv3.WithPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("读取etcd配置失败: %w", err)
+	}
+
+	kvs := make(map[string][]byte, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		if isChildKey(s.key, string(kv.Key)) {
+			kvs[string(kv.Key)] = kv.Value
+		}
+	}
+	return decodeKVs(s.key, kvs)
+}
+
+// Watch 监听 key 及其子键, 监听通道关闭或出错(如丢失 leader、版本已压缩)后按退避重新监听, 直到 ctx 取消
+func (s *EtcdSource) Watch(ctx context.Context) (<-chan struct{}, error) {
+	ch := make(chan struct{}, 1)
+
+	// 首次监听在返回前建立, 返回后的变化不会错过
+	var rev int64 // 下次监听的起始版本, 为 0 时从最新版本开始
+	watchChan, cancel := s.openWatch(ctx, rev)
+
+	go logger.WithRecover(s.log, func() {
+		defer close(ch)
+
+		backoff := minEtcdWatchBackoff
+		for {
+			received := s.consume(ctx, watchChan, ch, &rev)
+			cancel()
+			if received {
+				backoff = minEtcdWatchBackoff
+			}
+
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return
+			}
+			backoff = min(backoff*2, maxEtcdWatchBackoff)
+			watchChan, cancel = s.openWatch(ctx, rev)
+		}
+	})
+
+	return ch, nil
+}
+
+// openWatch 从 rev 开始监听, rev 为 0 时从最新版本开始; 丢失 leader 时通道返回错误
+func (s *EtcdSource) openWatch(ctx context.Context, rev int64) (clientv3.WatchChan, context.CancelFunc) {
+	watchCtx, cancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+
+	opts := []clientv3.OpOption{clientv3.WithPrefix()}
+	if rev > 0 {
+		opts = append(opts, clientv3.WithRev(rev))
+	}
+	return s.cli.Watch(watchCtx, s.key, opts...), cancel
+}
+
+// consume 处理一次监听直到通道关闭或出错, 收到过响应时返回 true; rev 更新为已处理版本的下一个版本
+func (s *EtcdSource) consume(ctx context.Context, watchChan clientv3.WatchChan, ch chan<- struct{}, rev *int64) bool {
+	received := false
+	for resp := range watchChan {
+		if err := resp.Err(); err != nil {
+			if ctx.Err() != nil {
+				return received
+			}
+			s.log.Warnf("监听etcd配置失败，重新监听，键: %s, 错误: %v", s.key, err)
+			// 版本已压缩时可能错过了变化, 从最新版本重新监听并重新读取
+			if resp.CompactRevision != 0 {
+				*rev = 0
+				notify(ch)
+			}
+			return received
+		}
+
+		received = true
+		*rev = resp.Header.Revision + 1
+		for _, event := range resp.Events {
+			if isChildKey(s.key, string(event.Kv.Key)) {
+				notify(ch)
+				break
+			}
+		}
+	}
+	return received
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// fakeWatchKV 按顺序返回预设的监听通道, 并记录每次监听的起始版本
+type fakeWatchKV struct {
+	EtcdKV
+	watches chan clientv3.WatchChan
+	revs    chan int64
+}
+
+func (f *fakeWatchKV) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	f.revs <- clientv3.OpGet(key, opts...).Rev()
+	select {
+	case ch := <-f.watches:
+		return ch
+	case <-ctx.Done():
+		ch := make(chan clientv3.WatchResponse)
+		close(ch)
+		return ch
+	}
+}
+
+// TestEtcdSource_Rewatch 测试监听通道关闭后从下一个版本重新监听, 版本压缩后从最新版本重新监听并通知重新读取
+func TestEtcdSource_Rewatch(t *testing.T) {
+	backoff := minEtcdWatchBackoff
+	minEtcdWatchBackoff = 10 * time.Millisecond
+	defer func() { minEtcdWatchBackoff = backoff }()
+
+	kv := &fakeWatchKV{watches: make(chan clientv3.WatchChan, 3), revs: make(chan int64, 3)}
+	source := NewEtcdSource(kv, "/wsh/moba/common", newTestLogger(t))
+
+	event := func(revision int64) clientv3.WatchResponse {
+		return clientv3.WatchResponse{
+			Header: etcdserverpb.ResponseHeader{Revision: revision},
+			Events: []*clientv3.Event{{Kv: &mvccpb.KeyValue{Key: []byte("/wsh/moba/common/RateLimit/qps")}}},
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 收到版本 5 的变化后通道关闭(如丢失 leader)
+	first := make(chan clientv3.WatchResponse, 1)
+	first <- event(5)
+	close(first)
+	kv.watches <- first
+
+	ch, err := source.Watch(ctx)
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+
+	expectRev := func(want int64) {
+		t.Helper()
+		select {
+		case rev := <-kv.revs:
+			if rev != want {
+				t.Fatalf("期望从版本 %d 监听, 实际 %d", want, rev)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("等待从版本 %d 重新监听超时", want)
+		}
+	}
+	expectNotify := func() {
+		t.Helper()
+		select {
+		case <-ch:
+		case <-time.After(3 * time.Second):
+			t.Fatal("等待变化通知超时")
+		}
+	}
+
+	expectRev(0)
+	expectNotify()
+
+	// 从下一个版本重新监听, 不会错过变化; 版本已压缩时通知重新读取
+	second := make(chan clientv3.WatchResponse, 1)
+	second <- clientv3.WatchResponse{Canceled: true, CompactRevision: 7}
+	close(second)
+	kv.watches <- second
+	expectRev(6)
+	expectNotify()
+
+	// 压缩后从最新版本重新监听, 重新监听后继续通知变化
+	third := make(chan clientv3.WatchResponse, 1)
+	kv.watches <- third
+	expectRev(0)
+	third <- event(9)
+	expectNotify()
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spelens-gud/logger"
+)
+
+// FileSource 本地 yaml/json 文件配置源
+type FileSource struct {
+	path string
+	log  logger.ILogger
+}
+
+// NewFileSource 创建文件配置源
+func NewFileSource(path string, log logger.ILogger) *FileSource {
+	return &FileSource{path: filepath.Clean(path), log: log}
+}
+
+// String 配置源名称
+func (s *FileSource) String() string {
+	return "file:" + s.path
+}
+
+// Load 读取文件, 文件不存在时返回空配置
+func (s *FileSource) Load(context.Context) (map[string]any, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return map[string]any{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	return decodeDocument(data)
+}
+
+// Watch 监听文件所在目录, 兼容编辑器先写临时文件再改名的保存方式
+func (s *FileSource) Watch(ctx context.Context) (<-chan struct{}, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("创建文件监听失败: %w", err)
+	}
+	if err := watcher.Add(filepath.Dir(s.path)); err != nil {
+		_ = watcher.Close()
+		return nil, fmt.Errorf("监听配置目录失败: %w", err)
+	}
+
+	ch := make(chan struct{}, 1)
+	go logger.WithRecover(s.log, func() {
+		defer close(ch)
+		defer watcher.Close()
+
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) == s.path && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) != 0 {
+					notify(ch)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				s.log.Warnf("监听配置文件失败，文件: %s, 错误: %v", s.path, err)
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+
+	return ch, nil
+}
@@ -0,0 +1,218 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spelens-gud/logger"
+	"github.com/spf13/viper"
+)
+
+// Manager 动态配置, 按顺序叠加配置源(后面的覆盖前面的)并热更新到服务的 viper
+//
+// 配置源涉及的顶层配置(如 RateLimit)与启动时的配置合并后以 viper.Set 写入, 优先级高于配置文件;
+// 配置源删除某个键后回退到启动时的值。
+// viper 不是并发安全的, 业务应通过 OnChange 回调拿到新配置, 而不是在其他协程中直接读取 viper
+type Manager struct {
+	v          *viper.Viper
+	log        logger.ILogger
+	sources    []Source
+	base       map[string]any      // 启动时 viper 中的配置
+	layers     []map[string]any    // 各配置源的配置
+	applied    map[string]any      // 生效配置展开后的叶子节点
+	overridden map[string]struct{} // 已写入 viper 的顶层键
+	handlers   []handler           // 变化回调
+	mu         sync.Mutex
+	applyMu    sync.Mutex // 串行写入 viper 和触发回调
+}
+
+// handler 变化回调
+type handler struct {
+	key string // 小写的层级键
+	fn  func()
+}
+
+// NewManager 创建动态配置
+func NewManager(v *viper.Viper, log logger.ILogger, sources ...Source) *Manager {
+	return &Manager{
+		v:          v,
+		log:        log,
+		sources:    sources,
+		layers:     make([]map[string]any, len(sources)),
+		overridden: make(map[string]struct{}),
+	}
+}
+
+// Start 读取全部配置源并写入 viper, 之后监听变化直到 ctx 取消; 任一配置源读取失败时返回错误
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	m.base = normalize(m.v.AllSettings())
+	m.applied = make(map[string]any)
+	flatten("", m.base, m.applied)
+	m.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	// 先监听再读取, 避免遗漏两者之间的变化
+	watches := make([]<-chan struct{}, len(m.sources))
+	for i, source := range m.sources {
+		ch, err := source.Watch(ctx)
+		if err != nil {
+			cancel()
+			return fmt.Errorf("监听配置源 %s 失败: %w", source, err)
+		}
+		watches[i] = ch
+	}
+
+	for i, source := range m.sources {
+		settings, err := source.Load(ctx)
+		if err != nil {
+			cancel()
+			return fmt.Errorf("读取配置源 %s 失败: %w", source, err)
+		}
+		m.setLayer(i, settings)
+	}
+	m.apply()
+
+	var wg sync.WaitGroup
+	for i, ch := range watches {
+		wg.Add(1)
+		go logger.WithRecover(m.log, func() {
+			defer wg.Done()
+			for range ch {
+				m.reload(ctx, i)
+			}
+		})
+	}
+	go func() {
+		wg.Wait()
+		cancel()
+	}()
+
+	m.log.Infof("动态配置启动成功，配置源: %v", m.sources)
+	return nil
+}
+
+// reload 重新读取配置源, 读取失败时保留上一次的配置
+func (m *Manager) reload(ctx context.Context, i int) {
+	settings, err := m.sources[i].Load(ctx)
+	if err != nil {
+		m.log.Errorf("重新读取配置源失败，配置源: %s, 错误: %v", m.sources[i], err)
+		return
+	}
+	m.setLayer(i, settings)
+	m.apply()
+}
+
+// setLayer 更新配置源的配置
+func (m *Manager) setLayer(i int, settings map[string]any) {
+	layer := normalize(settings)
+
+	m.mu.Lock()
+	m.layers[i] = layer
+	m.mu.Unlock()
+}
+
+// apply 合并全部配置源, 把变化写入 viper 并触发回调
+func (m *Manager) apply() {
+	m.applyMu.Lock()
+	defer m.applyMu.Unlock()
+
+	m.mu.Lock()
+
+	merged := make(map[string]any)
+	merge(merged, m.base)
+	touched := make(map[string]struct{})
+	for _, layer := range m.layers {
+		merge(merged, layer)
+		for k := range layer {
+			touched[k] = struct{}{}
+		}
+	}
+
+	// 整个顶层配置写入 viper, 读取 RateLimit 和 RateLimit.qps 都能得到合并后的值
+	for k := range touched {
+		m.v.Set(k, merged[k])
+	}
+	for k := range m.overridden {
+		if _, ok := touched[k]; !ok {
+			// 覆盖值为 nil 时 viper 回退到配置文件中的值
+			m.v.Set(k, nil)
+		}
+	}
+	m.overridden = touched
+
+	flat := make(map[string]any)
+	flatten("", merged, flat)
+
+	var changed []string
+	for k, v := range flat {
+		if old, ok := m.applied[k]; !ok || !reflect.DeepEqual(old, v) {
+			changed = append(changed, k)
+		}
+	}
+	for k := range m.applied {
+		if _, ok := flat[k]; !ok {
+			changed = append(changed, k)
+		}
+	}
+	m.applied = flat
+
+	var fns []func()
+	for _, h := range m.handlers {
+		if matchKey(h.key, changed) {
+			fns = append(fns, h.fn)
+		}
+	}
+	m.mu.Unlock()
+
+	if len(changed) == 0 {
+		return
+	}
+	sort.Strings(changed)
+	m.log.Infof("配置已更新，变化的键: %v", changed)
+
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// matchKey 变化的键中是否有 key 本身或其子键
+func matchKey(key string, changed []string) bool {
+	for _, k := range changed {
+		if key == "" || k == key || strings.HasPrefix(k, key+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// OnChange 注册变化回调, key 为 viper 的层级键(如 Logger 或 Logger.log_level), 为空时任意配置变化都会回调
+//
+// key 本身或其子键变化时, 把 viper 中 key 的最新值解码为 T 后回调, 解码失败时记录日志并跳过本次回调
+func OnChange[T any](m *Manager, key string, fn func(T), opts ...viper.DecoderConfigOption) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.handlers = append(m.handlers, handler{
+		key: strings.ToLower(key),
+		fn: func() {
+			var value T
+			var err error
+			if key == "" {
+				err = m.v.Unmarshal(&value, opts...)
+			} else {
+				err = m.v.UnmarshalKey(key, &value, opts...)
+			}
+			if err != nil {
+				m.log.Errorf("解码配置失败，键: %s, 错误: %v", key, err)
+				return
+			}
+			fn(value)
+		},
+	})
+}
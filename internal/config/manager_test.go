@@ -0,0 +1,202 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spelens-gud/logger"
+	"github.com/spf13/viper"
+)
+
+// testSource 测试用的配置源, Set 后通知重新读取
+type testSource struct {
+	mu       sync.Mutex
+	settings map[string]any
+	ch       chan struct{}
+}
+
+func newTestSource(settings map[string]any) *testSource {
+	return &testSource{settings: settings, ch: make(chan struct{}, 1)}
+}
+
+func (s *testSource) String() string { return "test" }
+
+func (s *testSource) Load(context.Context) (map[string]any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.settings, nil
+}
+
+func (s *testSource) Watch(context.Context) (<-chan struct{}, error) {
+	return s.ch, nil
+}
+
+func (s *testSource) Set(settings map[string]any) {
+	s.mu.Lock()
+	s.settings = settings
+	s.mu.Unlock()
+	notify(s.ch)
+}
+
+func newTestLogger(t *testing.T) logger.ILogger {
+	t.Helper()
+
+	log, err := logger.NewLogger(logger.DefaultConfig())
+	if err != nil {
+		t.Fatalf("创建 logger 失败: %v", err)
+	}
+	return log
+}
+
+// newTestViper 模拟服务读取的静态配置
+func newTestViper(t *testing.T) *viper.Viper {
+	t.Helper()
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader("Logger:\n  log_level: info\nRateLimit:\n  qps: 100\n  burst: 10\n")); err != nil {
+		t.Fatalf("读取配置失败: %v", err)
+	}
+	return v
+}
+
+type rateLimit struct {
+	QPS   int `mapstructure:"qps"`
+	Burst int `mapstructure:"burst"`
+}
+
+// waitValue 等待回调的值
+func waitValue[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("等待配置变化超时")
+	}
+	var zero T
+	return zero
+}
+
+// TestManager_Layers 测试配置源按顺序覆盖静态配置, 删除后回退
+func TestManager_Layers(t *testing.T) {
+	v := newTestViper(t)
+	common := newTestSource(map[string]any{"RateLimit": map[string]any{"qps": 200}})
+	service := newTestSource(map[string]any{})
+
+	m := NewManager(v, newTestLogger(t), common, service)
+
+	limits := make(chan rateLimit, 10)
+	OnChange(m, "RateLimit", func(l rateLimit) { limits <- l })
+	levels := make(chan string, 10)
+	OnChange(m, "Logger.log_level", func(level string) { levels <- level })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := m.Start(ctx); err != nil {
+		t.Fatalf("启动失败: %v", err)
+	}
+
+	if l := waitValue(t, limits); l.QPS != 200 || l.Burst != 10 {
+		t.Fatalf("期望 qps=200 burst=10, 实际: %+v", l)
+	}
+
+	// 后面的配置源优先
+	service.Set(map[string]any{"RateLimit": map[string]any{"qps": 300}})
+	if l := waitValue(t, limits); l.QPS != 300 {
+		t.Fatalf("期望 qps=300, 实际: %+v", l)
+	}
+
+	// 删除后回退到前一个配置源
+	service.Set(map[string]any{})
+	if l := waitValue(t, limits); l.QPS != 200 {
+		t.Fatalf("期望 qps=200, 实际: %+v", l)
+	}
+
+	// 全部删除后回退到配置文件
+	common.Set(map[string]any{"Logger": map[string]any{"log_level": "debug"}})
+	if l := waitValue(t, limits); l.QPS != 100 {
+		t.Fatalf("期望 qps=100, 实际: %+v", l)
+	}
+	if level := waitValue(t, levels); level != "debug" {
+		t.Fatalf("期望 debug, 实际: %s", level)
+	}
+
+	// 未变化的键不触发回调
+	common.Set(map[string]any{"Logger": map[string]any{"log_level": "debug"}})
+	select {
+	case l := <-limits:
+		t.Fatalf("配置未变化不应回调, 实际: %+v", l)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// TestFileSource 测试本地文件修改后热更新
+func TestFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "common.yaml")
+	if err := os.WriteFile(path, []byte("RateLimit:\n  qps: 150\n"), 0o644); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+
+	v := newTestViper(t)
+	m := NewManager(v, newTestLogger(t), NewFileSource(path, newTestLogger(t)))
+
+	qps := make(chan int, 10)
+	OnChange(m, "RateLimit.qps", func(n int) { qps <- n })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := m.Start(ctx); err != nil {
+		t.Fatalf("启动失败: %v", err)
+	}
+	if n := waitValue(t, qps); n != 150 {
+		t.Fatalf("期望 150, 实际: %d", n)
+	}
+
+	// 先写临时文件再改名, 模拟编辑器保存
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte("RateLimit:\n  qps: 250\n"), 0o644); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatalf("重命名文件失败: %v", err)
+	}
+	if n := waitValue(t, qps); n != 250 {
+		t.Fatalf("期望 250, 实际: %d", n)
+	}
+}
+
+// TestDecodeKVs 测试键值配置的文档和子键合并
+func TestDecodeKVs(t *testing.T) {
+	settings, err := decodeKVs("/wsh/moba/common", map[string][]byte{
+		"/wsh/moba/common":                  []byte("RateLimit:\n  qps: 100\n  burst: 10\n"),
+		"/wsh/moba/common/RateLimit/qps":    []byte("300"),
+		"/wsh/moba/common/Logger/log_level": []byte("debug"),
+	})
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+
+	flat := make(map[string]any)
+	flatten("", settings, flat)
+
+	expected := map[string]any{
+		"ratelimit.qps":    300,
+		"ratelimit.burst":  10,
+		"logger.log_level": "debug",
+	}
+	for k, v := range expected {
+		if flat[k] != v {
+			t.Errorf("%s 期望 %v, 实际 %v", k, v, flat[k])
+		}
+	}
+	if len(flat) != len(expected) {
+		t.Errorf("期望 %d 个键, 实际 %v", len(expected), flat)
+	}
+}
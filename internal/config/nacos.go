@@ -0,0 +1,79 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/clients/config_client"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+	"github.com/spelens-gud/logger"
+)
+
+// NacosSource nacos 配置中心配置源, 配置内容为 yaml 文档
+type NacosSource struct {
+	client config_client.IConfigClient
+	dataID string
+	group  string
+	log    logger.ILogger
+}
+
+// NewNacosSource 创建 nacos 配置源, key 如 /wsh/moba/common 会转换为 dataId wsh.moba.common
+func NewNacosSource(client config_client.IConfigClient, key, group string, log logger.ILogger) *NacosSource {
+	return &NacosSource{client: client, dataID: NacosDataID(key), group: group, log: log}
+}
+
+// NacosDataID 键转换为 dataId, dataId 不支持 /, 按层级转换为 . 分隔, 如 /wsh/moba/common 转换为 wsh.moba.common
+func NacosDataID(key string) string {
+	return strings.ReplaceAll(strings.Trim(key, "/"), "/", ".")
+}
+
+// String 配置源名称
+func (s *NacosSource) String() string {
+	return "nacos:" + s.group + "/" + s.dataID
+}
+
+// Load 读取配置
+func (s *NacosSource) Load(context.Context) (map[string]any, error) {
+	content, err := s.client.GetConfig(vo.ConfigParam{DataId: s.dataID, Group: s.group})
+	if err != nil {
+		return nil, fmt.Errorf("读取nacos配置失败: %w", err)
+	}
+	return decodeDocument([]byte(content))
+}
+
+// Watch 监听配置变化
+func (s *NacosSource) Watch(ctx context.Context) (<-chan struct{}, error) {
+	ch := make(chan struct{}, 1)
+	notifyCh := make(chan struct{}, 1)
+
+	err := s.client.ListenConfig(vo.ConfigParam{
+		DataId: s.dataID,
+		Group:  s.group,
+		OnChange: func(_, _, _, _ string) {
+			notify(notifyCh)
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("监听nacos配置失败: %w", err)
+	}
+
+	// 回调由 sdk 的协程触发, 转发到通道, ctx 取消后取消监听并关闭通道
+	go logger.WithRecover(s.log, func() {
+		defer close(ch)
+
+		for {
+			select {
+			case <-notifyCh:
+				notify(ch)
+			case <-ctx.Done():
+				if err := s.client.CancelListenConfig(vo.ConfigParam{DataId: s.dataID, Group: s.group}); err != nil {
+					s.log.Warnf("取消监听nacos配置失败，dataId: %s, 错误: %v", s.dataID, err)
+				}
+				return
+			}
+		}
+	})
+
+	return ch, nil
+}
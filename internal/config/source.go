@@ -0,0 +1,143 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Source 配置源, 读取结果按 viper 的层级键合并到服务配置
+type Source interface {
+	// String 配置源名称, 用于日志
+	String() string
+	// Load 读取全部配置
+	Load(ctx context.Context) (map[string]any, error)
+	// Watch 监听变化, 通道收到通知后重新 Load; ctx 取消后关闭通道
+	Watch(ctx context.Context) (<-chan struct{}, error)
+}
+
+// notify 发送变化通知, 已有未处理的通知时合并
+func notify(ch chan<- struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// decodeDocument 解析 yaml 文档(兼容 json), 空文档返回空配置
+func decodeDocument(data []byte) (map[string]any, error) {
+	settings := make(map[string]any)
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("解析配置失败: %w", err)
+	}
+	return settings, nil
+}
+
+// decodeKVs 解析键值配置, 返回 normalize 后的配置
+//
+// key 本身的值为 yaml 文档; key 下的子键按路径展开, 如 /wsh/moba/common/Logger/log_level 对应 Logger.log_level,
+// 子键的值按 yaml 标量解析(数字、布尔等), 子键优先于文档中的同名配置
+func decodeKVs(key string, kvs map[string][]byte) (map[string]any, error) {
+	settings := make(map[string]any)
+	if doc, ok := kvs[key]; ok {
+		var err error
+		if settings, err = decodeDocument(doc); err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		settings = normalize(settings)
+	}
+
+	prefix := strings.TrimSuffix(key, "/") + "/"
+	for k, v := range kvs {
+		path, ok := strings.CutPrefix(k, prefix)
+		if !ok || path == "" {
+			continue
+		}
+
+		var value any
+		if err := yaml.Unmarshal(v, &value); err != nil {
+			// 非法 yaml 按字符串处理
+			value = string(v)
+		}
+		setPath(settings, strings.Split(strings.ToLower(strings.Trim(path, "/")), "/"), normalizeValue(value))
+	}
+	return settings, nil
+}
+
+// isChildKey 是否为 key 本身或其子键
+func isChildKey(key, k string) bool {
+	return k == key || strings.HasPrefix(k, strings.TrimSuffix(key, "/")+"/")
+}
+
+// setPath 按路径写入嵌套配置, 路径上的非 map 值会被覆盖
+func setPath(settings map[string]any, path []string, value any) {
+	for _, name := range path[:len(path)-1] {
+		next, ok := settings[name].(map[string]any)
+		if !ok {
+			next = make(map[string]any)
+			settings[name] = next
+		}
+		settings = next
+	}
+	settings[path[len(path)-1]] = value
+}
+
+// normalize 转换为 viper 的小写键, 并统一嵌套 map 的类型
+func normalize(settings map[string]any) map[string]any {
+	out := make(map[string]any, len(settings))
+	for k, v := range settings {
+		out[strings.ToLower(k)] = normalizeValue(v)
+	}
+	return out
+}
+
+// normalizeValue 转换嵌套 map, 其他值原样返回
+func normalizeValue(v any) any {
+	switch child := v.(type) {
+	case map[string]any:
+		return normalize(child)
+	case map[any]any:
+		converted := make(map[string]any, len(child))
+		for k, cv := range child {
+			converted[fmt.Sprint(k)] = cv
+		}
+		return normalize(converted)
+	default:
+		return v
+	}
+}
+
+// merge 把 src 深度合并到 dst, 同名的非 map 值以 src 为准
+func merge(dst, src map[string]any) {
+	for k, v := range src {
+		srcChild, ok := v.(map[string]any)
+		if !ok {
+			dst[k] = v
+			continue
+		}
+		dstChild, ok := dst[k].(map[string]any)
+		if !ok {
+			dstChild = make(map[string]any, len(srcChild))
+			dst[k] = dstChild
+		}
+		merge(dstChild, srcChild)
+	}
+}
+
+// flatten 展开为点分键, 只保留叶子节点, settings 需已 normalize
+func flatten(prefix string, settings map[string]any, out map[string]any) {
+	for k, v := range settings {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+
+		if child, ok := v.(map[string]any); ok {
+			flatten(key, child, out)
+		} else {
+			out[key] = v
+		}
+	}
+}
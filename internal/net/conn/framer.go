@@ -0,0 +1,301 @@
+package conn
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// DefaultMaxFrameSize 默认最大帧长度(1MB)
+const DefaultMaxFrameSize = 1024 * 1024
+
+var (
+	// ErrFrameTooLarge 帧长度超过上限
+	ErrFrameTooLarge = errors.New("帧长度超过上限")
+	// ErrEmptyFrame 空帧
+	ErrEmptyFrame = errors.New("帧长度为0")
+)
+
+// Framer 流式传输分帧器(QUIC 流、TCP 等没有消息边界的传输使用)
+type Framer interface {
+	// ReadFrame 从流中读取一帧完整数据
+	ReadFrame(r io.Reader) ([]byte, error)
+	// WriteFrame 将一帧数据写入流
+	WriteFrame(w io.Writer, data []byte) error
+}
+
+// FramerType 分帧方式
+type FramerType string
+
+const (
+	FramerUint16    FramerType = "uint16"    // 2字节长度前缀
+	FramerUint32    FramerType = "uint32"    // 4字节长度前缀
+	FramerVarint    FramerType = "varint"    // varint长度前缀
+	FramerDelimiter FramerType = "delimiter" // 分隔符
+)
+
+// FramerConfig 分帧配置
+type FramerConfig struct {
+	Type         FramerType `yaml:"type"`         // 分帧方式，默认uint32
+	MaxFrameSize uint32     `yaml:"maxFrameSize"` // 最大帧长度，默认1MB
+	LittleEndian bool       `yaml:"littleEndian"` // 长度前缀是否使用小端序，默认大端序
+	Delimiter    string     `yaml:"delimiter"`    // 分隔符，默认"\n"
+}
+
+// GetMaxFrameSize 获取最大帧长度
+func (c *FramerConfig) GetMaxFrameSize() uint32 {
+	if c.MaxFrameSize == 0 {
+		return DefaultMaxFrameSize
+	}
+	return c.MaxFrameSize
+}
+
+// GetDelimiter 获取分隔符
+func (c *FramerConfig) GetDelimiter() []byte {
+	if c.Delimiter == "" {
+		return []byte{'\n'}
+	}
+	return []byte(c.Delimiter)
+}
+
+// NewFramer 根据配置创建分帧器
+func (c *FramerConfig) NewFramer() (Framer, error) {
+	var order binary.ByteOrder = binary.BigEndian
+	if c.LittleEndian {
+		order = binary.LittleEndian
+	}
+
+	switch c.Type {
+	case "", FramerUint32:
+		return NewLengthFramer(4, order, c.GetMaxFrameSize()), nil
+	case FramerUint16:
+		return NewLengthFramer(2, order, c.GetMaxFrameSize()), nil
+	case FramerVarint:
+		return NewVarintFramer(c.GetMaxFrameSize()), nil
+	case FramerDelimiter:
+		return NewDelimiterFramer(c.GetDelimiter(), c.GetMaxFrameSize()), nil
+	default:
+		return nil, fmt.Errorf("不支持的分帧方式: %s", c.Type)
+	}
+}
+
+// Resolve 确定分帧器: f 不为空时直接使用, 否则按配置创建; 传输层在启动时调用一次, 连接的读写协程只读取结果
+func (c *FramerConfig) Resolve(f Framer) (Framer, error) {
+	if f != nil {
+		return f, nil
+	}
+	return c.NewFramer()
+}
+
+// DefaultFramer 默认分帧器(4字节大端序长度前缀, 最大1MB)
+func DefaultFramer() Framer {
+	return NewLengthFramer(4, binary.BigEndian, DefaultMaxFrameSize)
+}
+
+// LengthFramer 定长长度前缀分帧器
+type LengthFramer struct {
+	prefixSize   int              // 长度前缀字节数(2或4)
+	order        binary.ByteOrder // 字节序
+	maxFrameSize uint32           // 最大帧长度
+}
+
+// NewLengthFramer 创建长度前缀分帧器, prefixSize 只支持2或4
+func NewLengthFramer(prefixSize int, order binary.ByteOrder, maxFrameSize uint32) *LengthFramer {
+	if prefixSize != 2 {
+		prefixSize = 4
+	}
+
+	// 2字节前缀最多表示 65535
+	if prefixSize == 2 && maxFrameSize > math.MaxUint16 {
+		maxFrameSize = math.MaxUint16
+	}
+
+	return &LengthFramer{
+		prefixSize:   prefixSize,
+		order:        order,
+		maxFrameSize: maxFrameSize,
+	}
+}
+
+// ReadFrame 读取一帧
+func (f *LengthFramer) ReadFrame(r io.Reader) ([]byte, error) {
+	lenBuf := make([]byte, f.prefixSize)
+	if _, err := io.ReadFull(r, lenBuf); err != nil {
+		return nil, err
+	}
+
+	var msgLen uint32
+	if f.prefixSize == 2 {
+		msgLen = uint32(f.order.Uint16(lenBuf))
+	} else {
+		msgLen = f.order.Uint32(lenBuf)
+	}
+
+	if err := checkFrameSize(uint64(msgLen), f.maxFrameSize); err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, msgLen)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// WriteFrame 写入一帧
+func (f *LengthFramer) WriteFrame(w io.Writer, data []byte) error {
+	if err := checkFrameSize(uint64(len(data)), f.maxFrameSize); err != nil {
+		return err
+	}
+
+	// 合并长度前缀和数据, 一次写入
+	buf := make([]byte, f.prefixSize+len(data))
+	if f.prefixSize == 2 {
+		f.order.PutUint16(buf, uint16(len(data)))
+	} else {
+		f.order.PutUint32(buf, uint32(len(data)))
+	}
+	copy(buf[f.prefixSize:], data)
+
+	_, err := w.Write(buf)
+	return err
+}
+
+// VarintFramer varint长度前缀分帧器(与 protobuf 的 delimited 格式兼容)
+type VarintFramer struct {
+	maxFrameSize uint32 // 最大帧长度
+}
+
+// NewVarintFramer 创建varint长度前缀分帧器
+func NewVarintFramer(maxFrameSize uint32) *VarintFramer {
+	return &VarintFramer{
+		maxFrameSize: maxFrameSize,
+	}
+}
+
+// ReadFrame 读取一帧
+func (f *VarintFramer) ReadFrame(r io.Reader) ([]byte, error) {
+	msgLen, err := binary.ReadUvarint(toByteReader(r))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkFrameSize(msgLen, f.maxFrameSize); err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, msgLen)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// WriteFrame 写入一帧
+func (f *VarintFramer) WriteFrame(w io.Writer, data []byte) error {
+	if err := checkFrameSize(uint64(len(data)), f.maxFrameSize); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 0, binary.MaxVarintLen32+len(data))
+	buf = binary.AppendUvarint(buf, uint64(len(data)))
+	buf = append(buf, data...)
+
+	_, err := w.Write(buf)
+	return err
+}
+
+// DelimiterFramer 分隔符分帧器(返回的帧不包含分隔符)
+type DelimiterFramer struct {
+	delimiter    []byte // 分隔符
+	maxFrameSize uint32 // 最大帧长度(不含分隔符)
+}
+
+// NewDelimiterFramer 创建分隔符分帧器
+func NewDelimiterFramer(delimiter []byte, maxFrameSize uint32) *DelimiterFramer {
+	if len(delimiter) == 0 {
+		delimiter = []byte{'\n'}
+	}
+
+	return &DelimiterFramer{
+		delimiter:    delimiter,
+		maxFrameSize: maxFrameSize,
+	}
+}
+
+// ReadFrame 读取一帧
+func (f *DelimiterFramer) ReadFrame(r io.Reader) ([]byte, error) {
+	br := toByteReader(r)
+	data := make([]byte, 0, 64)
+
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, b)
+
+		if bytes.HasSuffix(data, f.delimiter) {
+			return data[:len(data)-len(f.delimiter)], nil
+		}
+
+		if uint64(len(data)) > uint64(f.maxFrameSize)+uint64(len(f.delimiter)) {
+			return nil, fmt.Errorf("%w: 超过%d字节仍未读到分隔符", ErrFrameTooLarge, f.maxFrameSize)
+		}
+	}
+}
+
+// WriteFrame 写入一帧(数据中不应包含分隔符)
+func (f *DelimiterFramer) WriteFrame(w io.Writer, data []byte) error {
+	if uint64(len(data)) > uint64(f.maxFrameSize) {
+		return fmt.Errorf("%w: %d > %d", ErrFrameTooLarge, len(data), f.maxFrameSize)
+	}
+
+	buf := make([]byte, 0, len(data)+len(f.delimiter))
+	buf = append(buf, data...)
+	buf = append(buf, f.delimiter...)
+
+	_, err := w.Write(buf)
+	return err
+}
+
+// checkFrameSize 检查帧长度
+func checkFrameSize(size uint64, maxFrameSize uint32) error {
+	if size == 0 {
+		return ErrEmptyFrame
+	}
+	if size > uint64(maxFrameSize) {
+		return fmt.Errorf("%w: %d > %d", ErrFrameTooLarge, size, maxFrameSize)
+	}
+	return nil
+}
+
+// IsFrameError 是否为分帧协议错误(区别于连接关闭等 IO 错误)
+func IsFrameError(err error) bool {
+	return errors.Is(err, ErrFrameTooLarge) || errors.Is(err, ErrEmptyFrame)
+}
+
+// byteReader 单字节读取适配器, 不会多读数据, 可安全用于无缓冲的流
+type byteReader struct {
+	r   io.Reader
+	buf [1]byte
+}
+
+// ReadByte 读取一个字节
+func (b *byteReader) ReadByte() (byte, error) {
+	if _, err := io.ReadFull(b.r, b.buf[:]); err != nil {
+		return 0, err
+	}
+	return b.buf[0], nil
+}
+
+// toByteReader 转换为 io.ByteReader(传入 bufio.Reader 等可避免逐字节系统调用)
+func toByteReader(r io.Reader) io.ByteReader {
+	if br, ok := r.(io.ByteReader); ok {
+		return br
+	}
+	return &byteReader{r: r}
+}
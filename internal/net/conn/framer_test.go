@@ -0,0 +1,204 @@
+package conn
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// TestFramer_RoundTrip 测试各分帧器的读写往返
+func TestFramer_RoundTrip(t *testing.T) {
+	framers := map[string]Framer{
+		"uint16":    NewLengthFramer(2, binary.BigEndian, DefaultMaxFrameSize),
+		"uint32":    NewLengthFramer(4, binary.BigEndian, DefaultMaxFrameSize),
+		"uint32_le": NewLengthFramer(4, binary.LittleEndian, DefaultMaxFrameSize),
+		"varint":    NewVarintFramer(DefaultMaxFrameSize),
+		"delimiter": NewDelimiterFramer([]byte("\r\n"), DefaultMaxFrameSize),
+	}
+
+	payloads := [][]byte{
+		[]byte("hello"),
+		[]byte("a"),
+		bytes.Repeat([]byte("x"), 300), // varint 需要2字节长度
+	}
+
+	for name, f := range framers {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			for _, p := range payloads {
+				if err := f.WriteFrame(&buf, p); err != nil {
+					t.Fatalf("写入帧失败: %v", err)
+				}
+			}
+
+			// 使用带缓冲和不带缓冲的读取各验证一次
+			raw := buf.Bytes()
+			readers := []struct {
+				name string
+				r    func() io.Reader
+			}{
+				{"bufio", func() io.Reader { return bufio.NewReader(bytes.NewReader(raw)) }},
+				{"plain", func() io.Reader { return &plainReader{data: raw} }},
+			}
+
+			for _, rd := range readers {
+				r := rd.r()
+				for _, p := range payloads {
+					data, err := f.ReadFrame(r)
+					if err != nil {
+						t.Fatalf("[%s] 读取帧失败: %v", rd.name, err)
+					}
+					if !bytes.Equal(data, p) {
+						t.Errorf("[%s] 帧内容不匹配: 期望 %q, 实际 %q", rd.name, p, data)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestFramer_MaxFrameSize 测试最大帧长度限制
+func TestFramer_MaxFrameSize(t *testing.T) {
+	framers := map[string]Framer{
+		"uint32":    NewLengthFramer(4, binary.BigEndian, 8),
+		"varint":    NewVarintFramer(8),
+		"delimiter": NewDelimiterFramer([]byte("\n"), 8),
+	}
+
+	for name, f := range framers {
+		t.Run(name, func(t *testing.T) {
+			// 写入超长帧应失败
+			var buf bytes.Buffer
+			if err := f.WriteFrame(&buf, make([]byte, 9)); !errors.Is(err, ErrFrameTooLarge) {
+				t.Errorf("期望 ErrFrameTooLarge, 实际 = %v", err)
+			}
+
+			// 使用更宽松的分帧器写入, 读取时应被拒绝
+			var loose Framer
+			switch name {
+			case "uint32":
+				loose = NewLengthFramer(4, binary.BigEndian, 1024)
+			case "varint":
+				loose = NewVarintFramer(1024)
+			default:
+				loose = NewDelimiterFramer([]byte("\n"), 1024)
+			}
+			buf.Reset()
+			if err := loose.WriteFrame(&buf, bytes.Repeat([]byte("y"), 32)); err != nil {
+				t.Fatalf("写入帧失败: %v", err)
+			}
+			if _, err := f.ReadFrame(&buf); !IsFrameError(err) {
+				t.Errorf("期望分帧错误, 实际 = %v", err)
+			}
+		})
+	}
+}
+
+// TestLengthFramer_Uint16Cap 测试2字节长度前缀的上限
+func TestLengthFramer_Uint16Cap(t *testing.T) {
+	f := NewLengthFramer(2, binary.BigEndian, DefaultMaxFrameSize)
+
+	var buf bytes.Buffer
+	if err := f.WriteFrame(&buf, make([]byte, 70000)); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("超过65535字节应返回 ErrFrameTooLarge, 实际 = %v", err)
+	}
+}
+
+// TestFramer_EmptyFrame 测试空帧
+func TestFramer_EmptyFrame(t *testing.T) {
+	f := DefaultFramer()
+
+	var buf bytes.Buffer
+	if err := f.WriteFrame(&buf, nil); !errors.Is(err, ErrEmptyFrame) {
+		t.Errorf("期望 ErrEmptyFrame, 实际 = %v", err)
+	}
+
+	// 长度为0的帧头
+	buf.Write([]byte{0, 0, 0, 0})
+	if _, err := f.ReadFrame(&buf); !errors.Is(err, ErrEmptyFrame) {
+		t.Errorf("期望 ErrEmptyFrame, 实际 = %v", err)
+	}
+}
+
+// TestFramerConfig_NewFramer 测试根据配置创建分帧器
+func TestFramerConfig_NewFramer(t *testing.T) {
+	tests := []struct {
+		cfg     FramerConfig
+		want    string
+		wantErr bool
+	}{
+		{cfg: FramerConfig{}, want: "*conn.LengthFramer"},
+		{cfg: FramerConfig{Type: FramerUint16}, want: "*conn.LengthFramer"},
+		{cfg: FramerConfig{Type: FramerVarint}, want: "*conn.VarintFramer"},
+		{cfg: FramerConfig{Type: FramerDelimiter, Delimiter: "|"}, want: "*conn.DelimiterFramer"},
+		{cfg: FramerConfig{Type: "unknown"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		f, err := tt.cfg.NewFramer()
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("类型 %q 期望返回错误", tt.cfg.Type)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("创建分帧器失败: %v", err)
+		}
+		if got := fmt.Sprintf("%T", f); got != tt.want {
+			t.Errorf("类型 %q 期望 %s, 实际 %s", tt.cfg.Type, tt.want, got)
+		}
+	}
+
+	// 小端序配置
+	f, _ := (&FramerConfig{LittleEndian: true}).NewFramer()
+	var buf bytes.Buffer
+	_ = f.WriteFrame(&buf, []byte("ab"))
+	if !bytes.Equal(buf.Bytes()[:4], []byte{2, 0, 0, 0}) {
+		t.Errorf("小端序长度前缀错误: %v", buf.Bytes()[:4])
+	}
+}
+
+// TestFramerConfig_Resolve 测试已设置的分帧器优先于配置
+func TestFramerConfig_Resolve(t *testing.T) {
+	cfg := &FramerConfig{Type: FramerDelimiter}
+
+	f, err := cfg.Resolve(NewVarintFramer(1024))
+	if err != nil {
+		t.Fatalf("确定分帧器失败: %v", err)
+	}
+	if _, ok := f.(*VarintFramer); !ok {
+		t.Errorf("期望优先使用已设置的分帧器, 实际 %T", f)
+	}
+
+	f, err = cfg.Resolve(nil)
+	if err != nil {
+		t.Fatalf("确定分帧器失败: %v", err)
+	}
+	if _, ok := f.(*DelimiterFramer); !ok {
+		t.Errorf("期望按配置创建分隔符分帧器, 实际 %T", f)
+	}
+
+	if _, err := (&FramerConfig{Type: "unknown"}).Resolve(nil); err == nil {
+		t.Error("不支持的分帧方式应返回错误")
+	}
+}
+
+// plainReader 不实现 io.ByteReader 的读取器, 每次最多返回1字节
+type plainReader struct {
+	data []byte
+}
+
+// Read 读取
+func (r *plainReader) Read(b []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(b[:1], r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
@@ -47,7 +47,15 @@ func (c *NetGrpcClient) connect() error {
 		}),
 	}
 
-	conn, err := grpc.Dial(c.cnf.Host, opts...)
+	if c.cnf.Resolver != nil {
+		opts = append(opts, grpc.WithResolvers(c.cnf.Resolver))
+	}
+	if c.cnf.LoadBalancingPolicy != "" {
+		opts = append(opts, grpc.WithDefaultServiceConfig(fmt.Sprintf(`{"loadBalancingConfig":[{%q:{}}]}`, c.cnf.LoadBalancingPolicy)))
+	}
+
+	target := c.cnf.GetTarget()
+	conn, err := grpc.Dial(target, opts...)
 	if err != nil {
 		return fmt.Errorf("连接失败: %w", err)
 	}
@@ -57,7 +65,7 @@ func (c *NetGrpcClient) connect() error {
 	c.isStop = false
 	c.mu.Unlock()
 
-	c.log.Infof("gRPC客户端连接成功: %s", c.cnf.Host)
+	c.log.Infof("gRPC客户端连接成功: %s", target)
 
 	go c.watchConnection()
 
@@ -67,15 +75,11 @@ func (c *NetGrpcClient) connect() error {
 // watchConnection 监控连接状态
 func (c *NetGrpcClient) watchConnection() {
 	for {
-		if c.isStop {
-			return
-		}
-
 		c.mu.RLock()
-		conn := c.conn
+		conn, isStop := c.conn, c.isStop
 		c.mu.RUnlock()
 
-		if conn == nil {
+		if isStop || conn == nil {
 			return
 		}
 
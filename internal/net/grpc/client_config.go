@@ -2,17 +2,30 @@ package grpc
 
 import (
 	"time"
+
+	"google.golang.org/grpc/resolver"
 )
 
 // ClientConfig gRPC客户端配置
 type ClientConfig struct {
-	Name             string                      // 客户端名称
-	Host             string                      // 服务地址
-	KeepAliveTime    time.Duration               // keepalive时间间隔
-	KeepAliveTimeout time.Duration               // keepalive超时时间
-	ReconnectEnabled bool                        // 是否启用重连
-	ReconnectDelay   time.Duration               // 重连间隔
-	MaxReconnect     int                         // 最大重连次数
-	OnReconnect      func(client *NetGrpcClient) // 重连成功回调
-	OnDisconnect     func(client *NetGrpcClient) // 断开连接回调
+	Name                string                      // 客户端名称
+	Host                string                      // 服务地址
+	Target              string                      // 解析目标，如 etcd:///fight，设置后替代 Host
+	Resolver            resolver.Builder            // 名称解析器，未设置时使用 RegisterResolver 全局注册的解析器
+	LoadBalancingPolicy string                      // 负载均衡策略，如 round_robin、pick_first，默认 pick_first
+	KeepAliveTime       time.Duration               // keepalive时间间隔
+	KeepAliveTimeout    time.Duration               // keepalive超时时间
+	ReconnectEnabled    bool                        // 是否启用重连
+	ReconnectDelay      time.Duration               // 重连间隔
+	MaxReconnect        int                         // 最大重连次数
+	OnReconnect         func(client *NetGrpcClient) // 重连成功回调
+	OnDisconnect        func(client *NetGrpcClient) // 断开连接回调
+}
+
+// GetTarget 获取连接目标, 未设置 Target 时使用 Host
+func (c *ClientConfig) GetTarget() string {
+	if c.Target != "" {
+		return c.Target
+	}
+	return c.Host
 }
@@ -0,0 +1,118 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spelens-gud/logger"
+	"github.com/spelens-gud/trunk/internal/registry"
+	"google.golang.org/grpc/attributes"
+	"google.golang.org/grpc/resolver"
+)
+
+const (
+	// SchemeEtcd etcd 注册中心的解析前缀, 如 etcd:///fight
+	SchemeEtcd = "etcd"
+	// SchemeConsul consul 注册中心的解析前缀, 如 consul:///fight
+	SchemeConsul = "consul"
+	// SchemeNacos nacos 注册中心的解析前缀, 如 nacos:///fight
+	SchemeNacos = "nacos"
+)
+
+// instanceKey 地址属性中保存服务实例的键
+type instanceKey struct{}
+
+// ResolverBuilder 基于注册中心的 gRPC 名称解析, 实例上下线时推送地址给 gRPC
+type ResolverBuilder struct {
+	scheme    string             // 解析前缀
+	discovery registry.Discovery // 服务发现
+	prefix    string             // 服务名前缀
+	log       logger.ILogger     // 日志句柄
+}
+
+// NewResolverBuilder 创建解析器, prefix 为服务名前缀(如 etcd 的 "/services/", 解析 etcd:///fight 时发现 /services/fight)
+func NewResolverBuilder(scheme string, discovery registry.Discovery, prefix string, log logger.ILogger) *ResolverBuilder {
+	return &ResolverBuilder{
+		scheme:    scheme,
+		discovery: discovery,
+		prefix:    prefix,
+		log:       log,
+	}
+}
+
+// RegisterResolver 全局注册解析器, 之后 grpc.Dial("scheme:///service") 即可使用
+func RegisterResolver(scheme string, discovery registry.Discovery, prefix string, log logger.ILogger) {
+	resolver.Register(NewResolverBuilder(scheme, discovery, prefix, log))
+}
+
+// InstanceFromAddress 获取地址对应的服务实例
+func InstanceFromAddress(addr resolver.Address) (registry.ServiceInstance, bool) {
+	instance, ok := addr.BalancerAttributes.Value(instanceKey{}).(*registry.ServiceInstance)
+	if !ok {
+		return registry.ServiceInstance{}, false
+	}
+	return *instance, true
+}
+
+// Scheme 解析前缀
+func (b *ResolverBuilder) Scheme() string {
+	return b.scheme
+}
+
+// Build 订阅服务并创建解析器
+func (b *ResolverBuilder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	name := b.prefix + target.Endpoint()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := b.discovery.Subscribe(ctx, name)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("订阅服务失败: %w", err)
+	}
+
+	r := &registryResolver{cancel: cancel}
+	go logger.WithRecover(b.log, func() {
+		for instances := range ch {
+			if err := updateState(cc, name, instances); err != nil {
+				b.log.Warnf("更新gRPC地址失败，服务: %s, 错误: %v", name, err)
+			}
+		}
+	})
+
+	return r, nil
+}
+
+// updateState 推送健康且未在下线的实例的地址, 没有可用实例时上报错误
+func updateState(cc resolver.ClientConn, name string, instances []registry.ServiceInstance) error {
+	addrs := make([]resolver.Address, 0, len(instances))
+	for i := range instances {
+		if !instances[i].Healthy || instances[i].IsDraining() {
+			continue
+		}
+		// 属性值需可比较, 保存实例指针
+		addrs = append(addrs, resolver.Address{
+			Addr:               instances[i].Endpoint(),
+			BalancerAttributes: attributes.New(instanceKey{}, &instances[i]),
+		})
+	}
+
+	if len(addrs) == 0 {
+		err := fmt.Errorf("服务 %s 没有可用实例", name)
+		cc.ReportError(err)
+		return err
+	}
+	return cc.UpdateState(resolver.State{Addresses: addrs})
+}
+
+// registryResolver 基于注册中心订阅的解析器
+type registryResolver struct {
+	cancel context.CancelFunc
+}
+
+// ResolveNow 订阅会主动推送变化, 无需处理
+func (r *registryResolver) ResolveNow(resolver.ResolveNowOptions) {}
+
+// Close 取消订阅
+func (r *registryResolver) Close() {
+	r.cancel()
+}
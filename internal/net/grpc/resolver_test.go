@@ -0,0 +1,170 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/spelens-gud/assert"
+	"github.com/spelens-gud/logger"
+	"github.com/spelens-gud/trunk/internal/net/message"
+	"github.com/spelens-gud/trunk/internal/registry"
+	"google.golang.org/grpc/resolver"
+)
+
+// fakeClientConn 记录推送的地址
+type fakeClientConn struct {
+	resolver.ClientConn
+	state resolver.State
+	err   error
+}
+
+func (c *fakeClientConn) UpdateState(state resolver.State) error {
+	c.state = state
+	return nil
+}
+
+func (c *fakeClientConn) ReportError(err error) {
+	c.err = err
+}
+
+// TestUpdateState 测试跳过不健康和正在下线的实例
+func TestUpdateState(t *testing.T) {
+	instances := []registry.ServiceInstance{
+		{Address: "10.0.0.1", Port: 9000, Healthy: true},
+		{Address: "10.0.0.2", Port: 9000, Healthy: false},
+		{Address: "10.0.0.3", Port: 9000, Healthy: true, Metadata: map[string]string{registry.MetaState: string(registry.StateDraining)}},
+	}
+
+	cc := &fakeClientConn{}
+	if err := updateState(cc, "fight", instances); err != nil {
+		t.Fatalf("更新地址失败: %v", err)
+	}
+	if len(cc.state.Addresses) != 1 || cc.state.Addresses[0].Addr != "10.0.0.1:9000" {
+		t.Errorf("期望只推送 10.0.0.1:9000, 实际 %+v", cc.state.Addresses)
+	}
+
+	cc = &fakeClientConn{}
+	if err := updateState(cc, "fight", instances[1:]); err == nil || cc.err == nil {
+		t.Error("没有可用实例时应上报错误")
+	}
+}
+
+// startEchoServer 启动测试服务器
+func startEchoServer(t *testing.T, log logger.ILogger, port int) *TestServiceImpl {
+	t.Helper()
+
+	server := &NetGrpcServer{
+		cnf: &ServerConfig{
+			Name:                 fmt.Sprintf("resolver-server-%d", port),
+			Ip:                   "127.0.0.1",
+			Port:                 port,
+			MaxConnections:       10,
+			MaxConcurrentStreams: 100,
+			KeepAliveTime:        10 * time.Second,
+			KeepAliveTimeout:     3 * time.Second,
+		},
+		log: log,
+	}
+	server.New()
+
+	impl := &TestServiceImpl{
+		codec:     message.NewProtobufCodec[*EchoRequest](),
+		respCodec: message.NewProtobufCodec[*EchoResponse](),
+	}
+	RegisterTestServiceServer(server.GetServer(), impl)
+
+	go func() {
+		if err := server.Start(); err != nil {
+			t.Logf("服务器启动错误: %v", err)
+		}
+	}()
+	t.Cleanup(server.Stop)
+
+	if !waitForPort(port, 5*time.Second) {
+		t.Fatal("服务器启动超时")
+	}
+	return impl
+}
+
+// TestIntegration_RegistryResolver 测试通过注册中心解析地址并在实例下线后停止使用
+func TestIntegration_RegistryResolver(t *testing.T) {
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	log, _ := logger.NewLogger(&logger.Config{
+		Level:   "info",
+		Console: true,
+	})
+	assert.SetLogger(log)
+
+	store := registry.NewMemoryStore()
+	factory := registry.NewRegistryFactory(log)
+	publish := func(port int) registry.Registry {
+		reg, err := factory.CreateMemoryRegistry(&registry.MemoryConfig{Key: "/services/fight", Store: store})
+		if err != nil {
+			t.Fatalf("创建注册中心失败: %v", err)
+		}
+		reg.Publisher(fmt.Sprintf("127.0.0.1:%d", port))
+		return reg
+	}
+
+	first := startEchoServer(t, log, 60010)
+	second := startEchoServer(t, log, 60011)
+	firstReg := publish(60010)
+	secondReg := publish(60011)
+	defer secondReg.Close()
+
+	discovery, _ := factory.CreateMemoryRegistry(&registry.MemoryConfig{Store: store})
+	defer discovery.Close()
+
+	client := &NetGrpcClient{
+		cnf: &ClientConfig{
+			Name:                "resolver-client",
+			Target:              "etcd:///fight",
+			Resolver:            NewResolverBuilder(SchemeEtcd, discovery, "/services/", log),
+			LoadBalancingPolicy: "round_robin",
+			KeepAliveTime:       10 * time.Second,
+			KeepAliveTimeout:    3 * time.Second,
+		},
+		log: log,
+	}
+	client.New()
+	if err := client.Start(); err != nil {
+		t.Fatalf("客户端连接失败: %v", err)
+	}
+	defer client.Close()
+
+	echo := NewTestServiceClient(client.GetConn())
+	call := func(n int) {
+		for i := 0; i < n; i++ {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			_, err := echo.Echo(ctx, &EchoRequest{Message: "hi"})
+			cancel()
+			if err != nil {
+				t.Fatalf("调用失败: %v", err)
+			}
+		}
+	}
+
+	// round_robin 需要等待两个子连接都就绪
+	deadline := time.Now().Add(5 * time.Second)
+	for len(first.GetReceivedMessages()) == 0 || len(second.GetReceivedMessages()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("请求未分散到两个实例: %d, %d", len(first.GetReceivedMessages()), len(second.GetReceivedMessages()))
+		}
+		call(10)
+	}
+
+	// 第一个实例注销后不再收到请求(服务器仍在运行)
+	firstReg.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	before := len(first.GetReceivedMessages())
+	call(20)
+	if after := len(first.GetReceivedMessages()); after != before {
+		t.Errorf("注销后的实例不应再收到请求: %d -> %d", before, after)
+	}
+}
@@ -1,6 +1,7 @@
 package grpc
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"sync"
@@ -22,6 +23,8 @@ type NetGrpcServer struct {
 	totalAccepted int64
 	totalRejected int64
 	services      sync.Map
+	draining      atomic.Bool   // 正在下线, 停止接受新的连接和请求
+	drained       chan struct{} // 下线完成, 进行中的请求已全部结束
 }
 
 // ServerStats 服务器统计信息
@@ -35,6 +38,7 @@ type ServerStats struct {
 func (s *NetGrpcServer) New() {
 	s.stopChan = make(chan chan struct{})
 	s.services = sync.Map{}
+	s.drained = make(chan struct{})
 
 	// 配置gRPC服务器选项
 	opts := []grpc.ServerOption{
@@ -91,7 +95,12 @@ func (s *NetGrpcServer) handleStop() {
 	stopDone := <-s.stopChan
 
 	if s.server != nil {
-		s.server.GracefulStop()
+		// 下线超时后停止时不再等待剩余的请求
+		if s.draining.Load() {
+			s.server.Stop()
+		} else {
+			s.server.GracefulStop()
+		}
 	}
 
 	if s.listener != nil {
@@ -101,6 +110,29 @@ func (s *NetGrpcServer) handleStop() {
 	close(stopDone)
 }
 
+// Drain 停止接受新连接和请求, 已建立的连接收到 GOAWAY 后由客户端迁移到其他实例
+func (s *NetGrpcServer) Drain() {
+	if s.draining.Swap(true) {
+		return
+	}
+
+	s.log.Infof("开始下线，停止接受新的连接和请求")
+	go func() {
+		s.server.GracefulStop()
+		close(s.drained)
+	}()
+}
+
+// WaitIdle 等待进行中的请求全部结束, ctx 结束时返回 ctx 的错误
+func (s *NetGrpcServer) WaitIdle(ctx context.Context) error {
+	select {
+	case <-s.drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // Stop 停止服务器
 func (s *NetGrpcServer) Stop() {
 	stopDone := make(chan struct{}, 1)
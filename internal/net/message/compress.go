@@ -0,0 +1,233 @@
+package message
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+const (
+	// DefaultCompressThreshold 默认压缩阈值, 小于该长度的消息体不压缩
+	DefaultCompressThreshold = 1024
+	// DefaultMaxDecompressedSize 默认解压后最大长度(16MB), 防止压缩炸弹
+	DefaultMaxDecompressedSize = 16 * 1024 * 1024
+)
+
+// ErrDecompressedTooLarge 解压后数据超过上限
+var ErrDecompressedTooLarge = errors.New("解压后数据超过上限")
+
+// HeaderCodec 需要读写消息头的编解码器(如压缩、加密), Message 编解码时优先使用
+type HeaderCodec[T any] interface {
+	Codec[T]
+	// EncodeWithHeader 编码消息体, 可修改消息头标志位
+	EncodeWithHeader(h *Header, msg T) ([]byte, error)
+	// DecodeWithHeader 根据消息头解码消息体
+	DecodeWithHeader(h Header, data []byte) (T, error)
+}
+
+// Compressor 压缩算法
+type Compressor interface {
+	// Compress 压缩
+	Compress(data []byte) ([]byte, error)
+	// Decompress 解压, 解压后超过 maxSize 时返回 ErrDecompressedTooLarge
+	Decompress(data []byte, maxSize int) ([]byte, error)
+}
+
+// GzipCompressor gzip 压缩
+type GzipCompressor struct {
+	level   int
+	writers sync.Pool
+}
+
+// NewGzipCompressor 创建 gzip 压缩, level 取值同 compress/gzip
+func NewGzipCompressor(level int) *GzipCompressor {
+	return &GzipCompressor{level: level}
+}
+
+// Compress 压缩
+func (c *GzipCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w, ok := c.writers.Get().(*gzip.Writer)
+	if ok {
+		w.Reset(&buf)
+	} else {
+		var err error
+		if w, err = gzip.NewWriterLevel(&buf, c.level); err != nil {
+			return nil, err
+		}
+	}
+	defer c.writers.Put(w)
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress 解压
+func (c *GzipCompressor) Decompress(data []byte, maxSize int) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return readLimited(r, maxSize)
+}
+
+// FlateCompressor flate 压缩(无 gzip 头部, 体积更小)
+type FlateCompressor struct {
+	level   int
+	writers sync.Pool
+}
+
+// NewFlateCompressor 创建 flate 压缩, level 取值同 compress/flate
+func NewFlateCompressor(level int) *FlateCompressor {
+	return &FlateCompressor{level: level}
+}
+
+// Compress 压缩
+func (c *FlateCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w, ok := c.writers.Get().(*flate.Writer)
+	if ok {
+		w.Reset(&buf)
+	} else {
+		var err error
+		if w, err = flate.NewWriter(&buf, c.level); err != nil {
+			return nil, err
+		}
+	}
+	defer c.writers.Put(w)
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress 解压
+func (c *FlateCompressor) Decompress(data []byte, maxSize int) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+
+	return readLimited(r, maxSize)
+}
+
+// readLimited 读取全部数据, 超过 maxSize 时返回错误
+func readLimited(r io.Reader, maxSize int) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, int64(maxSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxSize {
+		return nil, fmt.Errorf("%w: > %d", ErrDecompressedTooLarge, maxSize)
+	}
+	return data, nil
+}
+
+// CompressedCodec 压缩编解码器, 包装任意 Codec
+//
+// 编码后的消息体超过阈值时压缩, 并在消息头设置 FlagCompressed(使用 v1 消息头);
+// 对端需配置相同的压缩算法。直接作为 Codec 使用时没有消息头, 不进行压缩
+type CompressedCodec[T any] struct {
+	codec               Codec[T]   // 被包装的编解码器
+	compressor          Compressor // 压缩算法
+	threshold           int        // 压缩阈值
+	maxDecompressedSize int        // 解压后最大长度
+}
+
+// NewCompressedCodec 创建压缩编解码器, compressor 为 nil 时使用默认级别的 gzip, threshold <= 0 时使用默认阈值
+func NewCompressedCodec[T any](codec Codec[T], compressor Compressor, threshold int) *CompressedCodec[T] {
+	if compressor == nil {
+		compressor = NewGzipCompressor(gzip.DefaultCompression)
+	}
+	if threshold <= 0 {
+		threshold = DefaultCompressThreshold
+	}
+
+	return &CompressedCodec[T]{
+		codec:               codec,
+		compressor:          compressor,
+		threshold:           threshold,
+		maxDecompressedSize: DefaultMaxDecompressedSize,
+	}
+}
+
+// SetMaxDecompressedSize 设置解压后最大长度
+func (c *CompressedCodec[T]) SetMaxDecompressedSize(size int) *CompressedCodec[T] {
+	c.maxDecompressedSize = size
+	return c
+}
+
+// Encode 编码(不压缩)
+func (c *CompressedCodec[T]) Encode(msg T) ([]byte, error) {
+	return c.codec.Encode(msg)
+}
+
+// Decode 解码(不解压)
+func (c *CompressedCodec[T]) Decode(data []byte) (T, error) {
+	return c.codec.Decode(data)
+}
+
+// EncodeWithHeader 编码, 超过阈值且压缩有收益时压缩并设置 FlagCompressed; 被包装的是 HeaderCodec 时先执行其编码
+func (c *CompressedCodec[T]) EncodeWithHeader(h *Header, msg T) ([]byte, error) {
+	h.ClearFlag(FlagCompressed)
+
+	var data []byte
+	var err error
+	if hc, ok := c.codec.(HeaderCodec[T]); ok {
+		data, err = hc.EncodeWithHeader(h, msg)
+	} else {
+		data, err = c.codec.Encode(msg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < c.threshold {
+		return data, nil
+	}
+
+	compressed, err := c.compressor.Compress(data)
+	if err != nil {
+		return nil, fmt.Errorf("压缩失败: %w", err)
+	}
+
+	// 压缩后没有变小则发送原始数据
+	if len(compressed) >= len(data) {
+		return data, nil
+	}
+
+	h.SetFlag(FlagCompressed)
+	return compressed, nil
+}
+
+// DecodeWithHeader 解码, 消息头设置了 FlagCompressed 时先解压, 再交给被包装的编解码器
+func (c *CompressedCodec[T]) DecodeWithHeader(h Header, data []byte) (T, error) {
+	if h.HasFlag(FlagCompressed) {
+		decompressed, err := c.compressor.Decompress(data, c.maxDecompressedSize)
+		if err != nil {
+			var zero T
+			return zero, fmt.Errorf("解压失败: %w", err)
+		}
+		data = decompressed
+	}
+
+	if hc, ok := c.codec.(HeaderCodec[T]); ok {
+		return hc.DecodeWithHeader(h, data)
+	}
+	return c.codec.Decode(data)
+}
@@ -0,0 +1,270 @@
+package message_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/spelens-gud/trunk/internal/net/message"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// InventoryItem 背包物品
+type InventoryItem struct {
+	ItemID   uint32 `json:"item_id"`
+	Name     string `json:"name"`
+	Count    int    `json:"count"`
+	Quality  string `json:"quality"`
+	Equipped bool   `json:"equipped"`
+}
+
+// Inventory 背包数据
+type Inventory struct {
+	UserID uint64          `json:"user_id"`
+	Items  []InventoryItem `json:"items"`
+}
+
+// newInventory 生成测试背包数据
+func newInventory(n int) Inventory {
+	inv := Inventory{UserID: 12345}
+	for i := 0; i < n; i++ {
+		inv.Items = append(inv.Items, InventoryItem{
+			ItemID:   uint32(10000 + i),
+			Name:     fmt.Sprintf("item_%d", i%20),
+			Count:    i % 99,
+			Quality:  []string{"common", "rare", "epic", "legendary"}[i%4],
+			Equipped: i%7 == 0,
+		})
+	}
+	return inv
+}
+
+// newInventoryProto 生成测试背包数据(Protobuf)
+func newInventoryProto(n int) *structpb.Struct {
+	items := make([]any, 0, n)
+	for _, item := range newInventory(n).Items {
+		items = append(items, map[string]any{
+			"item_id":  float64(item.ItemID),
+			"name":     item.Name,
+			"count":    float64(item.Count),
+			"quality":  item.Quality,
+			"equipped": item.Equipped,
+		})
+	}
+
+	s, err := structpb.NewStruct(map[string]any{"user_id": float64(12345), "items": items})
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// TestCompressedCodec_Threshold 测试阈值以上才压缩并设置标志位
+func TestCompressedCodec_Threshold(t *testing.T) {
+	codec := message.NewCompressedCodec[Inventory](message.NewJSONCodec[Inventory](), nil, 512)
+
+	// 小消息不压缩, 保持 v0 消息头
+	small := message.NewMessage[Inventory](codec, 1, 2, 3)
+	small.SetBody(newInventory(1))
+	data, err := small.Encode()
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	header, _, _ := message.DecodeHeader(data)
+	if header.HasFlag(message.FlagCompressed) || header.Version != message.HeaderV0 {
+		t.Errorf("小于阈值的消息不应压缩: %+v", header)
+	}
+
+	// 大消息压缩
+	large := message.NewMessage[Inventory](codec, 1, 2, 3)
+	large.SetBody(newInventory(200))
+	data, err = large.Encode()
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	header, body, _ := message.DecodeHeader(data)
+	if !header.HasFlag(message.FlagCompressed) {
+		t.Fatal("超过阈值的消息应压缩")
+	}
+
+	raw, _ := message.NewJSONCodec[Inventory]().Encode(newInventory(200))
+	if len(body) >= len(raw) {
+		t.Errorf("压缩后应更小: 原始 %d, 压缩 %d", len(raw), len(body))
+	}
+
+	decoded := message.NewMessage[Inventory](codec, 0, 0, 0)
+	if err := decoded.Decode(data); err != nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+	if len(decoded.GetBody().Items) != 200 || decoded.GetBody().Items[199].ItemID != 10199 {
+		t.Errorf("解码内容错误: %d 个物品", len(decoded.GetBody().Items))
+	}
+}
+
+// traceCodec 编码时把链路追踪ID写入消息头扩展, 解码时要求扩展存在的 HeaderCodec
+type traceCodec struct {
+	message.Codec[Inventory]
+	traceID string
+}
+
+// EncodeWithHeader 编码并写入链路追踪ID
+func (c *traceCodec) EncodeWithHeader(h *message.Header, msg Inventory) ([]byte, error) {
+	h.SetExtension(message.ExtTraceID, []byte(c.traceID))
+	return c.Encode(msg)
+}
+
+// DecodeWithHeader 校验链路追踪ID后解码
+func (c *traceCodec) DecodeWithHeader(h message.Header, data []byte) (Inventory, error) {
+	if traceID, ok := h.GetExtension(message.ExtTraceID); !ok || string(traceID) != c.traceID {
+		return Inventory{}, fmt.Errorf("链路追踪ID不符: %q", traceID)
+	}
+	return c.Decode(data)
+}
+
+// TestCompressedCodec_HeaderCodec 测试包装 HeaderCodec 时转发消息头, 扩展字段随压缩消息往返
+func TestCompressedCodec_HeaderCodec(t *testing.T) {
+	inner := &traceCodec{Codec: message.NewJSONCodec[Inventory](), traceID: "trace-1"}
+	codec := message.NewCompressedCodec[Inventory](inner, nil, 512)
+
+	msg := message.NewMessage[Inventory](codec, 1, 2, 3)
+	msg.Header.SetExtension(message.ExtUserID, []byte("10001"))
+	msg.SetBody(newInventory(200))
+	data, err := msg.Encode()
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+
+	header, _, err := message.DecodeHeader(data)
+	if err != nil {
+		t.Fatalf("解码消息头失败: %v", err)
+	}
+	if !header.HasFlag(message.FlagCompressed) {
+		t.Error("超过阈值的消息应压缩")
+	}
+	if traceID, ok := header.GetExtension(message.ExtTraceID); !ok || string(traceID) != "trace-1" {
+		t.Errorf("被包装的编解码器写入的扩展丢失: %q", traceID)
+	}
+
+	decoded := message.NewMessage[Inventory](codec, 0, 0, 0)
+	if err := decoded.Decode(data); err != nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+	decodedHeader := decoded.GetHeader()
+	if userID, ok := decodedHeader.GetExtension(message.ExtUserID); !ok || string(userID) != "10001" {
+		t.Errorf("消息头扩展丢失: %q", userID)
+	}
+	if len(decoded.GetBody().Items) != 200 {
+		t.Errorf("解码内容错误: %d 个物品", len(decoded.GetBody().Items))
+	}
+}
+
+// TestCompressedCodec_Compressors 测试各压缩算法往返
+func TestCompressedCodec_Compressors(t *testing.T) {
+	compressors := map[string]message.Compressor{
+		"gzip":  message.NewGzipCompressor(gzip.BestSpeed),
+		"flate": message.NewFlateCompressor(flate.DefaultCompression),
+	}
+
+	for name, compressor := range compressors {
+		t.Run(name, func(t *testing.T) {
+			codec := message.NewCompressedCodec[*structpb.Struct](message.NewProtobufCodec[*structpb.Struct](), compressor, 0)
+
+			// 复用压缩器, 验证池化的 writer 可以重复使用
+			for i := 0; i < 3; i++ {
+				msg := message.NewMessage[*structpb.Struct](codec, 1, 2, 3)
+				msg.SetBody(newInventoryProto(100))
+				data, err := msg.Encode()
+				if err != nil {
+					t.Fatalf("编码失败: %v", err)
+				}
+
+				decoded := message.NewMessage[*structpb.Struct](codec, 0, 0, 0)
+				if err := decoded.Decode(data); err != nil {
+					t.Fatalf("解码失败: %v", err)
+				}
+				if !proto.Equal(decoded.GetBody(), newInventoryProto(100)) {
+					t.Error("解码内容不一致")
+				}
+			}
+		})
+	}
+}
+
+// TestCompressedCodec_MaxDecompressedSize 测试解压长度上限
+func TestCompressedCodec_MaxDecompressedSize(t *testing.T) {
+	codec := message.NewCompressedCodec[[]byte](message.NewRawCodec(), nil, 0)
+
+	msg := message.NewMessage[[]byte](codec, 1, 2, 3)
+	msg.SetBody(bytes.Repeat([]byte("a"), 64*1024))
+	data, err := msg.Encode()
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+
+	codec.SetMaxDecompressedSize(1024)
+	decoded := message.NewMessage[[]byte](codec, 0, 0, 0)
+	if err := decoded.Decode(data); !errors.Is(err, message.ErrDecompressedTooLarge) {
+		t.Errorf("期望 ErrDecompressedTooLarge, 实际 %v", err)
+	}
+}
+
+// benchmarkCodec 基准测试编解码并报告消息体大小
+func benchmarkCodec[T any](b *testing.B, codec message.Codec[T], body T) {
+	msg := message.NewMessage[T](codec, 1, 2, 3)
+	msg.SetBody(body)
+
+	data, err := msg.Encode()
+	if err != nil {
+		b.Fatalf("编码失败: %v", err)
+	}
+
+	decoded := message.NewMessage[T](codec, 0, 0, 0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		data, _ := msg.Encode()
+		if err := decoded.Decode(data); err != nil {
+			b.Fatalf("解码失败: %v", err)
+		}
+	}
+
+	// 计时重置会清除自定义指标, 需在循环结束后上报
+	b.ReportMetric(float64(len(data)), "wire-bytes")
+}
+
+// BenchmarkCodec_JSON 对比 JSON 编解码在不同压缩方式下的体积与耗时
+func BenchmarkCodec_JSON(b *testing.B) {
+	inv := newInventory(200)
+	json := message.NewJSONCodec[Inventory]()
+
+	b.Run("plain", func(b *testing.B) { benchmarkCodec[Inventory](b, json, inv) })
+	b.Run("gzip", func(b *testing.B) {
+		benchmarkCodec[Inventory](b, message.NewCompressedCodec[Inventory](json, message.NewGzipCompressor(gzip.DefaultCompression), 0), inv)
+	})
+	b.Run("gzip-speed", func(b *testing.B) {
+		benchmarkCodec[Inventory](b, message.NewCompressedCodec[Inventory](json, message.NewGzipCompressor(gzip.BestSpeed), 0), inv)
+	})
+	b.Run("flate", func(b *testing.B) {
+		benchmarkCodec[Inventory](b, message.NewCompressedCodec[Inventory](json, message.NewFlateCompressor(flate.DefaultCompression), 0), inv)
+	})
+}
+
+// BenchmarkCodec_Protobuf 对比 Protobuf 编解码在不同压缩方式下的体积与耗时
+func BenchmarkCodec_Protobuf(b *testing.B) {
+	inv := newInventoryProto(200)
+	pb := message.NewProtobufCodec[*structpb.Struct]()
+
+	b.Run("plain", func(b *testing.B) { benchmarkCodec[*structpb.Struct](b, pb, inv) })
+	b.Run("gzip", func(b *testing.B) {
+		benchmarkCodec[*structpb.Struct](b, message.NewCompressedCodec[*structpb.Struct](pb, message.NewGzipCompressor(gzip.DefaultCompression), 0), inv)
+	})
+	b.Run("gzip-speed", func(b *testing.B) {
+		benchmarkCodec[*structpb.Struct](b, message.NewCompressedCodec[*structpb.Struct](pb, message.NewGzipCompressor(gzip.BestSpeed), 0), inv)
+	})
+	b.Run("flate", func(b *testing.B) {
+		benchmarkCodec[*structpb.Struct](b, message.NewCompressedCodec[*structpb.Struct](pb, message.NewFlateCompressor(flate.DefaultCompression), 0), inv)
+	})
+}
@@ -0,0 +1,271 @@
+package message
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// replayWindowSize 防重放滑动窗口大小
+const replayWindowSize = 64
+
+var (
+	// ErrReplay 重复或过旧的序列号
+	ErrReplay = errors.New("重放的消息")
+	// ErrInvalidSequence 加密消息的序列号不能为 0
+	ErrInvalidSequence = errors.New("加密消息的序列号不能为0")
+	// ErrSequenceReused 发送的序列号没有递增, 重复使用会导致 nonce 重复
+	ErrSequenceReused = errors.New("加密消息的序列号必须递增")
+	// ErrNotEncrypted 消息未加密
+	ErrNotEncrypted = errors.New("消息未加密")
+	// ErrHeaderRequired 编解码器需要消息头, 只能通过 Message 使用
+	ErrHeaderRequired = errors.New("编解码器需要消息头")
+)
+
+// KeyExchange X25519 密钥交换
+//
+// 握手是未认证的 ECDH, 只能防止被动窃听, 不能防止中间人替换公钥;
+// 需要防中间人时在外层使用 TLS(如 QUIC), 或用预置的服务端密钥对公钥签名
+type KeyExchange struct {
+	priv *ecdh.PrivateKey
+}
+
+// NewKeyExchange 生成临时密钥对
+func NewKeyExchange() (*KeyExchange, error) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("生成密钥失败: %w", err)
+	}
+	return &KeyExchange{priv: priv}, nil
+}
+
+// PublicKey 获取公钥(32字节)
+func (k *KeyExchange) PublicKey() []byte {
+	return k.priv.PublicKey().Bytes()
+}
+
+// Session 根据对端公钥协商会话, isClient 决定收发方向使用的密钥
+func (k *KeyExchange) Session(peerPublic []byte, isClient bool) (*Session, error) {
+	peer, err := ecdh.X25519().NewPublicKey(peerPublic)
+	if err != nil {
+		return nil, fmt.Errorf("无效的对端公钥: %w", err)
+	}
+
+	secret, err := k.priv.ECDH(peer)
+	if err != nil {
+		return nil, fmt.Errorf("密钥协商失败: %w", err)
+	}
+
+	// 以双方公钥作为盐, 两个方向派生不同的密钥
+	clientPub, serverPub := k.PublicKey(), peerPublic
+	if !isClient {
+		clientPub, serverPub = peerPublic, k.PublicKey()
+	}
+	salt := append(append([]byte{}, clientPub...), serverPub...)
+
+	c2s, err := newAEAD(secret, salt, "trunk c2s")
+	if err != nil {
+		return nil, err
+	}
+	s2c, err := newAEAD(secret, salt, "trunk s2c")
+	if err != nil {
+		return nil, err
+	}
+
+	if isClient {
+		return &Session{send: c2s, recv: s2c}, nil
+	}
+	return &Session{send: s2c, recv: c2s}, nil
+}
+
+// newAEAD 派生 AES-256-GCM
+func newAEAD(secret, salt []byte, info string) (cipher.AEAD, error) {
+	key, err := hkdf.Key(sha256.New, secret, salt, info, 32)
+	if err != nil {
+		return nil, fmt.Errorf("派生密钥失败: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Session 加密会话, 每个连接持有独立的会话密钥
+//
+// nonce 由 Header.Sequence 派生, 发送方向的序列号必须严格递增(调用方需按序列号顺序加密);
+// 接收方用滑动窗口拒绝重放
+type Session struct {
+	send    cipher.AEAD // 发送方向
+	recv    cipher.AEAD // 接收方向
+	sentSeq uint64      // 已发送的最大序列号
+	maxSeq  uint64      // 已接收的最大序列号
+	window  uint64      // 最大序列号之前 64 个序列号的接收位图
+	mu      sync.Mutex
+}
+
+// Seal 加密消息体, 消息头的版本、标志位、协议号、服务ID、消息ID、序列号和扩展区作为附加认证数据
+//
+// 序列号不大于已发送的最大序列号时返回 ErrSequenceReused, 避免同一密钥下 nonce 重复
+func (s *Session) Seal(h Header, plaintext []byte) ([]byte, error) {
+	if h.Sequence == 0 {
+		return nil, ErrInvalidSequence
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if h.Sequence <= s.sentSeq {
+		return nil, fmt.Errorf("%w: seq=%d, 已发送 %d", ErrSequenceReused, h.Sequence, s.sentSeq)
+	}
+	s.sentSeq = h.Sequence
+	return s.send.Seal(nil, sequenceNonce(h.Sequence), plaintext, headerAAD(h)), nil
+}
+
+// Open 解密消息体并检查重放
+func (s *Session) Open(h Header, ciphertext []byte) ([]byte, error) {
+	if h.Sequence == 0 {
+		return nil, ErrInvalidSequence
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.isReplay(h.Sequence) {
+		return nil, fmt.Errorf("%w: seq=%d", ErrReplay, h.Sequence)
+	}
+
+	plaintext, err := s.recv.Open(nil, sequenceNonce(h.Sequence), ciphertext, headerAAD(h))
+	if err != nil {
+		return nil, fmt.Errorf("解密失败: %w", err)
+	}
+
+	// 认证通过后才更新窗口, 伪造的消息不会影响窗口
+	s.accept(h.Sequence)
+	return plaintext, nil
+}
+
+// isReplay 序列号是否重复或已滑出窗口
+func (s *Session) isReplay(seq uint64) bool {
+	if seq > s.maxSeq {
+		return false
+	}
+	diff := s.maxSeq - seq
+	if diff >= replayWindowSize {
+		return true
+	}
+	return s.window&(1<<diff) != 0
+}
+
+// accept 记录已接收的序列号
+func (s *Session) accept(seq uint64) {
+	if seq > s.maxSeq {
+		shift := seq - s.maxSeq
+		if shift >= replayWindowSize {
+			s.window = 0
+		} else {
+			s.window <<= shift
+		}
+		s.window |= 1
+		s.maxSeq = seq
+		return
+	}
+	s.window |= 1 << (s.maxSeq - seq)
+}
+
+// sequenceNonce 由序列号派生 12 字节 nonce
+func sequenceNonce(seq uint64) []byte {
+	nonce := make([]byte, 12)
+	putUint64(nonce[4:], seq)
+	return nonce
+}
+
+// headerAAD 消息头附加认证数据(不含加密标志位本身), 扩展区按编码后的格式写入
+func headerAAD(h Header) []byte {
+	extLen := 0
+	for _, ext := range h.Extensions {
+		extLen += 4 + len(ext.Value)
+	}
+
+	aad := make([]byte, 22+extLen)
+	aad[0] = HeaderV1 // 加密消息带 FlagEncrypted, 总是按 v1 编码
+	aad[1] = byte(h.Flags &^ FlagEncrypted)
+	putUint32(aad[2:6], h.ProtocolID)
+	putUint32(aad[6:10], h.ServiceID)
+	putUint32(aad[10:14], h.MessageID)
+	putUint64(aad[14:22], h.Sequence)
+	putExtensions(aad[22:], h.Extensions)
+	return aad
+}
+
+// EncryptedCodec AES-GCM 加密编解码器, 包装任意 Codec(包装 HeaderCodec 时先执行其编码, 如先压缩再加密)
+type EncryptedCodec[T any] struct {
+	codec   Codec[T]
+	session *Session
+}
+
+// NewEncryptedCodec 创建加密编解码器
+func NewEncryptedCodec[T any](codec Codec[T], session *Session) *EncryptedCodec[T] {
+	return &EncryptedCodec[T]{
+		codec:   codec,
+		session: session,
+	}
+}
+
+// Encode 加密需要消息头, 不支持直接编码
+func (c *EncryptedCodec[T]) Encode(msg T) ([]byte, error) {
+	return nil, ErrHeaderRequired
+}
+
+// Decode 加密需要消息头, 不支持直接解码
+func (c *EncryptedCodec[T]) Decode(data []byte) (T, error) {
+	var zero T
+	return zero, ErrHeaderRequired
+}
+
+// EncodeWithHeader 编码并加密, 设置 FlagEncrypted
+func (c *EncryptedCodec[T]) EncodeWithHeader(h *Header, msg T) ([]byte, error) {
+	var data []byte
+	var err error
+	if hc, ok := c.codec.(HeaderCodec[T]); ok {
+		data, err = hc.EncodeWithHeader(h, msg)
+	} else {
+		data, err = c.codec.Encode(msg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := c.session.Seal(*h, data)
+	if err != nil {
+		return nil, err
+	}
+
+	h.SetFlag(FlagEncrypted)
+	return sealed, nil
+}
+
+// DecodeWithHeader 解密并解码
+func (c *EncryptedCodec[T]) DecodeWithHeader(h Header, data []byte) (T, error) {
+	var zero T
+	if !h.HasFlag(FlagEncrypted) {
+		return zero, ErrNotEncrypted
+	}
+
+	plaintext, err := c.session.Open(h, data)
+	if err != nil {
+		return zero, err
+	}
+
+	if hc, ok := c.codec.(HeaderCodec[T]); ok {
+		return hc.DecodeWithHeader(h, plaintext)
+	}
+	return c.codec.Decode(plaintext)
+}
@@ -0,0 +1,263 @@
+package message_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spelens-gud/trunk/internal/net/conn"
+	"github.com/spelens-gud/trunk/internal/net/message"
+)
+
+// newSessionPair 协商一对客户端/服务端会话
+func newSessionPair(t *testing.T) (*message.Session, *message.Session) {
+	client, err := message.NewKeyExchange()
+	if err != nil {
+		t.Fatalf("生成密钥失败: %v", err)
+	}
+	server, err := message.NewKeyExchange()
+	if err != nil {
+		t.Fatalf("生成密钥失败: %v", err)
+	}
+
+	cs, err := client.Session(server.PublicKey(), true)
+	if err != nil {
+		t.Fatalf("客户端协商失败: %v", err)
+	}
+	ss, err := server.Session(client.PublicKey(), false)
+	if err != nil {
+		t.Fatalf("服务端协商失败: %v", err)
+	}
+	return cs, ss
+}
+
+// TestEncryptedCodec_RoundTrip 测试加密往返, 密文不含明文且设置加密标志
+func TestEncryptedCodec_RoundTrip(t *testing.T) {
+	clientSession, serverSession := newSessionPair(t)
+	plain := []byte("secret payload")
+
+	msg := message.NewMessage[[]byte](message.NewEncryptedCodec[[]byte](message.NewRawCodec(), clientSession), 1, 2, 3)
+	msg.SetSequence(1)
+	msg.SetBody(plain)
+	data, err := msg.Encode()
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+
+	header, body, _ := message.DecodeHeader(data)
+	if !header.HasFlag(message.FlagEncrypted) {
+		t.Error("应设置 FlagEncrypted")
+	}
+	if bytes.Contains(body, plain) {
+		t.Error("密文中不应包含明文")
+	}
+
+	decoded := message.NewMessage[[]byte](message.NewEncryptedCodec[[]byte](message.NewRawCodec(), serverSession), 0, 0, 0)
+	if err := decoded.Decode(data); err != nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+	if !bytes.Equal(decoded.GetBody(), plain) {
+		t.Errorf("期望 %q, 实际 %q", plain, decoded.GetBody())
+	}
+
+	// 同方向密钥不同, 客户端不能解密自己发出的消息
+	self := message.NewMessage[[]byte](message.NewEncryptedCodec[[]byte](message.NewRawCodec(), clientSession), 0, 0, 0)
+	if err := self.Decode(data); err == nil {
+		t.Error("发送方向的密钥不应能解密")
+	}
+}
+
+// TestEncryptedCodec_Compressed 测试先压缩后加密
+func TestEncryptedCodec_Compressed(t *testing.T) {
+	clientSession, serverSession := newSessionPair(t)
+
+	encode := message.NewEncryptedCodec[Inventory](message.NewCompressedCodec[Inventory](message.NewJSONCodec[Inventory](), nil, 0), clientSession)
+	msg := message.NewMessage[Inventory](encode, 1, 2, 3)
+	msg.SetSequence(1)
+	msg.SetBody(newInventory(200))
+	data, err := msg.Encode()
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+
+	header, _, _ := message.DecodeHeader(data)
+	if !header.HasFlag(message.FlagCompressed) || !header.HasFlag(message.FlagEncrypted) {
+		t.Errorf("应同时设置压缩和加密标志: %08b", header.Flags)
+	}
+
+	decode := message.NewEncryptedCodec[Inventory](message.NewCompressedCodec[Inventory](message.NewJSONCodec[Inventory](), nil, 0), serverSession)
+	decoded := message.NewMessage[Inventory](decode, 0, 0, 0)
+	if err := decoded.Decode(data); err != nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+	if len(decoded.GetBody().Items) != 200 {
+		t.Errorf("期望 200 个物品, 实际 %d", len(decoded.GetBody().Items))
+	}
+}
+
+// TestSession_Replay 测试重放与过旧序列号被拒绝, 乱序在窗口内可接收
+func TestSession_Replay(t *testing.T) {
+	clientSession, serverSession := newSessionPair(t)
+
+	seal := func(seq uint64) (message.Header, []byte) {
+		h := message.Header{ProtocolID: 1, MessageID: 2, Sequence: seq, Flags: message.FlagEncrypted}
+		data, err := clientSession.Seal(h, []byte("x"))
+		if err != nil {
+			t.Fatalf("加密失败: %v", err)
+		}
+		return h, data
+	}
+
+	h3, c3 := seal(3)
+	h5, c5 := seal(5)
+	h100, c100 := seal(100)
+
+	if _, err := serverSession.Open(h5, c5); err != nil {
+		t.Fatalf("解密失败: %v", err)
+	}
+	if _, err := serverSession.Open(h5, c5); !errors.Is(err, message.ErrReplay) {
+		t.Errorf("重放应返回 ErrReplay, 实际 %v", err)
+	}
+	if _, err := serverSession.Open(h3, c3); err != nil {
+		t.Errorf("窗口内乱序消息应可接收: %v", err)
+	}
+	if _, err := serverSession.Open(h100, c100); err != nil {
+		t.Fatalf("解密失败: %v", err)
+	}
+
+	// 序列号 3 已滑出窗口
+	if _, err := serverSession.Open(h3, c3); !errors.Is(err, message.ErrReplay) {
+		t.Errorf("过旧序列号应返回 ErrReplay, 实际 %v", err)
+	}
+}
+
+// TestSession_Tamper 测试篡改消息体或消息头后解密失败, 且不影响窗口
+func TestSession_Tamper(t *testing.T) {
+	clientSession, serverSession := newSessionPair(t)
+
+	h := message.Header{ProtocolID: 1, MessageID: 2, Sequence: 1}
+	data, err := clientSession.Seal(h, []byte("payload"))
+	if err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+
+	tampered := append([]byte{}, data...)
+	tampered[0] ^= 0xff
+	if _, err := serverSession.Open(h, tampered); err == nil {
+		t.Error("篡改消息体应解密失败")
+	}
+
+	forged := h
+	forged.MessageID = 3
+	if _, err := serverSession.Open(forged, data); err == nil {
+		t.Error("篡改消息头应解密失败")
+	}
+
+	forged = h
+	forged.SetExtension(message.ExtUserID, []byte("10086"))
+	if _, err := serverSession.Open(forged, data); err == nil {
+		t.Error("篡改扩展字段应解密失败")
+	}
+
+	// 伪造消息未占用序列号, 原消息仍可解密
+	if _, err := serverSession.Open(h, data); err != nil {
+		t.Errorf("原消息应可解密: %v", err)
+	}
+}
+
+// TestSession_SequenceReused 测试发送的序列号必须递增
+func TestSession_SequenceReused(t *testing.T) {
+	clientSession, _ := newSessionPair(t)
+
+	for _, seq := range []uint64{1, 2} {
+		if _, err := clientSession.Seal(message.Header{ProtocolID: 1, Sequence: seq}, []byte("x")); err != nil {
+			t.Fatalf("加密失败: %v", err)
+		}
+	}
+	for _, seq := range []uint64{2, 1} {
+		if _, err := clientSession.Seal(message.Header{ProtocolID: 1, Sequence: seq}, []byte("x")); !errors.Is(err, message.ErrSequenceReused) {
+			t.Errorf("序列号 %d 重复使用应返回 ErrSequenceReused, 实际 %v", seq, err)
+		}
+	}
+}
+
+// TestSession_ZeroSequence 测试序列号 0 不能用于加密
+func TestSession_ZeroSequence(t *testing.T) {
+	clientSession, _ := newSessionPair(t)
+	if _, err := clientSession.Seal(message.Header{ProtocolID: 1}, []byte("x")); !errors.Is(err, message.ErrInvalidSequence) {
+		t.Errorf("期望 ErrInvalidSequence, 实际 %v", err)
+	}
+}
+
+// TestSecureHandshake 测试客户端与服务端完成密钥交换后收发加密消息
+func TestSecureHandshake(t *testing.T) {
+	var received []byte
+	var server *message.SecureServer
+	server = message.NewSecureServer(func(c conn.IConn, data []byte) error {
+		session, _ := server.Session(c)
+		msg := message.NewMessage[[]byte](message.NewEncryptedCodec[[]byte](message.NewRawCodec(), session), 0, 0, 0)
+		if err := msg.Decode(data); err != nil {
+			return err
+		}
+		received = msg.GetBody()
+		return nil
+	})
+
+	c := &recordConn{}
+	server.OnConnect(c)
+
+	// 握手前的业务消息被拒绝
+	if err := server.OnData(c, mustEncodePacket(t, message.Header{ProtocolID: 1, Sequence: 1}, []byte("x"))); !errors.Is(err, message.ErrHandshakeRequired) {
+		t.Errorf("期望 ErrHandshakeRequired, 实际 %v", err)
+	}
+
+	client := message.NewSecureClient()
+	hello, err := client.Hello()
+	if err != nil {
+		t.Fatalf("生成握手消息失败: %v", err)
+	}
+	if err := server.OnData(c, hello); err != nil {
+		t.Fatalf("服务端握手失败: %v", err)
+	}
+	if len(c.written) != 1 {
+		t.Fatalf("期望服务端回复 1 条握手消息, 实际 %d", len(c.written))
+	}
+
+	if handled, err := client.HandleData(c.written[0]); !handled || err != nil {
+		t.Fatalf("客户端握手失败: handled=%v err=%v", handled, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	session, err := client.Session(ctx)
+	if err != nil {
+		t.Fatalf("获取会话失败: %v", err)
+	}
+
+	msg := message.NewMessage[[]byte](message.NewEncryptedCodec[[]byte](message.NewRawCodec(), session), 1, 2, 3)
+	msg.SetSequence(1)
+	msg.SetBody([]byte("hello"))
+	data, err := msg.Encode()
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	if err := server.OnData(c, data); err != nil {
+		t.Fatalf("服务端处理失败: %v", err)
+	}
+	if string(received) != "hello" {
+		t.Errorf("期望 hello, 实际 %q", received)
+	}
+
+	// 非握手消息交给业务处理
+	if handled, _ := client.HandleData(data); handled {
+		t.Error("业务消息不应被握手处理")
+	}
+
+	// 连接关闭后会话被清理
+	_ = server.OnClose(c)
+	if _, ok := server.Session(c); ok {
+		t.Error("连接关闭后应清理会话")
+	}
+}
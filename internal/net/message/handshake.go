@@ -0,0 +1,192 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/spelens-gud/trunk/internal/net/conn"
+)
+
+// HandshakeMessageID 密钥交换消息ID(协议号为 0), 业务消息不能使用
+const HandshakeMessageID uint32 = 0xFFFFFFFF
+
+// ErrHandshakeRequired 未完成密钥交换
+var ErrHandshakeRequired = errors.New("未完成密钥交换")
+
+// isHandshake 是否为密钥交换消息
+func isHandshake(h Header) bool {
+	return h.ProtocolID == 0 && h.MessageID == HandshakeMessageID
+}
+
+// encodeHandshake 编码密钥交换消息, 消息体为公钥
+func encodeHandshake(publicKey []byte) ([]byte, error) {
+	return EncodePacket(Header{MessageID: HandshakeMessageID}, publicKey)
+}
+
+// SecureServer 服务端密钥交换, 为每个连接协商独立的会话
+//
+// 接入方式: OnConnect、OnData、OnClose 分别挂到传输层服务端配置的同名回调,
+// 握手完成后的消息交给 next 处理, next 中通过 Session 获取连接的会话
+type SecureServer struct {
+	next     func(conn.IConn, []byte) error
+	pending  sync.Map // 预生成的密钥, key: 连接ID, value: *KeyExchange
+	sessions sync.Map // 已协商的会话, key: 连接ID, value: *Session
+}
+
+// NewSecureServer 创建服务端密钥交换
+func NewSecureServer(next func(conn.IConn, []byte) error) *SecureServer {
+	return &SecureServer{next: next}
+}
+
+// OnConnect 连接建立时预生成密钥对
+func (s *SecureServer) OnConnect(c conn.IConn) {
+	if kx, err := NewKeyExchange(); err == nil {
+		s.pending.Store(c.GetId(), kx)
+	}
+}
+
+// OnData 处理密钥交换消息, 未完成握手的连接发送业务消息时返回 ErrHandshakeRequired
+func (s *SecureServer) OnData(c conn.IConn, data []byte) error {
+	header, body, err := DecodeHeader(data)
+	if err != nil {
+		return err
+	}
+
+	if isHandshake(header) {
+		return s.handshake(c, body)
+	}
+
+	if _, ok := s.Session(c); !ok {
+		return ErrHandshakeRequired
+	}
+
+	if s.next != nil {
+		return s.next(c, data)
+	}
+	return nil
+}
+
+// handshake 完成密钥交换并回复服务端公钥, 同一连接重复握手时替换会话
+func (s *SecureServer) handshake(c conn.IConn, clientPublic []byte) error {
+	var kx *KeyExchange
+	if value, ok := s.pending.LoadAndDelete(c.GetId()); ok {
+		kx = value.(*KeyExchange)
+	} else {
+		var err error
+		if kx, err = NewKeyExchange(); err != nil {
+			return err
+		}
+	}
+
+	session, err := kx.Session(clientPublic, false)
+	if err != nil {
+		return err
+	}
+
+	reply, err := encodeHandshake(kx.PublicKey())
+	if err != nil {
+		return err
+	}
+
+	s.sessions.Store(c.GetId(), session)
+	c.Write(reply)
+	return nil
+}
+
+// OnClose 连接关闭时清理会话
+func (s *SecureServer) OnClose(c conn.IConn) error {
+	s.pending.Delete(c.GetId())
+	s.sessions.Delete(c.GetId())
+	return nil
+}
+
+// Session 获取连接的会话
+func (s *SecureServer) Session(c conn.IConn) (*Session, bool) {
+	value, ok := s.sessions.Load(c.GetId())
+	if !ok {
+		return nil, false
+	}
+	return value.(*Session), true
+}
+
+// SecureClient 客户端密钥交换
+//
+// 接入方式: 在 FirstPingFunc 中发送 Hello 的结果, 在 OnData 中先调用 HandleData
+type SecureClient struct {
+	kx      *KeyExchange
+	session *Session
+	ready   chan struct{}
+	mu      sync.Mutex
+}
+
+// NewSecureClient 创建客户端密钥交换
+func NewSecureClient() *SecureClient {
+	return &SecureClient{ready: make(chan struct{})}
+}
+
+// Hello 生成新的密钥对并返回握手消息, 每次(重新)连接时调用
+func (c *SecureClient) Hello() ([]byte, error) {
+	kx, err := NewKeyExchange()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.kx = kx
+	c.session = nil
+	select {
+	case <-c.ready:
+		c.ready = make(chan struct{})
+	default:
+	}
+	c.mu.Unlock()
+
+	return encodeHandshake(kx.PublicKey())
+}
+
+// HandleData 处理服务端的握手回复, 返回消息是否已被处理
+func (c *SecureClient) HandleData(data []byte) (bool, error) {
+	header, body, err := DecodeHeader(data)
+	if err != nil || !isHandshake(header) {
+		return false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.kx == nil {
+		return true, fmt.Errorf("未发起密钥交换")
+	}
+
+	session, err := c.kx.Session(body, true)
+	if err != nil {
+		return true, err
+	}
+
+	c.kx = nil
+	c.session = session
+	close(c.ready)
+	return true, nil
+}
+
+// Session 等待握手完成并返回会话
+func (c *SecureClient) Session(ctx context.Context) (*Session, error) {
+	c.mu.Lock()
+	ready := c.ready
+	c.mu.Unlock()
+
+	select {
+	case <-ready:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.session == nil {
+		return nil, ErrHandshakeRequired
+	}
+	return c.session, nil
+}
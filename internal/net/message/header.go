@@ -0,0 +1,210 @@
+package message
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// 消息头版本
+const (
+	HeaderV0 uint8 = 0 // 旧版: ProtocolID(4) ServiceID(4) MessageID(4) Sequence(8)
+	HeaderV1 uint8 = 1 // 带魔数、版本、标志位、消息体长度和扩展区
+)
+
+const (
+	// HeaderSize 旧版(v0)消息头长度(4+4+4+8 字节)
+	HeaderSize = 20
+	// HeaderV1Size v1 消息头固定部分长度(不含扩展区)
+	HeaderV1Size = 30
+	// HeaderMagic v1 消息头魔数, v0 的 ProtocolID 高16位不能与之相同
+	HeaderMagic uint16 = 0xFEED
+)
+
+// Flag 消息头标志位
+type Flag uint8
+
+const (
+	FlagCompressed Flag = 1 << iota // 消息体已压缩
+	FlagEncrypted                   // 消息体已加密
+	FlagError                       // 消息体为 ErrorCode
+)
+
+// 预定义的扩展字段类型, 业务自定义类型建议从 1000 开始
+const (
+	ExtTraceID uint16 = 1 // 链路追踪ID
+	ExtUserID  uint16 = 2 // 用户ID
+)
+
+// Extension 消息头扩展字段(TLV)
+type Extension struct {
+	Type  uint16 // 类型
+	Value []byte // 值
+}
+
+// HasFlag 是否设置了指定标志位
+func (h *Header) HasFlag(f Flag) bool {
+	return h.Flags&f != 0
+}
+
+// SetFlag 设置标志位
+func (h *Header) SetFlag(f Flag) {
+	h.Flags |= f
+}
+
+// ClearFlag 清除标志位
+func (h *Header) ClearFlag(f Flag) {
+	h.Flags &^= f
+}
+
+// GetExtension 获取扩展字段
+func (h *Header) GetExtension(t uint16) ([]byte, bool) {
+	for _, ext := range h.Extensions {
+		if ext.Type == t {
+			return ext.Value, true
+		}
+	}
+	return nil, false
+}
+
+// SetExtension 设置扩展字段, 已存在时覆盖
+func (h *Header) SetExtension(t uint16, value []byte) {
+	for i := range h.Extensions {
+		if h.Extensions[i].Type == t {
+			h.Extensions[i].Value = value
+			return
+		}
+	}
+	h.Extensions = append(h.Extensions, Extension{Type: t, Value: value})
+}
+
+// isV1 是否需要使用 v1 格式编码(显式指定版本, 或使用了 v0 无法表示的标志位、扩展字段)
+func (h *Header) isV1() bool {
+	return h.Version >= HeaderV1 || h.Flags != 0 || len(h.Extensions) > 0
+}
+
+// EncodePacket 编码消息头和消息体
+//
+// 默认使用 v0 格式以兼容旧客户端, 设置了 Version、Flags 或 Extensions 时使用 v1 格式
+func EncodePacket(h Header, body []byte) ([]byte, error) {
+	if !h.isV1() {
+		data := make([]byte, HeaderSize+len(body))
+		putUint32(data[0:4], h.ProtocolID)
+		putUint32(data[4:8], h.ServiceID)
+		putUint32(data[8:12], h.MessageID)
+		putUint64(data[12:20], h.Sequence)
+		copy(data[HeaderSize:], body)
+		return data, nil
+	}
+
+	if uint64(len(body)) > math.MaxUint32 {
+		return nil, errors.New("消息体过长")
+	}
+
+	extLen := 0
+	for _, ext := range h.Extensions {
+		if len(ext.Value) > math.MaxUint16 {
+			return nil, fmt.Errorf("扩展字段过长: type=%d", ext.Type)
+		}
+		extLen += 4 + len(ext.Value)
+	}
+	if extLen > math.MaxUint16 {
+		return nil, errors.New("扩展区过长")
+	}
+
+	data := make([]byte, HeaderV1Size+extLen+len(body))
+	putUint16(data[0:2], HeaderMagic)
+	data[2] = HeaderV1
+	data[3] = byte(h.Flags)
+	putUint32(data[4:8], h.ProtocolID)
+	putUint32(data[8:12], h.ServiceID)
+	putUint32(data[12:16], h.MessageID)
+	putUint64(data[16:24], h.Sequence)
+	putUint32(data[24:28], uint32(len(body)))
+	putUint16(data[28:30], uint16(extLen))
+
+	offset := HeaderV1Size + putExtensions(data[HeaderV1Size:], h.Extensions)
+	copy(data[offset:], body)
+	return data, nil
+}
+
+// putExtensions 写入扩展区: Type(2) Length(2) Value, 返回写入的长度
+func putExtensions(b []byte, extensions []Extension) int {
+	offset := 0
+	for _, ext := range extensions {
+		putUint16(b[offset:offset+2], ext.Type)
+		putUint16(b[offset+2:offset+4], uint16(len(ext.Value)))
+		copy(b[offset+4:], ext.Value)
+		offset += 4 + len(ext.Value)
+	}
+	return offset
+}
+
+// DecodeHeader 解码消息头, 返回消息头和消息体数据, 自动识别 v0 和 v1 格式
+func DecodeHeader(data []byte) (Header, []byte, error) {
+	if len(data) >= HeaderV1Size && getUint16(data[0:2]) == HeaderMagic && data[2] == HeaderV1 {
+		return decodeHeaderV1(data)
+	}
+
+	if len(data) < HeaderSize {
+		return Header{}, nil, errors.New("编码数据数据小于编码限定值")
+	}
+
+	header := Header{
+		ProtocolID: getUint32(data[0:4]),
+		ServiceID:  getUint32(data[4:8]),
+		MessageID:  getUint32(data[8:12]),
+		Sequence:   getUint64(data[12:20]),
+		BodyLength: uint32(len(data) - HeaderSize),
+	}
+	return header, data[HeaderSize:], nil
+}
+
+// decodeHeaderV1 解码 v1 消息头
+func decodeHeaderV1(data []byte) (Header, []byte, error) {
+	header := Header{
+		Version:    data[2],
+		Flags:      Flag(data[3]),
+		ProtocolID: getUint32(data[4:8]),
+		ServiceID:  getUint32(data[8:12]),
+		MessageID:  getUint32(data[12:16]),
+		Sequence:   getUint64(data[16:24]),
+		BodyLength: getUint32(data[24:28]),
+	}
+
+	extLen := int(getUint16(data[28:30]))
+	if len(data) < HeaderV1Size+extLen {
+		return Header{}, nil, errors.New("扩展区数据不完整")
+	}
+
+	ext := data[HeaderV1Size : HeaderV1Size+extLen]
+	for len(ext) > 0 {
+		if len(ext) < 4 {
+			return Header{}, nil, errors.New("扩展字段格式错误")
+		}
+		t := getUint16(ext[0:2])
+		n := int(getUint16(ext[2:4]))
+		if len(ext) < 4+n {
+			return Header{}, nil, errors.New("扩展字段格式错误")
+		}
+		header.Extensions = append(header.Extensions, Extension{Type: t, Value: ext[4 : 4+n]})
+		ext = ext[4+n:]
+	}
+
+	body := data[HeaderV1Size+extLen:]
+	if uint64(len(body)) != uint64(header.BodyLength) {
+		return Header{}, nil, fmt.Errorf("消息体长度不匹配: 期望 %d, 实际 %d", header.BodyLength, len(body))
+	}
+	return header, body, nil
+}
+
+// putUint16 将 uint16 写入字节数组(大端序)
+func putUint16(b []byte, v uint16) {
+	b[0] = byte(v >> 8)
+	b[1] = byte(v)
+}
+
+// getUint16 从字节数组读取 uint16(大端序)
+func getUint16(b []byte) uint16 {
+	return uint16(b[0])<<8 | uint16(b[1])
+}
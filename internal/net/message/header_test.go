@@ -0,0 +1,131 @@
+package message_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spelens-gud/trunk/internal/net/message"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// TestHeader_V0Compatible 测试默认编码与旧版20字节格式一致
+func TestHeader_V0Compatible(t *testing.T) {
+	legacy := []byte{
+		0, 0, 0x03, 0xe9, // ProtocolID 1001
+		0, 0, 0x07, 0xd1, // ServiceID 2001
+		0, 0, 0x0b, 0xb9, // MessageID 3001
+		0, 0, 0, 0, 0, 0, 0, 0x64, // Sequence 100
+		'h', 'i',
+	}
+
+	data, err := message.EncodePacket(message.Header{ProtocolID: 1001, ServiceID: 2001, MessageID: 3001, Sequence: 100}, []byte("hi"))
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	if !bytes.Equal(data, legacy) {
+		t.Errorf("v0 编码结果与旧格式不一致:\n期望 %v\n实际 %v", legacy, data)
+	}
+
+	header, body, err := message.DecodeHeader(legacy)
+	if err != nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+	if header.Version != message.HeaderV0 || header.ProtocolID != 1001 || header.Sequence != 100 || header.BodyLength != 2 {
+		t.Errorf("v0 消息头解码错误: %+v", header)
+	}
+	if string(body) != "hi" {
+		t.Errorf("期望消息体 'hi', 实际 '%s'", body)
+	}
+}
+
+// TestHeader_V1RoundTrip 测试 v1 格式的标志位和扩展字段
+func TestHeader_V1RoundTrip(t *testing.T) {
+	header := message.Header{ProtocolID: 1, ServiceID: 2, MessageID: 3, Sequence: 4}
+	header.SetFlag(message.FlagCompressed)
+	header.SetFlag(message.FlagError)
+	header.SetExtension(message.ExtTraceID, []byte("trace-abc"))
+	header.SetExtension(message.ExtUserID, []byte{0, 0, 0, 42})
+	header.SetExtension(message.ExtTraceID, []byte("trace-xyz")) // 覆盖
+
+	data, err := message.EncodePacket(header, []byte("body"))
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+
+	got, body, err := message.DecodeHeader(data)
+	if err != nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+	if got.Version != message.HeaderV1 {
+		t.Errorf("使用标志位时应编码为 v1, 实际版本 %d", got.Version)
+	}
+	if got.ProtocolID != 1 || got.ServiceID != 2 || got.MessageID != 3 || got.Sequence != 4 || got.BodyLength != 4 {
+		t.Errorf("v1 消息头解码错误: %+v", got)
+	}
+	if !got.HasFlag(message.FlagCompressed) || !got.HasFlag(message.FlagError) || got.HasFlag(message.FlagEncrypted) {
+		t.Errorf("标志位错误: %08b", got.Flags)
+	}
+	if v, ok := got.GetExtension(message.ExtTraceID); !ok || string(v) != "trace-xyz" {
+		t.Errorf("期望 trace-xyz, 实际 %q", v)
+	}
+	if v, ok := got.GetExtension(message.ExtUserID); !ok || !bytes.Equal(v, []byte{0, 0, 0, 42}) {
+		t.Errorf("用户ID扩展错误: %v", v)
+	}
+	if len(got.Extensions) != 2 {
+		t.Errorf("期望 2 个扩展字段, 实际 %d", len(got.Extensions))
+	}
+	if string(body) != "body" {
+		t.Errorf("期望消息体 'body', 实际 '%s'", body)
+	}
+
+	got.ClearFlag(message.FlagCompressed)
+	if got.HasFlag(message.FlagCompressed) {
+		t.Error("清除标志位失败")
+	}
+}
+
+// TestHeader_V1Invalid 测试损坏的 v1 数据
+func TestHeader_V1Invalid(t *testing.T) {
+	header := message.Header{Version: message.HeaderV1, ProtocolID: 1}
+	header.SetExtension(message.ExtTraceID, []byte("trace"))
+
+	data, err := message.EncodePacket(header, []byte("body"))
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+
+	// 消息体被截断
+	if _, _, err := message.DecodeHeader(data[:len(data)-1]); err == nil {
+		t.Error("消息体长度不匹配时应返回错误")
+	}
+
+	// 扩展区被截断
+	if _, _, err := message.DecodeHeader(data[:message.HeaderV1Size+3]); err == nil {
+		t.Error("扩展区不完整时应返回错误")
+	}
+}
+
+// TestRouter_V1ErrorFlag 测试 v1 请求的错误回复带错误标志并保留扩展字段
+func TestRouter_V1ErrorFlag(t *testing.T) {
+	router := newTestRouter()
+
+	header := message.Header{Version: message.HeaderV1, ProtocolID: 1, MessageID: 999, Sequence: 5}
+	header.SetExtension(message.ExtTraceID, []byte("trace-1"))
+
+	c := &recordConn{}
+	if err := router.OnData(c, encodeRequest(t, header, wrapperspb.String("x"))); err != nil {
+		t.Fatalf("分发失败: %v", err)
+	}
+
+	errCode := &message.ErrorCode{}
+	got := decodeReply(t, c.written[0], errCode)
+	if !got.HasFlag(message.FlagError) {
+		t.Error("v1 错误回复应设置 FlagError")
+	}
+	if v, _ := got.GetExtension(message.ExtTraceID); string(v) != "trace-1" {
+		t.Errorf("回复应保留链路追踪ID, 实际 %q", v)
+	}
+	if errCode.GetCode() != message.ErrCodeUnknownMessage {
+		t.Errorf("期望错误码 %d, 实际 %d", message.ErrCodeUnknownMessage, errCode.GetCode())
+	}
+}
@@ -22,6 +22,14 @@ type Header struct {
 	MessageID uint32
 	// Sequence 序列号
 	Sequence uint64
+	// Version 头部版本, 0 为旧版20字节格式
+	Version uint8
+	// Flags 标志位(压缩、加密、错误等)
+	Flags Flag
+	// BodyLength 消息体长度, 解码时填充, 编码时自动计算
+	BodyLength uint32
+	// Extensions 扩展字段(TLV), 如链路追踪ID、用户ID
+	Extensions []Extension
 }
 
 // Message 泛型消息结构
@@ -72,25 +80,20 @@ func (m *Message[T]) Encode() ([]byte, error) {
 		return nil, errors.New("编码器不存在")
 	}
 
-	// 编码消息体
-	bodyData, err := m.codec.Encode(m.Body)
+	// 编码消息体(需要读写消息头的编解码器可修改标志位)
+	var bodyData []byte
+	var err error
+	if hc, ok := m.codec.(HeaderCodec[T]); ok {
+		bodyData, err = hc.EncodeWithHeader(&m.Header, m.Body)
+	} else {
+		bodyData, err = m.codec.Encode(m.Body)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	// 编码消息头(简单的二进制格式)
-	headerData := make([]byte, 20) // 4+4+4+8 字节
-	putUint32(headerData[0:4], m.Header.ProtocolID)
-	putUint32(headerData[4:8], m.Header.ServiceID)
-	putUint32(headerData[8:12], m.Header.MessageID)
-	putUint64(headerData[12:20], m.Header.Sequence)
-
 	// 组合消息头和消息体
-	result := make([]byte, len(headerData)+len(bodyData))
-	copy(result, headerData)
-	copy(result[len(headerData):], bodyData)
-
-	return result, nil
+	return EncodePacket(m.Header, bodyData)
 }
 
 // Decode 解码消息（包含消息头和消息体）
@@ -99,18 +102,20 @@ func (m *Message[T]) Decode(data []byte) error {
 		return errors.New("编码器不存在")
 	}
 
-	if len(data) < 20 {
-		return errors.New("编码数据数据小于编码限定值")
-	}
-
 	// 解码消息头
-	m.Header.ProtocolID = getUint32(data[0:4])
-	m.Header.ServiceID = getUint32(data[4:8])
-	m.Header.MessageID = getUint32(data[8:12])
-	m.Header.Sequence = getUint64(data[12:20])
+	header, bodyData, err := DecodeHeader(data)
+	if err != nil {
+		return err
+	}
+	m.Header = header
 
 	// 解码消息体
-	body, err := m.codec.Decode(data[20:])
+	var body T
+	if hc, ok := m.codec.(HeaderCodec[T]); ok {
+		body, err = hc.DecodeWithHeader(header, bodyData)
+	} else {
+		body, err = m.codec.Decode(bodyData)
+	}
 	if err != nil {
 		return err
 	}
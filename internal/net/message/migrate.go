@@ -0,0 +1,21 @@
+package message
+
+// MigratingMessageID 服务器迁移通知消息ID(协议号为 0), 业务消息不能使用
+//
+// 服务端下线前广播给已连接的客户端, 消息体为可选的原因, 不经过加密和压缩;
+// 客户端收到后在当前对局或会话结束时重新连接, 服务发现会跳过下线中的实例
+const MigratingMessageID uint32 = 0xFFFFFFFE
+
+// EncodeMigrating 编码服务器迁移通知
+func EncodeMigrating(reason string) ([]byte, error) {
+	return EncodePacket(Header{MessageID: MigratingMessageID}, []byte(reason))
+}
+
+// IsMigrating 是否为服务器迁移通知, 是时返回原因
+func IsMigrating(data []byte) (string, bool) {
+	header, body, err := DecodeHeader(data)
+	if err != nil || header.ProtocolID != 0 || header.MessageID != MigratingMessageID {
+		return "", false
+	}
+	return string(body), true
+}
@@ -0,0 +1,254 @@
+package message
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/spelens-gud/logger"
+	"github.com/spelens-gud/trunk/internal/net/conn"
+	"google.golang.org/protobuf/proto"
+)
+
+// 路由器回复的错误码
+const (
+	ErrCodeBadRequest     int32 = 400 // 消息体解码失败
+	ErrCodeUnauthorized   int32 = 401 // 未认证
+	ErrCodeUnknownMessage int32 = 404 // 未注册的消息
+	ErrCodeInternal       int32 = 500 // 处理器内部错误
+)
+
+// DefaultErrorMessageID 默认错误回复的消息ID
+const DefaultErrorMessageID uint32 = 0
+
+// CodeError 带错误码的业务错误, 处理器返回后由路由器转换为 ErrorCode 回复
+type CodeError struct {
+	Code int32  // 错误码
+	Msg  string // 错误信息
+}
+
+// NewCodeError 创建带错误码的业务错误
+func NewCodeError(code int32, msg string) *CodeError {
+	return &CodeError{Code: code, Msg: msg}
+}
+
+// Error 实现 error 接口
+func (e *CodeError) Error() string {
+	return fmt.Sprintf("code=%d msg=%s", e.Code, e.Msg)
+}
+
+// Context 路由上下文
+type Context struct {
+	Conn   conn.IConn     // 消息来源连接
+	Header Header         // 消息头
+	Body   proto.Message  // 已解码的消息体
+	values map[string]any // 中间件间传递的数据
+}
+
+// Set 设置上下文数据
+func (c *Context) Set(key string, value any) {
+	if c.values == nil {
+		c.values = make(map[string]any)
+	}
+	c.values[key] = value
+}
+
+// Get 获取上下文数据
+func (c *Context) Get(key string) (any, bool) {
+	value, ok := c.values[key]
+	return value, ok
+}
+
+// HandlerFunc 消息处理函数, 返回非 nil 的消息时作为回复发送给来源连接
+type HandlerFunc func(c *Context) (proto.Message, error)
+
+// Middleware 消息处理中间件
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// routeKey 路由键
+type routeKey struct {
+	protocolID uint32
+	messageID  uint32
+}
+
+// route 路由项
+type route struct {
+	newBody func() proto.Message // 创建消息体实例
+	handler HandlerFunc          // 处理函数
+}
+
+// Router 按 (ProtocolID, MessageID) 分发消息的路由器
+type Router struct {
+	log            logger.ILogger
+	routes         map[routeKey]*route
+	middlewares    []Middleware
+	errorMessageID uint32
+	mu             sync.RWMutex
+}
+
+// NewRouter 创建路由器
+func NewRouter(log logger.ILogger) *Router {
+	return &Router{
+		log:            log,
+		routes:         make(map[routeKey]*route),
+		middlewares:    make([]Middleware, 0),
+		errorMessageID: DefaultErrorMessageID,
+	}
+}
+
+// Use 添加中间件, 按添加顺序由外到内执行
+func (r *Router) Use(middleware Middleware) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.middlewares = append(r.middlewares, middleware)
+	return r
+}
+
+// SetErrorMessageID 设置错误回复的消息ID
+func (r *Router) SetErrorMessageID(id uint32) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.errorMessageID = id
+	return r
+}
+
+// Handle 注册类型化的消息处理器, 消息体按 Req 类型自动创建并解码
+func Handle[Req proto.Message](r *Router, protocolID, messageID uint32, handler func(c *Context, req Req) (proto.Message, error)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.routes[routeKey{protocolID: protocolID, messageID: messageID}] = &route{
+		newBody: func() proto.Message {
+			var req Req
+			return req.ProtoReflect().New().Interface()
+		},
+		handler: func(c *Context) (proto.Message, error) {
+			return handler(c, c.Body.(Req))
+		},
+	}
+}
+
+// OnData 分发一条完整消息, 签名与各传输层的 OnData 回调一致
+func (r *Router) OnData(c conn.IConn, data []byte) error {
+	header, bodyData, err := DecodeHeader(data)
+	if err != nil {
+		return err
+	}
+
+	r.mu.RLock()
+	rt, ok := r.routes[routeKey{protocolID: header.ProtocolID, messageID: header.MessageID}]
+	middlewares := r.middlewares
+	r.mu.RUnlock()
+
+	if !ok {
+		r.replyError(c, header, ErrCodeUnknownMessage,
+			fmt.Sprintf("未知消息: protocol=%d message=%d", header.ProtocolID, header.MessageID))
+		return nil
+	}
+
+	body := rt.newBody()
+	if err := proto.Unmarshal(bodyData, body); err != nil {
+		r.replyError(c, header, ErrCodeBadRequest, "消息体解码失败")
+		return nil
+	}
+
+	handler := rt.handler
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	resp, err := handler(&Context{Conn: c, Header: header, Body: body})
+	if err != nil {
+		var codeErr *CodeError
+		if errors.As(err, &codeErr) {
+			r.replyError(c, header, codeErr.Code, codeErr.Msg)
+		} else {
+			r.log.Errorf("消息处理失败: protocol=%d message=%d %v", header.ProtocolID, header.MessageID, err)
+			r.replyError(c, header, ErrCodeInternal, "内部错误")
+		}
+		return nil
+	}
+
+	if resp != nil {
+		r.reply(c, header, resp)
+	}
+	return nil
+}
+
+// reply 以请求的消息头回复(保留版本和扩展字段, 清除请求的标志位)
+func (r *Router) reply(c conn.IConn, header Header, body proto.Message) {
+	header.Flags = 0
+	r.send(c, header, body)
+}
+
+// replyError 回复 ErrorCode, 保留请求的协议号、服务ID和序列号
+func (r *Router) replyError(c conn.IConn, header Header, code int32, msg string) {
+	r.mu.RLock()
+	header.MessageID = r.errorMessageID
+	r.mu.RUnlock()
+
+	// v1 消息头通过标志位标识错误回复, v0 只能依赖错误消息ID
+	header.Flags = 0
+	if header.Version >= HeaderV1 {
+		header.Flags = FlagError
+	}
+	r.send(c, header, &ErrorCode{Code: code, Msg: msg})
+}
+
+// send 编码并发送消息
+func (r *Router) send(c conn.IConn, header Header, body proto.Message) {
+	data, err := EncodeProto(header, body)
+	if err != nil {
+		r.log.Errorf("回复编码失败: protocol=%d message=%d %v", header.ProtocolID, header.MessageID, err)
+		return
+	}
+
+	c.Write(data)
+}
+
+// LoggingMiddleware 日志中间件
+func LoggingMiddleware(log logger.ILogger) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) (proto.Message, error) {
+			resp, err := next(c)
+			if err != nil {
+				log.Warnf("消息处理: conn=%d protocol=%d message=%d seq=%d err=%v",
+					c.Conn.GetId(), c.Header.ProtocolID, c.Header.MessageID, c.Header.Sequence, err)
+			} else {
+				log.Debugf("消息处理: conn=%d protocol=%d message=%d seq=%d",
+					c.Conn.GetId(), c.Header.ProtocolID, c.Header.MessageID, c.Header.Sequence)
+			}
+			return resp, err
+		}
+	}
+}
+
+// RecoveryMiddleware 异常恢复中间件, 处理器 panic 时回复内部错误
+func RecoveryMiddleware(log logger.ILogger) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) (resp proto.Message, err error) {
+			defer func() {
+				if p := recover(); p != nil {
+					log.Errorf("消息处理异常: protocol=%d message=%d panic=%v",
+						c.Header.ProtocolID, c.Header.MessageID, p)
+					resp, err = nil, NewCodeError(ErrCodeInternal, "内部错误")
+				}
+			}()
+			return next(c)
+		}
+	}
+}
+
+// AuthMiddleware 认证中间件, check 返回错误时回复未认证
+func AuthMiddleware(check func(c *Context) error) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) (proto.Message, error) {
+			if err := check(c); err != nil {
+				return nil, NewCodeError(ErrCodeUnauthorized, err.Error())
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package message_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spelens-gud/logger"
+	"github.com/spelens-gud/trunk/internal/net/message"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// recordConn 记录写入数据的连接
+type recordConn struct {
+	written [][]byte
+}
+
+func (c *recordConn) Start()                       {}
+func (c *recordConn) Write(b []byte)               { c.written = append(c.written, b) }
+func (c *recordConn) Close() error                 { return nil }
+func (c *recordConn) SetId(id uint64)              {}
+func (c *recordConn) GetId() uint64                { return 1 }
+func (c *recordConn) IsClosed() bool               { return false }
+func (c *recordConn) GetCreateTime() time.Time     { return time.Time{} }
+func (c *recordConn) GetLastActiveTime() time.Time { return time.Time{} }
+
+// encodeRequest 编码请求
+func encodeRequest(t *testing.T, header message.Header, body proto.Message) []byte {
+	data, err := message.EncodeProto(header, body)
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	return data
+}
+
+// mustEncodePacket 编码消息头和原始消息体
+func mustEncodePacket(t *testing.T, header message.Header, body []byte) []byte {
+	data, err := message.EncodePacket(header, body)
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	return data
+}
+
+// decodeReply 解码回复
+func decodeReply[T proto.Message](t *testing.T, data []byte, body T) message.Header {
+	header, bodyData, err := message.DecodeHeader(data)
+	if err != nil {
+		t.Fatalf("解码消息头失败: %v", err)
+	}
+	if err := proto.Unmarshal(bodyData, body); err != nil {
+		t.Fatalf("解码消息体失败: %v", err)
+	}
+	return header
+}
+
+// newTestRouter 创建测试路由器
+func newTestRouter() *message.Router {
+	log, _ := logger.NewLogger(&logger.Config{
+		Level:   "info",
+		Console: true,
+	})
+	return message.NewRouter(log)
+}
+
+// TestRouter_Dispatch 测试按消息ID分发并回复
+func TestRouter_Dispatch(t *testing.T) {
+	router := newTestRouter()
+
+	message.Handle(router, 1, 100, func(c *message.Context, req *wrapperspb.StringValue) (proto.Message, error) {
+		return wrapperspb.String("hello " + req.GetValue()), nil
+	})
+	message.Handle(router, 1, 101, func(c *message.Context, req *wrapperspb.Int32Value) (proto.Message, error) {
+		return nil, nil // 通知类消息不回复
+	})
+
+	c := &recordConn{}
+	header := message.Header{ProtocolID: 1, ServiceID: 2, MessageID: 100, Sequence: 7}
+	if err := router.OnData(c, encodeRequest(t, header, wrapperspb.String("alice"))); err != nil {
+		t.Fatalf("分发失败: %v", err)
+	}
+	if err := router.OnData(c, encodeRequest(t, message.Header{ProtocolID: 1, MessageID: 101}, wrapperspb.Int32(1))); err != nil {
+		t.Fatalf("分发失败: %v", err)
+	}
+
+	if len(c.written) != 1 {
+		t.Fatalf("期望回复 1 条, 实际 %d 条", len(c.written))
+	}
+
+	resp := &wrapperspb.StringValue{}
+	if got := decodeReply(t, c.written[0], resp); got.ProtocolID != header.ProtocolID || got.ServiceID != header.ServiceID ||
+		got.MessageID != header.MessageID || got.Sequence != header.Sequence {
+		t.Errorf("回复消息头应与请求一致: 期望 %+v, 实际 %+v", header, got)
+	}
+	if resp.GetValue() != "hello alice" {
+		t.Errorf("期望回复 'hello alice', 实际 '%s'", resp.GetValue())
+	}
+}
+
+// TestRouter_Errors 测试未知消息、解码失败和处理器错误的 ErrorCode 回复
+func TestRouter_Errors(t *testing.T) {
+	router := newTestRouter().SetErrorMessageID(9999)
+
+	message.Handle(router, 1, 100, func(c *message.Context, req *wrapperspb.StringValue) (proto.Message, error) {
+		return nil, message.NewCodeError(1001, "余额不足")
+	})
+	message.Handle(router, 1, 101, func(c *message.Context, req *wrapperspb.StringValue) (proto.Message, error) {
+		return nil, errors.New("db down")
+	})
+
+	tests := []struct {
+		name     string
+		data     []byte
+		wantCode int32
+	}{
+		{"未知消息", encodeRequest(t, message.Header{ProtocolID: 1, MessageID: 999, Sequence: 3}, wrapperspb.String("x")), message.ErrCodeUnknownMessage},
+		{"解码失败", mustEncodePacket(t, message.Header{ProtocolID: 1, MessageID: 100, Sequence: 3}, []byte{0xff, 0xff}), message.ErrCodeBadRequest},
+		{"业务错误", encodeRequest(t, message.Header{ProtocolID: 1, MessageID: 100, Sequence: 3}, wrapperspb.String("x")), 1001},
+		{"内部错误", encodeRequest(t, message.Header{ProtocolID: 1, MessageID: 101, Sequence: 3}, wrapperspb.String("x")), message.ErrCodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &recordConn{}
+			if err := router.OnData(c, tt.data); err != nil {
+				t.Fatalf("分发失败: %v", err)
+			}
+			if len(c.written) != 1 {
+				t.Fatalf("期望回复 1 条, 实际 %d 条", len(c.written))
+			}
+
+			errCode := &message.ErrorCode{}
+			header := decodeReply(t, c.written[0], errCode)
+			if header.MessageID != 9999 || header.Sequence != 3 {
+				t.Errorf("错误回复消息头不正确: %+v", header)
+			}
+			if errCode.GetCode() != tt.wantCode {
+				t.Errorf("期望错误码 %d, 实际 %d (%s)", tt.wantCode, errCode.GetCode(), errCode.GetMsg())
+			}
+		})
+	}
+
+	// 消息头不完整时直接返回错误
+	if err := router.OnData(&recordConn{}, []byte{1, 2, 3}); err == nil {
+		t.Error("不完整的消息应返回错误")
+	}
+}
+
+// TestRouter_Middleware 测试中间件执行顺序、认证和异常恢复
+func TestRouter_Middleware(t *testing.T) {
+	router := newTestRouter()
+
+	var order []string
+	trace := func(name string) message.Middleware {
+		return func(next message.HandlerFunc) message.HandlerFunc {
+			return func(c *message.Context) (proto.Message, error) {
+				order = append(order, name)
+				return next(c)
+			}
+		}
+	}
+
+	log, _ := logger.NewLogger(&logger.Config{Level: "info", Console: true})
+	router.Use(trace("first")).Use(trace("second")).
+		Use(message.RecoveryMiddleware(log)).
+		Use(message.AuthMiddleware(func(c *message.Context) error {
+			if c.Header.ServiceID != 1 {
+				return errors.New("未登录")
+			}
+			c.Set("uid", uint64(42))
+			return nil
+		}))
+
+	message.Handle(router, 1, 100, func(c *message.Context, req *wrapperspb.StringValue) (proto.Message, error) {
+		uid, _ := c.Get("uid")
+		if uid != uint64(42) {
+			t.Errorf("期望从上下文获取 uid=42, 实际 %v", uid)
+		}
+		return wrapperspb.String("ok"), nil
+	})
+	message.Handle(router, 1, 101, func(c *message.Context, req *wrapperspb.StringValue) (proto.Message, error) {
+		panic("boom")
+	})
+
+	c := &recordConn{}
+	_ = router.OnData(c, encodeRequest(t, message.Header{ProtocolID: 1, ServiceID: 1, MessageID: 100}, wrapperspb.String("x")))
+	if len(order) != 2 || order[0] != "first" || order[1] != "second" {
+		t.Errorf("中间件执行顺序错误: %v", order)
+	}
+
+	// 认证失败
+	c = &recordConn{}
+	_ = router.OnData(c, encodeRequest(t, message.Header{ProtocolID: 1, ServiceID: 2, MessageID: 100}, wrapperspb.String("x")))
+	errCode := &message.ErrorCode{}
+	decodeReply(t, c.written[0], errCode)
+	if errCode.GetCode() != message.ErrCodeUnauthorized {
+		t.Errorf("期望错误码 %d, 实际 %d", message.ErrCodeUnauthorized, errCode.GetCode())
+	}
+
+	// 处理器 panic
+	c = &recordConn{}
+	_ = router.OnData(c, encodeRequest(t, message.Header{ProtocolID: 1, ServiceID: 1, MessageID: 101}, wrapperspb.String("x")))
+	errCode = &message.ErrorCode{}
+	decodeReply(t, c.written[0], errCode)
+	if errCode.GetCode() != message.ErrCodeInternal {
+		t.Errorf("期望错误码 %d, 实际 %d", message.ErrCodeInternal, errCode.GetCode())
+	}
+}
@@ -0,0 +1,237 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/spelens-gud/trunk/internal/net/conn"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	// ErrRPCDisconnected 连接断开, 等待中的调用被取消
+	ErrRPCDisconnected = errors.New("连接已断开")
+	// ErrRPCClosed RPC客户端已关闭
+	ErrRPCClosed = errors.New("RPC客户端已关闭")
+)
+
+// PushHandler 服务端推送(Sequence 为 0)处理函数
+type PushHandler func(header Header, body []byte)
+
+// rpcResult 调用结果
+type rpcResult struct {
+	body []byte
+	err  error
+}
+
+// RPCClient 基于 Header.Sequence 的请求/响应关联, 可运行在任意传输层之上
+//
+// 请求使用 v1 消息头, 服务端的错误回复通过 FlagError 识别, 任意消息ID(包括 0)都可以作为方法;
+// 传输层的 OnData 回调中调用 OnData, 断线回调中调用 OnDisconnect
+type RPCClient struct {
+	write      func([]byte) error // 发送完整消息
+	protocolID uint32
+	serviceID  uint32
+	seq        uint64
+	pending    map[uint64]chan rpcResult
+	onPush     PushHandler
+	closed     bool
+	mu         sync.Mutex
+}
+
+// NewRPCClient 创建RPC客户端
+func NewRPCClient(write func([]byte) error, protocolID, serviceID uint32) *RPCClient {
+	return &RPCClient{
+		write:      write,
+		protocolID: protocolID,
+		serviceID:  serviceID,
+		pending:    make(map[uint64]chan rpcResult),
+	}
+}
+
+// SetPushHandler 设置服务端推送处理函数
+func (c *RPCClient) SetPushHandler(handler PushHandler) *RPCClient {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.onPush = handler
+	return c
+}
+
+// nextSequence 生成下一个序列号(跳过推送使用的 0)
+func (c *RPCClient) nextSequence() uint64 {
+	for {
+		if seq := atomic.AddUint64(&c.seq, 1); seq != 0 {
+			return seq
+		}
+	}
+}
+
+// Call 发起调用并等待响应, 返回响应消息体
+//
+// 服务端回复 ErrorCode 时返回 *CodeError
+func (c *RPCClient) Call(ctx context.Context, msgID uint32, req proto.Message) ([]byte, error) {
+	seq := c.nextSequence()
+	data, err := EncodeProto(Header{
+		Version:    HeaderV1,
+		ProtocolID: c.protocolID,
+		ServiceID:  c.serviceID,
+		MessageID:  msgID,
+		Sequence:   seq,
+	}, req)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan rpcResult, 1)
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrRPCClosed
+	}
+	c.pending[seq] = ch
+	c.mu.Unlock()
+
+	if err := c.write(data); err != nil {
+		c.removePending(seq)
+		return nil, err
+	}
+
+	select {
+	case result := <-ch:
+		if result.err != nil {
+			return nil, result.err
+		}
+		return result.body, nil
+	case <-ctx.Done():
+		c.removePending(seq)
+		return nil, ctx.Err()
+	}
+}
+
+// CallTyped 发起调用并将响应解码为 Resp
+func CallTyped[Resp proto.Message](ctx context.Context, c *RPCClient, msgID uint32, req proto.Message) (Resp, error) {
+	var resp Resp
+
+	body, err := c.Call(ctx, msgID, req)
+	if err != nil {
+		return resp, err
+	}
+
+	resp = resp.ProtoReflect().New().Interface().(Resp)
+	if err := proto.Unmarshal(body, resp); err != nil {
+		return resp, fmt.Errorf("响应解码失败: %w", err)
+	}
+	return resp, nil
+}
+
+// OnData 处理收到的完整消息, 按序列号唤醒对应调用, 序列号为 0 时作为推送处理
+func (c *RPCClient) OnData(data []byte) error {
+	header, body, err := DecodeHeader(data)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if header.Sequence == 0 {
+		onPush := c.onPush
+		c.mu.Unlock()
+
+		if onPush != nil {
+			onPush(header, body)
+		}
+		return nil
+	}
+
+	ch, ok := c.pending[header.Sequence]
+	delete(c.pending, header.Sequence)
+	isError := header.HasFlag(FlagError)
+	c.mu.Unlock()
+
+	// 已超时或取消的调用, 丢弃迟到的响应
+	if !ok {
+		return nil
+	}
+
+	result := rpcResult{body: body}
+	if isError {
+		errCode := &ErrorCode{}
+		if err := proto.Unmarshal(body, errCode); err != nil {
+			result.err = fmt.Errorf("错误码解码失败: %w", err)
+		} else {
+			result.err = NewCodeError(errCode.GetCode(), errCode.GetMsg())
+		}
+	}
+
+	ch <- result
+	return nil
+}
+
+// OnDisconnect 连接断开时取消所有等待中的调用, 重连后可继续使用
+func (c *RPCClient) OnDisconnect() {
+	c.failPending(ErrRPCDisconnected)
+}
+
+// Close 关闭客户端并取消所有等待中的调用
+func (c *RPCClient) Close() {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+
+	c.failPending(ErrRPCClosed)
+}
+
+// PendingCount 获取等待中的调用数量
+func (c *RPCClient) PendingCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pending)
+}
+
+// failPending 以指定错误结束所有等待中的调用
+func (c *RPCClient) failPending(err error) {
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = make(map[uint64]chan rpcResult)
+	c.mu.Unlock()
+
+	for _, ch := range pending {
+		ch <- rpcResult{err: err}
+	}
+}
+
+// removePending 移除等待中的调用
+func (c *RPCClient) removePending(seq uint64) {
+	c.mu.Lock()
+	delete(c.pending, seq)
+	c.mu.Unlock()
+}
+
+// Push 向连接推送消息(Sequence 为 0, 客户端不需要回复)
+func Push(c conn.IConn, protocolID, serviceID, msgID uint32, body proto.Message) error {
+	data, err := EncodeProto(Header{
+		ProtocolID: protocolID,
+		ServiceID:  serviceID,
+		MessageID:  msgID,
+	}, body)
+	if err != nil {
+		return err
+	}
+
+	c.Write(data)
+	return nil
+}
+
+// EncodeProto 编码消息头和 Protobuf 消息体
+func EncodeProto(header Header, body proto.Message) ([]byte, error) {
+	bodyData, err := proto.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("消息编码失败: %w", err)
+	}
+
+	return EncodePacket(header, bodyData)
+}
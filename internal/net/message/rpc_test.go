@@ -0,0 +1,179 @@
+package message_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spelens-gud/trunk/internal/net/message"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// loopConn 将服务端写入的数据异步交给RPC客户端
+type loopConn struct {
+	recordConn
+	client *message.RPCClient
+}
+
+func (c *loopConn) Write(b []byte) { go func() { _ = c.client.OnData(b) }() }
+
+// newLoopRPC 创建通过路由器回环的RPC客户端
+func newLoopRPC(router *message.Router) (*message.RPCClient, *loopConn) {
+	server := &loopConn{}
+	client := message.NewRPCClient(func(data []byte) error {
+		go func() { _ = router.OnData(server, data) }()
+		return nil
+	}, 1, 2)
+	server.client = client
+	return client, server
+}
+
+// TestRPCClient_Call 测试请求/响应关联
+func TestRPCClient_Call(t *testing.T) {
+	router := newTestRouter()
+	message.Handle(router, 1, 100, func(c *message.Context, req *wrapperspb.StringValue) (proto.Message, error) {
+		// 打乱回复顺序, 验证按序列号匹配
+		if req.GetValue() == "slow" {
+			time.Sleep(50 * time.Millisecond)
+		}
+		return wrapperspb.String("re:" + req.GetValue()), nil
+	})
+	message.Handle(router, 1, 101, func(c *message.Context, req *wrapperspb.StringValue) (proto.Message, error) {
+		return nil, message.NewCodeError(1001, "余额不足")
+	})
+	message.Handle(router, 1, 0, func(c *message.Context, req *wrapperspb.StringValue) (proto.Message, error) {
+		return wrapperspb.String("zero:" + req.GetValue()), nil
+	})
+	message.Handle(router, 1, 102, func(c *message.Context, req *wrapperspb.StringValue) (proto.Message, error) {
+		// 非法 UTF-8, 按 StringValue 解码失败
+		return wrapperspb.Bytes([]byte{0xff}), nil
+	})
+
+	client, _ := newLoopRPC(router)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		resp, err := message.CallTyped[*wrapperspb.StringValue](ctx, client, 100, wrapperspb.String("slow"))
+		if err == nil && resp.GetValue() != "re:slow" {
+			err = errors.New("slow 响应不匹配: " + resp.GetValue())
+		}
+		done <- err
+	}()
+
+	resp, err := message.CallTyped[*wrapperspb.StringValue](ctx, client, 100, wrapperspb.String("fast"))
+	if err != nil {
+		t.Fatalf("调用失败: %v", err)
+	}
+	if resp.GetValue() != "re:fast" {
+		t.Errorf("期望 're:fast', 实际 '%s'", resp.GetValue())
+	}
+	if err := <-done; err != nil {
+		t.Errorf("并发调用失败: %v", err)
+	}
+
+	// 服务端返回 ErrorCode
+	_, err = client.Call(ctx, 101, wrapperspb.String("x"))
+	var codeErr *message.CodeError
+	if !errors.As(err, &codeErr) || codeErr.Code != 1001 {
+		t.Errorf("期望 CodeError(1001), 实际 %v", err)
+	}
+
+	// 消息ID 0 与默认错误消息ID相同, 正常回复不应被当作错误
+	resp, err = message.CallTyped[*wrapperspb.StringValue](ctx, client, 0, wrapperspb.String("x"))
+	if err != nil || resp.GetValue() != "zero:x" {
+		t.Errorf("消息ID 0 期望 'zero:x', 实际 %q, %v", resp.GetValue(), err)
+	}
+
+	// 响应解码失败时保留原因
+	_, err = message.CallTyped[*wrapperspb.StringValue](ctx, client, 102, wrapperspb.String("x"))
+	if !errors.Is(err, proto.Error) {
+		t.Errorf("期望包装 proto 解码错误, 实际 %v", err)
+	}
+
+	// 未知消息
+	_, err = client.Call(ctx, 999, wrapperspb.String("x"))
+	if !errors.As(err, &codeErr) || codeErr.Code != message.ErrCodeUnknownMessage {
+		t.Errorf("期望 CodeError(%d), 实际 %v", message.ErrCodeUnknownMessage, err)
+	}
+
+	if n := client.PendingCount(); n != 0 {
+		t.Errorf("调用结束后不应有等待中的调用, 实际 %d", n)
+	}
+}
+
+// TestRPCClient_Timeout 测试超时与断线清理
+func TestRPCClient_Timeout(t *testing.T) {
+	router := newTestRouter()
+	message.Handle(router, 1, 100, func(c *message.Context, req *wrapperspb.StringValue) (proto.Message, error) {
+		return nil, nil // 不回复
+	})
+
+	client, _ := newLoopRPC(router)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := client.Call(ctx, 100, wrapperspb.String("x")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("期望超时错误, 实际 %v", err)
+	}
+	if n := client.PendingCount(); n != 0 {
+		t.Errorf("超时后应清理等待中的调用, 实际 %d", n)
+	}
+
+	// 断线时取消等待中的调用
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := client.Call(context.Background(), 100, wrapperspb.String("x"))
+		errCh <- err
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for client.PendingCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	client.OnDisconnect()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, message.ErrRPCDisconnected) {
+			t.Errorf("期望 ErrRPCDisconnected, 实际 %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("断线后调用未返回")
+	}
+
+	client.Close()
+	if _, err := client.Call(context.Background(), 100, wrapperspb.String("x")); !errors.Is(err, message.ErrRPCClosed) {
+		t.Errorf("期望 ErrRPCClosed, 实际 %v", err)
+	}
+}
+
+// TestRPCClient_Push 测试服务端推送
+func TestRPCClient_Push(t *testing.T) {
+	client, server := newLoopRPC(newTestRouter())
+
+	pushed := make(chan string, 1)
+	client.SetPushHandler(func(header message.Header, body []byte) {
+		msg := &wrapperspb.StringValue{}
+		_ = proto.Unmarshal(body, msg)
+		if header.MessageID == 200 {
+			pushed <- msg.GetValue()
+		}
+	})
+
+	if err := message.Push(server, 1, 2, 200, wrapperspb.String("notice")); err != nil {
+		t.Fatalf("推送失败: %v", err)
+	}
+
+	select {
+	case v := <-pushed:
+		if v != "notice" {
+			t.Errorf("期望 'notice', 实际 '%s'", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("超时：未收到推送")
+	}
+}
@@ -0,0 +1,164 @@
+package quic
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/spelens-gud/trunk/internal/net/conn"
+)
+
+// channelOpenTimeout 打开通道流超时(对端流数量达到上限时 OpenStreamSync 会阻塞)
+var channelOpenTimeout = 5 * time.Second
+
+// channelStream 服务端通道流
+type channelStream struct {
+	stream *quic.Stream
+	mu     sync.Mutex // 保证单帧写入的完整性
+}
+
+// writeFrame 写入一帧
+func (cs *channelStream) writeFrame(framer conn.Framer, data []byte) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return framer.WriteFrame(cs.stream, data)
+}
+
+// SendToChannel 在指定连接的通道上发送消息, 通道需由客户端先打开; 同一连接上按 ChannelPriority 调度
+func (s *NetQuicServer) SendToChannel(id uint64, channel string, data []byte) error {
+	value, ok := s.nets.Load(id)
+	if !ok {
+		return fmt.Errorf("连接不存在: %d", id)
+	}
+
+	sc := value.(*conn.Conn[*streamConn]).GetConn()
+	cs, ok := sc.getChannel(channel)
+	if !ok {
+		return fmt.Errorf("通道不存在: %s", channel)
+	}
+
+	priority := s.cnf.GetChannelPriority(channel)
+	sc.scheduler.acquire(priority)
+	defer sc.scheduler.release(priority)
+
+	return cs.writeFrame(s.cnf.GetFramer(), data)
+}
+
+// Channel 客户端逻辑通道, 每个通道独占一个QUIC流, 通道之间不会互相队头阻塞
+type Channel struct {
+	client *NetQuicClient
+	name   string
+	conn   *quic.Conn // 打开流时所在的连接, 重连后需重新打开
+	stream *quic.Stream
+	mu     sync.Mutex
+}
+
+// Channel 获取指定名称的通道, 流在首次写入时打开
+func (c *NetQuicClient) Channel(name string) *Channel {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.channels == nil {
+		c.channels = make(map[string]*Channel)
+	}
+
+	ch, ok := c.channels[name]
+	if !ok {
+		ch = &Channel{client: c, name: name}
+		c.channels[name] = ch
+	}
+	return ch
+}
+
+// Name 获取通道名称
+func (ch *Channel) Name() string {
+	return ch.name
+}
+
+// Write 在通道上写入数据, 有更高优先级(ClientConfig.ChannelPriority)的通道正在写入时等待
+func (ch *Channel) Write(data []byte) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	stream, err := ch.ensureStream()
+	if err != nil {
+		return err
+	}
+
+	priority := ch.client.cnf.GetChannelPriority(ch.name)
+	ch.client.scheduler.acquire(priority)
+	defer ch.client.scheduler.release(priority)
+
+	if err := ch.client.cnf.GetFramer().WriteFrame(stream, data); err != nil {
+		return fmt.Errorf("写入通道消息失败: %w", err)
+	}
+	return nil
+}
+
+// ensureStream 确保通道流已在当前连接上打开, 新流的第一帧为通道名
+func (ch *Channel) ensureStream() (*quic.Stream, error) {
+	if ch.name == "" {
+		return nil, fmt.Errorf("通道名不能为空")
+	}
+
+	ch.client.mu.RLock()
+	qconn := ch.client.conn
+	isStop := ch.client.isStop
+	ch.client.mu.RUnlock()
+
+	if qconn == nil || isStop {
+		return nil, fmt.Errorf("连接未建立")
+	}
+
+	if ch.stream != nil && ch.conn == qconn {
+		return ch.stream, nil
+	}
+
+	ctx, cancel := context.WithTimeout(qconn.Context(), channelOpenTimeout)
+	defer cancel()
+
+	stream, err := qconn.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("打开通道流失败: %w", err)
+	}
+
+	if err := ch.client.cnf.GetFramer().WriteFrame(stream, []byte(ch.name)); err != nil {
+		stream.CancelWrite(0)
+		return nil, fmt.Errorf("写入通道名失败: %w", err)
+	}
+
+	ch.conn = qconn
+	ch.stream = stream
+
+	go ch.client.channelReadLoop(ch.name, stream)
+
+	return stream, nil
+}
+
+// channelReadLoop 通道流读取循环, 断线由主流的 readLoop 处理
+func (c *NetQuicClient) channelReadLoop(name string, stream *quic.Stream) {
+	framer := c.cnf.GetFramer()
+	reader := bufio.NewReader(stream)
+
+	for {
+		data, err := framer.ReadFrame(reader)
+		if err != nil {
+			if conn.IsFrameError(err) {
+				c.log.Errorf("无效的消息帧: channel=%s %v", name, err)
+			}
+			return
+		}
+
+		if c.cnf.OnChannelData != nil {
+			err = c.cnf.OnChannelData(c, name, data)
+		} else if c.cnf.OnData != nil {
+			err = c.cnf.OnData(c, data)
+		}
+		if err != nil {
+			c.log.Errorf("数据处理失败: channel=%s %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package quic
+
+import (
+	"crypto/tls"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/spelens-gud/logger"
+	"github.com/spelens-gud/trunk/internal/net/conn"
+)
+
+// channelMessage 通道消息
+type channelMessage struct {
+	channel string
+	data    string
+}
+
+// TestIntegration_Channels 集成测试：命名通道的收发
+func TestIntegration_Channels(t *testing.T) {
+	log, _ := logger.NewLogger(&logger.Config{
+		Level:   "info",
+		Console: true,
+	})
+
+	port := 18480
+	serverReceived := make(chan channelMessage, 8)
+	connIDs := make(chan uint64, 1)
+
+	server := &NetQuicServer{
+		cnf: &ServerConfig{
+			Name:      "test-server",
+			Ip:        "127.0.0.1",
+			Port:      port,
+			TLSConfig: generateIntegrationTestTLSConfig(),
+			OnConnect: func(c conn.IConn) {
+				connIDs <- c.GetId()
+			},
+			OnData: func(_ conn.IConn, data []byte) error {
+				serverReceived <- channelMessage{data: string(data)}
+				return nil
+			},
+			OnChannelData: func(_ conn.IConn, channel string, data []byte) error {
+				serverReceived <- channelMessage{channel: channel, data: string(data)}
+				return nil
+			},
+		},
+		log: log,
+	}
+
+	server.New()
+	if err := server.Start(); err != nil {
+		t.Fatalf("启动服务器失败: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	clientReceived := make(chan channelMessage, 8)
+	client := &NetQuicClient{
+		cnf: &ClientConfig{
+			Name: "test-client",
+			Host: fmt.Sprintf("127.0.0.1:%d", port),
+			TLSConfig: &tls.Config{
+				InsecureSkipVerify: true,
+				NextProtos:         []string{"quic-trunk"},
+			},
+			OnChannelData: func(_ *NetQuicClient, channel string, data []byte) error {
+				clientReceived <- channelMessage{channel: channel, data: string(data)}
+				return nil
+			},
+		},
+		log: log,
+	}
+
+	client.New()
+	if err := client.Start(); err != nil {
+		t.Fatalf("启动客户端失败: %v", err)
+	}
+	defer client.Close()
+
+	var id uint64
+	select {
+	case id = <-connIDs:
+	case <-time.After(2 * time.Second):
+		t.Fatal("超时：服务端未触发 OnConnect")
+	}
+
+	if client.Channel("battle") != client.Channel("battle") {
+		t.Error("同名通道应返回同一对象")
+	}
+
+	if err := client.Write([]byte("main")); err != nil {
+		t.Fatalf("主流发送失败: %v", err)
+	}
+	if err := client.Channel("battle").Write([]byte("attack")); err != nil {
+		t.Fatalf("battle 通道发送失败: %v", err)
+	}
+	if err := client.Channel("chat").Write([]byte("hi")); err != nil {
+		t.Fatalf("chat 通道发送失败: %v", err)
+	}
+
+	// 不同流之间不保证顺序, 按通道归类校验
+	got := make(map[string]string)
+	for i := 0; i < 3; i++ {
+		select {
+		case msg := <-serverReceived:
+			got[msg.channel] = msg.data
+		case <-time.After(2 * time.Second):
+			t.Fatalf("超时：服务端仅收到 %d 条消息", i)
+		}
+	}
+	want := map[string]string{"": "main", "battle": "attack", "chat": "hi"}
+	for channel, data := range want {
+		if got[channel] != data {
+			t.Errorf("通道 %q 期望收到 %q, 实际 %q", channel, data, got[channel])
+		}
+	}
+
+	// 服务端在通道上回复
+	if err := server.SendToChannel(id, "battle", []byte("hit")); err != nil {
+		t.Fatalf("SendToChannel 失败: %v", err)
+	}
+	select {
+	case msg := <-clientReceived:
+		if msg.channel != "battle" || msg.data != "hit" {
+			t.Errorf("期望在 battle 通道收到 'hit', 实际 %q: %q", msg.channel, msg.data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("超时：客户端未收到通道回复")
+	}
+
+	if err := server.SendToChannel(id, "unknown", []byte("x")); err == nil {
+		t.Error("发送到未打开的通道应返回错误")
+	}
+	if err := client.Channel("").Write([]byte("x")); err == nil {
+		t.Error("空通道名应返回错误")
+	}
+}
+
+// TestIntegration_MaxStreamCount 集成测试：服务端限制每个连接的流数量
+func TestIntegration_MaxStreamCount(t *testing.T) {
+	log, _ := logger.NewLogger(&logger.Config{
+		Level:   "info",
+		Console: true,
+	})
+
+	oldTimeout := channelOpenTimeout
+	channelOpenTimeout = 300 * time.Millisecond
+	defer func() { channelOpenTimeout = oldTimeout }()
+
+	port := 18481
+	server := &NetQuicServer{
+		cnf: &ServerConfig{
+			Name:           "test-server",
+			Ip:             "127.0.0.1",
+			Port:           port,
+			TLSConfig:      generateIntegrationTestTLSConfig(),
+			MaxStreamCount: 2, // 主流 + 1个通道
+		},
+		log: log,
+	}
+
+	server.New()
+	if err := server.Start(); err != nil {
+		t.Fatalf("启动服务器失败: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	client := &NetQuicClient{
+		cnf: &ClientConfig{
+			Name: "test-client",
+			Host: fmt.Sprintf("127.0.0.1:%d", port),
+			TLSConfig: &tls.Config{
+				InsecureSkipVerify: true,
+				NextProtos:         []string{"quic-trunk"},
+			},
+		},
+		log: log,
+	}
+
+	client.New()
+	if err := client.Start(); err != nil {
+		t.Fatalf("启动客户端失败: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.Channel("battle").Write([]byte("a")); err != nil {
+		t.Fatalf("第一个通道应能打开: %v", err)
+	}
+	if err := client.Channel("chat").Write([]byte("b")); err == nil {
+		t.Error("超过 MaxStreamCount 时打开通道应失败")
+	}
+}
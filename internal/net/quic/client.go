@@ -1,6 +1,7 @@
 package quic
 
 import (
+	"bufio"
 	"context"
 	"crypto/tls"
 	"fmt"
@@ -22,6 +23,8 @@ type NetQuicClient struct {
 	stopChan       chan struct{}
 	isStop         bool
 	reconnectCount int32
+	channels       map[string]*Channel // 逻辑通道, key: 通道名
+	scheduler      writeScheduler      // 通道写入的优先级调度
 	mu             sync.RWMutex
 }
 
@@ -29,10 +32,14 @@ type NetQuicClient struct {
 func (c *NetQuicClient) New() {
 	c.stopChan = make(chan struct{})
 	c.isStop = true
+	c.channels = make(map[string]*Channel)
 }
 
 // Start 启动客户端
 func (c *NetQuicClient) Start() error {
+	if err := c.cnf.initFramer(); err != nil {
+		return fmt.Errorf("分帧配置错误: %w", err)
+	}
 	return c.connect()
 }
 
@@ -51,6 +58,7 @@ func (c *NetQuicClient) connect() error {
 	quicConfig := &quic.Config{
 		MaxIdleTimeout:  30 * time.Second,
 		KeepAlivePeriod: 10 * time.Second,
+		EnableDatagrams: c.cnf.EnableDatagrams,
 	}
 
 	conn, err := quic.DialAddr(ctx, c.cnf.Host, tlsConf, quicConfig)
@@ -79,47 +87,36 @@ func (c *NetQuicClient) connect() error {
 	go c.readLoop()
 	go c.pingLoop()
 
+	if c.cnf.EnableDatagrams {
+		go c.datagramLoop(conn)
+	}
+
 	return nil
 }
 
 // readLoop 读取数据循环
 func (c *NetQuicClient) readLoop() {
-	for {
-		if c.isStop {
-			return
-		}
+	c.mu.RLock()
+	stream := c.stream
+	c.mu.RUnlock()
 
-		c.mu.RLock()
-		stream := c.stream
-		c.mu.RUnlock()
+	if stream == nil {
+		return
+	}
 
-		if stream == nil {
-			return
-		}
+	framer := c.cnf.GetFramer()
+	reader := bufio.NewReader(stream)
 
-		// 读取消息长度（4字节）
-		lenBuf := make([]byte, 4)
-		if _, err := io.ReadFull(stream, lenBuf); err != nil {
-			if err != io.EOF && !c.isStop {
-				c.log.Errorf("读取消息长度失败: %v", err)
-			}
-			c.handleDisconnect()
-			return
-		}
-
-		// 解析消息长度
-		msgLen := uint32(lenBuf[0])<<24 | uint32(lenBuf[1])<<16 | uint32(lenBuf[2])<<8 | uint32(lenBuf[3])
-		if msgLen == 0 || msgLen > 1024*1024 { // 最大1MB
-			c.log.Errorf("无效的消息长度: %d", msgLen)
-			c.handleDisconnect()
+	for {
+		if c.isStop {
 			return
 		}
 
-		// 读取消息内容
-		data := make([]byte, msgLen)
-		if _, err := io.ReadFull(stream, data); err != nil {
+		// 按配置的分帧方式读取一条完整消息
+		data, err := framer.ReadFrame(reader)
+		if err != nil {
 			if err != io.EOF && !c.isStop {
-				c.log.Errorf("读取消息内容失败: %v", err)
+				c.log.Errorf("读取消息失败: %v", err)
 			}
 			c.handleDisconnect()
 			return
@@ -205,22 +202,9 @@ func (c *NetQuicClient) Write(data []byte) error {
 		return fmt.Errorf("流未连接")
 	}
 
-	// 写入消息长度（4字节）
-	msgLen := uint32(len(data))
-	lenBuf := []byte{
-		byte(msgLen >> 24),
-		byte(msgLen >> 16),
-		byte(msgLen >> 8),
-		byte(msgLen),
-	}
-
-	if _, err := c.stream.Write(lenBuf); err != nil {
-		return fmt.Errorf("写入消息长度失败: %w", err)
-	}
-
-	// 写入消息内容
-	if _, err := c.stream.Write(data); err != nil {
-		return fmt.Errorf("写入消息内容失败: %w", err)
+	// 按配置的分帧方式写入消息
+	if err := c.cnf.GetFramer().WriteFrame(c.stream, data); err != nil {
+		return fmt.Errorf("写入消息失败: %w", err)
 	}
 
 	return nil
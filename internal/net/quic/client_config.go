@@ -3,20 +3,47 @@ package quic
 import (
 	"crypto/tls"
 	"time"
+
+	"github.com/spelens-gud/trunk/internal/net/conn"
 )
 
 // ClientConfig QUIC客户端配置
 type ClientConfig struct {
-	Name             string                                         // 客户端名称
-	Host             string                                         // 服务器地址
-	TLSConfig        *tls.Config                                    // TLS配置
-	PingTicker       time.Duration                                  // 心跳间隔
-	PingFunc         func(client *NetQuicClient)                    // 心跳函数
-	FirstPingFunc    func(client *NetQuicClient)                    // 首次连接心跳函数
-	ReconnectEnabled bool                                           // 是否启用重连
-	ReconnectDelay   time.Duration                                  // 重连延迟
-	MaxReconnect     int                                            // 最大重连次数
-	OnReconnect      func(client *NetQuicClient)                    // 重连成功回调
-	OnDisconnect     func(client *NetQuicClient)                    // 断开连接回调
-	OnData           func(client *NetQuicClient, data []byte) error // 数据处理回调
+	Name             string                                                         // 客户端名称
+	Host             string                                                         // 服务器地址
+	TLSConfig        *tls.Config                                                    // TLS配置
+	PingTicker       time.Duration                                                  // 心跳间隔
+	PingFunc         func(client *NetQuicClient)                                    // 心跳函数
+	FirstPingFunc    func(client *NetQuicClient)                                    // 首次连接心跳函数
+	ReconnectEnabled bool                                                           // 是否启用重连
+	ReconnectDelay   time.Duration                                                  // 重连延迟
+	MaxReconnect     int                                                            // 最大重连次数
+	OnReconnect      func(client *NetQuicClient)                                    // 重连成功回调
+	OnDisconnect     func(client *NetQuicClient)                                    // 断开连接回调
+	OnData           func(client *NetQuicClient, data []byte) error                 // 数据处理回调
+	OnChannelData    func(client *NetQuicClient, channel string, data []byte) error // 通道数据处理回调，未设置时通道数据交给 OnData
+	Framer           conn.Framer                                                    // 流分帧器，优先于 FramerConfig
+	FramerConfig     conn.FramerConfig                                              `yaml:"framer"` // 流分帧配置，未设置 Framer 时按配置创建，默认4字节大端序长度前缀(最大1MB)
+	EnableDatagrams  bool                                                           // 是否启用QUIC数据报(RFC 9221)
+	OnDatagram       func(client *NetQuicClient, data []byte) error                 // 数据报处理回调(不可靠、无序)
+	ChannelPriority  map[string]int                                                 `yaml:"channelPriority"` // 通道写入优先级，数值越大越优先，未配置的通道为0
+}
+
+// GetChannelPriority 获取通道的写入优先级, 未配置时为0
+func (c *ClientConfig) GetChannelPriority(channel string) int {
+	return c.ChannelPriority[channel]
+}
+
+// initFramer 确定流分帧器, 见 conn.FramerConfig.Resolve
+func (c *ClientConfig) initFramer() (err error) {
+	c.Framer, err = c.FramerConfig.Resolve(c.Framer)
+	return err
+}
+
+// GetFramer 获取流分帧器, 未初始化时为默认分帧器
+func (c *ClientConfig) GetFramer() conn.Framer {
+	if c.Framer == nil {
+		return conn.DefaultFramer()
+	}
+	return c.Framer
 }
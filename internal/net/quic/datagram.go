@@ -0,0 +1,101 @@
+package quic
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/quic-go/quic-go"
+	"github.com/spelens-gud/trunk/internal/net/conn"
+)
+
+// ErrDatagramNotSupported 本端或对端未启用QUIC数据报
+var ErrDatagramNotSupported = errors.New("QUIC数据报未启用")
+
+// sendDatagram 在连接上发送一个数据报
+func sendDatagram(qconn *quic.Conn, data []byte) error {
+	if !qconn.ConnectionState().SupportsDatagrams {
+		return ErrDatagramNotSupported
+	}
+
+	if err := qconn.SendDatagram(data); err != nil {
+		return fmt.Errorf("发送数据报失败: %w", err)
+	}
+	return nil
+}
+
+// datagramLoop 服务端数据报接收循环, 连接关闭时退出
+func (s *NetQuicServer) datagramLoop(qconn *quic.Conn, cn conn.IConn) {
+	for {
+		data, err := qconn.ReceiveDatagram(qconn.Context())
+		if err != nil {
+			return
+		}
+
+		if s.cnf.OnDatagram != nil {
+			if err := s.cnf.OnDatagram(cn, data); err != nil {
+				s.log.Errorf("数据报处理失败: %v", err)
+			}
+		}
+	}
+}
+
+// SendDatagram 发送数据报给指定连接(不可靠、无序, 大小受路径MTU限制)
+func (s *NetQuicServer) SendDatagram(id uint64, data []byte) error {
+	if !s.cnf.EnableDatagrams {
+		return ErrDatagramNotSupported
+	}
+
+	value, ok := s.nets.Load(id)
+	if !ok {
+		return fmt.Errorf("连接不存在: %d", id)
+	}
+
+	return sendDatagram(value.(*conn.Conn[*streamConn]).GetConn().conn, data)
+}
+
+// BroadcastDatagram 广播数据报给所有连接, 单个连接发送失败不影响其他连接
+func (s *NetQuicServer) BroadcastDatagram(data []byte) {
+	if !s.cnf.EnableDatagrams {
+		return
+	}
+
+	s.nets.Range(func(key, value interface{}) bool {
+		if err := sendDatagram(value.(*conn.Conn[*streamConn]).GetConn().conn, data); err != nil {
+			s.log.Debugf("广播数据报失败: id=%v %v", key, err)
+		}
+		return true
+	})
+}
+
+// datagramLoop 客户端数据报接收循环, 连接关闭时退出
+func (c *NetQuicClient) datagramLoop(qconn *quic.Conn) {
+	for {
+		data, err := qconn.ReceiveDatagram(qconn.Context())
+		if err != nil {
+			return
+		}
+
+		if c.cnf.OnDatagram != nil {
+			if err := c.cnf.OnDatagram(c, data); err != nil {
+				c.log.Errorf("数据报处理失败: %v", err)
+			}
+		}
+	}
+}
+
+// SendDatagram 发送数据报(不可靠、无序, 大小受路径MTU限制)
+func (c *NetQuicClient) SendDatagram(data []byte) error {
+	if !c.cnf.EnableDatagrams {
+		return ErrDatagramNotSupported
+	}
+
+	c.mu.RLock()
+	qconn := c.conn
+	c.mu.RUnlock()
+
+	if qconn == nil {
+		return fmt.Errorf("连接未建立")
+	}
+
+	return sendDatagram(qconn, data)
+}
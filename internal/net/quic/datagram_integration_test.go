@@ -0,0 +1,205 @@
+package quic
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/spelens-gud/logger"
+	"github.com/spelens-gud/trunk/internal/net/conn"
+)
+
+// TestIntegration_DatagramVsStream 集成测试：对比回环上数据报与流的投递情况
+func TestIntegration_DatagramVsStream(t *testing.T) {
+	log, _ := logger.NewLogger(&logger.Config{
+		Level:   "info",
+		Console: true,
+	})
+
+	port := 18470
+	var server *NetQuicServer
+
+	serverConfig := &ServerConfig{
+		Name:            "test-server",
+		Ip:              "127.0.0.1",
+		Port:            port,
+		TLSConfig:       generateIntegrationTestTLSConfig(),
+		EnableDatagrams: true,
+		OnData: func(c conn.IConn, data []byte) error {
+			c.Write(data)
+			return nil
+		},
+		OnDatagram: func(c conn.IConn, data []byte) error {
+			return server.SendDatagram(c.GetId(), data)
+		},
+	}
+
+	server = &NetQuicServer{
+		cnf: serverConfig,
+		log: log,
+	}
+
+	server.New()
+	if err := server.Start(); err != nil {
+		t.Fatalf("启动服务器失败: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	streamCh := make(chan []byte, 16)
+	datagramCh := make(chan []byte, 16)
+	client := &NetQuicClient{
+		cnf: &ClientConfig{
+			Name: "test-client",
+			Host: fmt.Sprintf("127.0.0.1:%d", port),
+			TLSConfig: &tls.Config{
+				InsecureSkipVerify: true,
+				NextProtos:         []string{"quic-trunk"},
+			},
+			EnableDatagrams: true,
+			OnData: func(_ *NetQuicClient, data []byte) error {
+				streamCh <- data
+				return nil
+			},
+			OnDatagram: func(_ *NetQuicClient, data []byte) error {
+				datagramCh <- data
+				return nil
+			},
+		},
+		log: log,
+	}
+
+	client.New()
+	if err := client.Start(); err != nil {
+		t.Fatalf("启动客户端失败: %v", err)
+	}
+	defer client.Close()
+
+	// 服务端在主流首次写入后才绑定连接, 先用流消息完成握手
+	if err := client.Write([]byte("hello")); err != nil {
+		t.Fatalf("发送数据失败: %v", err)
+	}
+	select {
+	case <-streamCh:
+	case <-time.After(2 * time.Second):
+		t.Fatal("超时：未收到流回显")
+	}
+
+	const total = 100
+
+	// roundTrip 逐条发送并等待回显, 返回送达数量和平均往返时延
+	roundTrip := func(send func([]byte) error, recv chan []byte) (int, time.Duration) {
+		delivered := 0
+		var elapsed time.Duration
+		for i := 0; i < total; i++ {
+			payload := []byte(fmt.Sprintf("pos-%d", i))
+			start := time.Now()
+			if err := send(payload); err != nil {
+				t.Fatalf("发送失败: %v", err)
+			}
+
+			// 数据报可能丢失, 超时则视为未送达
+			timeout := time.After(200 * time.Millisecond)
+		wait:
+			for {
+				select {
+				case data := <-recv:
+					if string(data) == string(payload) {
+						delivered++
+						elapsed += time.Since(start)
+						break wait
+					}
+				case <-timeout:
+					break wait
+				}
+			}
+		}
+
+		if delivered == 0 {
+			return 0, 0
+		}
+		return delivered, elapsed / time.Duration(delivered)
+	}
+
+	streamDelivered, streamRTT := roundTrip(client.Write, streamCh)
+	datagramDelivered, datagramRTT := roundTrip(client.SendDatagram, datagramCh)
+
+	t.Logf("流: 送达 %d/%d, 平均往返 %v", streamDelivered, total, streamRTT)
+	t.Logf("数据报: 送达 %d/%d, 平均往返 %v", datagramDelivered, total, datagramRTT)
+
+	if streamDelivered != total {
+		t.Errorf("流消息应全部送达: 期望 %d, 实际 %d", total, streamDelivered)
+	}
+	// 回环网络几乎不丢包, 但数据报不保证送达, 只要求大部分到达
+	if datagramDelivered < total*8/10 {
+		t.Errorf("数据报丢失过多: 期望至少 %d, 实际 %d", total*8/10, datagramDelivered)
+	}
+
+	// 服务端广播数据报
+	server.BroadcastDatagram([]byte("snapshot"))
+	select {
+	case data := <-datagramCh:
+		if string(data) != "snapshot" {
+			t.Errorf("期望收到 'snapshot', 实际收到 '%s'", data)
+		}
+	case <-time.After(time.Second):
+		t.Error("超时：未收到广播数据报")
+	}
+}
+
+// TestIntegration_DatagramDisabled 集成测试：未启用数据报时发送返回错误
+func TestIntegration_DatagramDisabled(t *testing.T) {
+	log, _ := logger.NewLogger(&logger.Config{
+		Level:   "info",
+		Console: true,
+	})
+
+	port := 18471
+	server := &NetQuicServer{
+		cnf: &ServerConfig{
+			Name:      "test-server",
+			Ip:        "127.0.0.1",
+			Port:      port,
+			TLSConfig: generateIntegrationTestTLSConfig(),
+		},
+		log: log,
+	}
+
+	server.New()
+	if err := server.Start(); err != nil {
+		t.Fatalf("启动服务器失败: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	// 客户端启用而服务端未启用, 对端不支持数据报
+	client := &NetQuicClient{
+		cnf: &ClientConfig{
+			Name: "test-client",
+			Host: fmt.Sprintf("127.0.0.1:%d", port),
+			TLSConfig: &tls.Config{
+				InsecureSkipVerify: true,
+				NextProtos:         []string{"quic-trunk"},
+			},
+			EnableDatagrams: true,
+		},
+		log: log,
+	}
+
+	client.New()
+	if err := client.Start(); err != nil {
+		t.Fatalf("启动客户端失败: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.SendDatagram([]byte("x")); !errors.Is(err, ErrDatagramNotSupported) {
+		t.Errorf("期望 ErrDatagramNotSupported, 实际 = %v", err)
+	}
+	if err := server.SendDatagram(1, []byte("x")); !errors.Is(err, ErrDatagramNotSupported) {
+		t.Errorf("期望 ErrDatagramNotSupported, 实际 = %v", err)
+	}
+}
@@ -584,3 +584,124 @@ func TestIntegration_HighThroughput(t *testing.T) {
 		t.Errorf("数据丢失过多: 期望 %d KB, 接收 %d KB", expectedBytes/1024, bytes/1024)
 	}
 }
+
+// TestIntegration_ServerReply 集成测试：服务端通过连接对象回复客户端
+func TestIntegration_ServerReply(t *testing.T) {
+	log, _ := logger.NewLogger(&logger.Config{
+		Level:   "info",
+		Console: true,
+	})
+
+	port := 18460
+	var connected, closed int32
+	connIDs := make(chan uint64, 1)
+
+	serverConfig := &ServerConfig{
+		Name:      "test-server",
+		Ip:        "127.0.0.1",
+		Port:      port,
+		TLSConfig: generateIntegrationTestTLSConfig(),
+		OnConnect: func(c conn.IConn) {
+			atomic.AddInt32(&connected, 1)
+			connIDs <- c.GetId()
+		},
+		OnData: func(c conn.IConn, data []byte) error {
+			// 回显数据
+			c.Write(append([]byte("echo:"), data...))
+			return nil
+		},
+		OnClose: func(c conn.IConn) error {
+			atomic.AddInt32(&closed, 1)
+			return nil
+		},
+	}
+
+	server := &NetQuicServer{
+		cnf: serverConfig,
+		log: log,
+	}
+
+	server.New()
+	if err := server.Start(); err != nil {
+		t.Fatalf("启动服务器失败: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	received := make(chan []byte, 4)
+	client := &NetQuicClient{
+		cnf: &ClientConfig{
+			Name: "test-client",
+			Host: fmt.Sprintf("127.0.0.1:%d", port),
+			TLSConfig: &tls.Config{
+				InsecureSkipVerify: true,
+				NextProtos:         []string{"quic-trunk"},
+			},
+			OnData: func(_ *NetQuicClient, data []byte) error {
+				received <- data
+				return nil
+			},
+		},
+		log: log,
+	}
+
+	client.New()
+	if err := client.Start(); err != nil {
+		t.Fatalf("启动客户端失败: %v", err)
+	}
+
+	var id uint64
+	select {
+	case id = <-connIDs:
+	case <-time.After(2 * time.Second):
+		t.Fatal("超时：服务端未触发 OnConnect")
+	}
+
+	if err := client.Write([]byte("hello")); err != nil {
+		t.Fatalf("发送数据失败: %v", err)
+	}
+
+	select {
+	case data := <-received:
+		if string(data) != "echo:hello" {
+			t.Errorf("期望收到 'echo:hello', 实际收到 '%s'", data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("超时：未收到服务端回复")
+	}
+
+	// 服务端主动推送
+	if !server.SendTo(id, []byte("push")) {
+		t.Fatalf("SendTo 连接 %d 失败", id)
+	}
+	server.BroadcastMessage([]byte("broadcast"))
+
+	for _, want := range []string{"push", "broadcast"} {
+		select {
+		case data := <-received:
+			if string(data) != want {
+				t.Errorf("期望收到 '%s', 实际收到 '%s'", want, data)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("超时：未收到 '%s'", want)
+		}
+	}
+
+	if server.SendTo(id+1000, []byte("none")) {
+		t.Error("发送给不存在的连接应返回 false")
+	}
+
+	_ = client.Close()
+	time.Sleep(300 * time.Millisecond)
+
+	if atomic.LoadInt32(&connected) != 1 {
+		t.Errorf("期望 OnConnect 调用 1 次, 实际 %d 次", connected)
+	}
+	if atomic.LoadInt32(&closed) != 1 {
+		t.Errorf("期望 OnClose 调用 1 次, 实际 %d 次", closed)
+	}
+	if _, ok := server.GetConnection(id); ok {
+		t.Error("连接关闭后应从连接表中移除")
+	}
+}
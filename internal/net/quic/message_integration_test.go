@@ -1,6 +1,7 @@
 package quic
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"sync/atomic"
@@ -8,7 +9,10 @@ import (
 	"time"
 
 	"github.com/spelens-gud/logger"
+	"github.com/spelens-gud/trunk/internal/net/conn"
 	"github.com/spelens-gud/trunk/internal/net/message"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 // UserRequest 用户请求消息
@@ -319,3 +323,212 @@ func TestIntegration_MessageMultiple(t *testing.T) {
 		t.Errorf("期望接收 %d 条消息, 实际接收 %d 条", messageCount, count)
 	}
 }
+
+// TestIntegration_MessageRPC 测试基于序列号的请求/响应与服务端推送
+func TestIntegration_MessageRPC(t *testing.T) {
+	log, _ := logger.NewLogger(&logger.Config{
+		Level:   "info",
+		Console: true,
+	})
+
+	port := 18490
+	router := message.NewRouter(log)
+	message.Handle(router, 1, 100, func(c *message.Context, req *wrapperspb.StringValue) (proto.Message, error) {
+		// 先推送再回复
+		if err := message.Push(c.Conn, 1, 0, 200, wrapperspb.String("notice")); err != nil {
+			return nil, err
+		}
+		return wrapperspb.String("re:" + req.GetValue()), nil
+	})
+
+	server := &NetQuicServer{
+		cnf: &ServerConfig{
+			Name:      "rpc-server",
+			Ip:        "127.0.0.1",
+			Port:      port,
+			TLSConfig: generateIntegrationTestTLSConfig(),
+			OnData:    router.OnData,
+		},
+		log: log,
+	}
+
+	server.New()
+	if err := server.Start(); err != nil {
+		t.Fatalf("启动服务器失败: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	var rpc *message.RPCClient
+	client := &NetQuicClient{
+		cnf: &ClientConfig{
+			Name: "rpc-client",
+			Host: fmt.Sprintf("127.0.0.1:%d", port),
+			TLSConfig: &tls.Config{
+				InsecureSkipVerify: true,
+				NextProtos:         []string{"quic-trunk"},
+			},
+			OnData: func(_ *NetQuicClient, data []byte) error {
+				return rpc.OnData(data)
+			},
+			OnDisconnect: func(_ *NetQuicClient) {
+				rpc.OnDisconnect()
+			},
+		},
+		log: log,
+	}
+	rpc = message.NewRPCClient(client.Write, 1, 0)
+
+	pushed := make(chan string, 1)
+	rpc.SetPushHandler(func(header message.Header, body []byte) {
+		msg := &wrapperspb.StringValue{}
+		_ = proto.Unmarshal(body, msg)
+		pushed <- msg.GetValue()
+	})
+
+	client.New()
+	if err := client.Start(); err != nil {
+		t.Fatalf("启动客户端失败: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	resp, err := message.CallTyped[*wrapperspb.StringValue](ctx, rpc, 100, wrapperspb.String("quic"))
+	if err != nil {
+		t.Fatalf("调用失败: %v", err)
+	}
+	if resp.GetValue() != "re:quic" {
+		t.Errorf("期望 're:quic', 实际 '%s'", resp.GetValue())
+	}
+
+	select {
+	case v := <-pushed:
+		if v != "notice" {
+			t.Errorf("期望推送 'notice', 实际 '%s'", v)
+		}
+	case <-time.After(time.Second):
+		t.Error("超时：未收到推送")
+	}
+}
+
+// TestIntegration_SecureMessage 测试首次连接时完成密钥交换并收发加密消息
+func TestIntegration_SecureMessage(t *testing.T) {
+	log, _ := logger.NewLogger(&logger.Config{
+		Level:   "info",
+		Console: true,
+	})
+
+	port := 18491
+	var secureServer *message.SecureServer
+	secureServer = message.NewSecureServer(func(c conn.IConn, data []byte) error {
+		session, _ := secureServer.Session(c)
+		codec := message.NewEncryptedCodec[[]byte](message.NewRawCodec(), session)
+
+		req := message.NewMessage[[]byte](codec, 0, 0, 0)
+		if err := req.Decode(data); err != nil {
+			return err
+		}
+
+		header := req.GetHeader()
+		resp := message.NewMessage[[]byte](codec, header.ProtocolID, header.ServiceID, header.MessageID)
+		resp.SetSequence(header.Sequence)
+		resp.SetBody(append([]byte("re:"), req.GetBody()...))
+		out, err := resp.Encode()
+		if err != nil {
+			return err
+		}
+		c.Write(out)
+		return nil
+	})
+
+	server := &NetQuicServer{
+		cnf: &ServerConfig{
+			Name:      "secure-server",
+			Ip:        "127.0.0.1",
+			Port:      port,
+			TLSConfig: generateIntegrationTestTLSConfig(),
+			OnConnect: secureServer.OnConnect,
+			OnData:    secureServer.OnData,
+			OnClose:   secureServer.OnClose,
+		},
+		log: log,
+	}
+
+	server.New()
+	if err := server.Start(); err != nil {
+		t.Fatalf("启动服务器失败: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	secureClient := message.NewSecureClient()
+	replies := make(chan []byte, 1)
+	client := &NetQuicClient{
+		cnf: &ClientConfig{
+			Name: "secure-client",
+			Host: fmt.Sprintf("127.0.0.1:%d", port),
+			TLSConfig: &tls.Config{
+				InsecureSkipVerify: true,
+				NextProtos:         []string{"quic-trunk"},
+			},
+			FirstPingFunc: func(c *NetQuicClient) {
+				hello, err := secureClient.Hello()
+				if err != nil {
+					t.Errorf("生成握手消息失败: %v", err)
+					return
+				}
+				_ = c.Write(hello)
+			},
+			OnData: func(_ *NetQuicClient, data []byte) error {
+				if handled, err := secureClient.HandleData(data); handled {
+					return err
+				}
+				replies <- data
+				return nil
+			},
+		},
+		log: log,
+	}
+
+	client.New()
+	if err := client.Start(); err != nil {
+		t.Fatalf("启动客户端失败: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	session, err := secureClient.Session(ctx)
+	if err != nil {
+		t.Fatalf("密钥交换失败: %v", err)
+	}
+
+	codec := message.NewEncryptedCodec[[]byte](message.NewRawCodec(), session)
+	req := message.NewMessage[[]byte](codec, 1, 0, 100)
+	req.SetSequence(1)
+	req.SetBody([]byte("quic"))
+	data, err := req.Encode()
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	if err := client.Write(data); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+
+	select {
+	case reply := <-replies:
+		resp := message.NewMessage[[]byte](codec, 0, 0, 0)
+		if err := resp.Decode(reply); err != nil {
+			t.Fatalf("解密回复失败: %v", err)
+		}
+		if string(resp.GetBody()) != "re:quic" {
+			t.Errorf("期望 're:quic', 实际 '%s'", resp.GetBody())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("超时：未收到回复")
+	}
+}
@@ -0,0 +1,49 @@
+package quic
+
+import "sync"
+
+// writeScheduler 通道写入的优先级调度, 有更高优先级的通道正在写入时, 低优先级的写入等待
+//
+// quic-go 未提供流调度优先级, 各流的数据按轮转发送; 流的写入在发送窗口用尽时阻塞,
+// 此时推迟低优先级通道的写入, 让出拥塞和流量控制窗口。发送不受限时写入立即完成, 调度不产生等待
+type writeScheduler struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	writing map[int]int // 各优先级正在写入的数量
+}
+
+// acquire 等待没有更高优先级的写入后开始写入, 写入结束后需调用 release
+func (s *writeScheduler) acquire(priority int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cond == nil {
+		s.cond = sync.NewCond(&s.mu)
+		s.writing = make(map[int]int)
+	}
+	for s.higherWriting(priority) {
+		s.cond.Wait()
+	}
+	s.writing[priority]++
+}
+
+// release 结束写入, 唤醒等待的写入
+func (s *writeScheduler) release(priority int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.writing[priority]--; s.writing[priority] <= 0 {
+		delete(s.writing, priority)
+	}
+	s.cond.Broadcast()
+}
+
+// higherWriting 是否有更高优先级的写入, 调用方需持有锁
+func (s *writeScheduler) higherWriting(priority int) bool {
+	for p := range s.writing {
+		if p > priority {
+			return true
+		}
+	}
+	return false
+}
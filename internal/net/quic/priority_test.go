@@ -0,0 +1,45 @@
+package quic
+
+import (
+	"testing"
+	"time"
+)
+
+// TestWriteScheduler 测试高优先级写入期间低优先级写入等待, 同优先级不互相等待
+func TestWriteScheduler(t *testing.T) {
+	var s writeScheduler
+
+	s.acquire(10)
+
+	// 同优先级不等待
+	done := make(chan struct{})
+	go func() {
+		s.acquire(10)
+		s.release(10)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("同优先级的写入不应等待")
+	}
+
+	low := make(chan struct{})
+	go func() {
+		s.acquire(0)
+		s.release(0)
+		close(low)
+	}()
+	select {
+	case <-low:
+		t.Fatal("高优先级写入期间低优先级写入应等待")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	s.release(10)
+	select {
+	case <-low:
+	case <-time.After(time.Second):
+		t.Fatal("高优先级写入结束后低优先级写入应继续")
+	}
+}
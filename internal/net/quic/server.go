@@ -1,11 +1,12 @@
 package quic
 
 import (
+	"bufio"
 	"context"
 	"fmt"
-	"io"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/quic-go/quic-go"
 	"github.com/spelens-gud/logger"
@@ -18,10 +19,12 @@ type NetQuicServer struct {
 	log           logger.ILogger
 	listener      *quic.Listener
 	stopChan      chan chan struct{}
-	nets          sync.Map
+	nets          sync.Map // 所有连接, key: 连接ID, value: *conn.Conn[*streamConn]
+	nextID        uint64
 	connCount     int32
 	totalAccepted int64
 	totalRejected int64
+	draining      atomic.Bool // 正在下线, 已关闭监听
 }
 
 // ServerStats 服务器统计信息
@@ -39,11 +42,19 @@ func (s *NetQuicServer) New() {
 
 // Start 启动服务器
 func (s *NetQuicServer) Start() error {
+	if err := s.cnf.initFramer(); err != nil {
+		return fmt.Errorf("分帧配置错误: %w", err)
+	}
+
 	addr := fmt.Sprintf("%s:%d", s.cnf.Ip, s.cnf.Port)
 
 	quicConfig := &quic.Config{
 		MaxIdleTimeout:  s.cnf.IdleTimeout,
 		KeepAlivePeriod: s.cnf.KeepAlivePeriod,
+		EnableDatagrams: s.cnf.EnableDatagrams,
+	}
+	if s.cnf.MaxStreamCount > 0 {
+		quicConfig.MaxIncomingStreams = s.cnf.MaxStreamCount
 	}
 
 	listener, err := quic.ListenAddr(addr, s.cnf.TLSConfig, quicConfig)
@@ -103,8 +114,31 @@ func (s *NetQuicServer) handleConnection(qconn *quic.Conn) {
 		_ = qconn.CloseWithError(0, "")
 	}()
 
+	// 创建连接, 主流在客户端首次写入后绑定
+	sc := newStreamConn(qconn)
+	cn := conn.NewConn(sc, conn.NetConfig[*streamConn]{
+		Id:      atomic.AddUint64(&s.nextID, 1),
+		Name:    s.cnf.Name,
+		Host:    qconn.RemoteAddr().String(),
+		OnWrite: s.onWriteFunc,
+		OnRead:  s.onReadFunc,
+		OnClose: s.onCloseFunc,
+		OnData:  s.cnf.OnData,
+	})
+	cn.SetLogger(s.log)
+
+	s.nets.Store(cn.GetId(), cn)
+	s.log.Debugf("新连接建立: id=%d 来源:%s", cn.GetId(), qconn.RemoteAddr())
+
 	if s.cnf.OnConnect != nil {
-		s.cnf.OnConnect(nil)
+		s.cnf.OnConnect(cn)
+	}
+
+	// 启动连接的读写循环(读写均在主流上进行)
+	cn.Start()
+
+	if s.cnf.EnableDatagrams {
+		go s.datagramLoop(qconn, cn)
 	}
 
 	// 接受流
@@ -117,48 +151,92 @@ func (s *NetQuicServer) handleConnection(qconn *quic.Conn) {
 			} else {
 				s.log.Errorf("接受流失败: %v", err)
 			}
-			if s.cnf.OnClose != nil {
-				_ = s.cnf.OnClose(nil)
-			}
-			return
+			break
+		}
+
+		// 第一个流作为主流, 其余流为通道流并共用同一个连接对象
+		if !sc.setStream(stream) {
+			go s.handleStream(stream, cn)
 		}
+	}
+
+	_ = cn.Close()
+	s.nets.Delete(cn.GetId())
 
-		go s.handleStream(stream)
+	if s.cnf.OnClose != nil {
+		_ = s.cnf.OnClose(cn)
 	}
 }
 
-// handleStream 处理流
-func (s *NetQuicServer) handleStream(stream *quic.Stream) {
+// handleStream 处理通道流, 流上第一帧为通道名
+func (s *NetQuicServer) handleStream(stream *quic.Stream, cn *conn.Conn[*streamConn]) {
 	defer stream.Close()
 
-	for {
-		// 读取消息长度（4字节）
-		lenBuf := make([]byte, 4)
-		if _, err := io.ReadFull(stream, lenBuf); err != nil {
-			return
+	framer := s.cnf.GetFramer()
+	reader := bufio.NewReader(stream)
+
+	name, err := framer.ReadFrame(reader)
+	if err != nil {
+		if conn.IsFrameError(err) {
+			s.log.Errorf("无效的通道名帧: %v", err)
 		}
+		return
+	}
 
-		// 解析消息长度
-		msgLen := uint32(lenBuf[0])<<24 | uint32(lenBuf[1])<<16 | uint32(lenBuf[2])<<8 | uint32(lenBuf[3])
-		if msgLen == 0 || msgLen > 1024*1024 { // 最大1MB
-			s.log.Errorf("无效的消息长度: %d", msgLen)
+	channel := string(name)
+	cs := cn.GetConn().addChannel(channel, stream)
+	defer cn.GetConn().removeChannel(channel, cs)
+
+	for {
+		// 按配置的分帧方式读取一条完整消息
+		data, err := framer.ReadFrame(reader)
+		if err != nil {
+			if conn.IsFrameError(err) {
+				s.log.Errorf("无效的消息帧: %v", err)
+			}
 			return
 		}
 
-		// 读取消息内容
-		data := make([]byte, msgLen)
-		if _, err := io.ReadFull(stream, data); err != nil {
-			s.log.Errorf("读取消息内容失败: %v", err)
+		if err := s.dispatchChannel(cn, channel, data); err != nil {
+			s.log.Errorf("数据处理失败: channel=%s %v", channel, err)
 			return
 		}
+	}
+}
 
-		if s.cnf.OnData != nil {
-			if err := s.cnf.OnData(nil, data); err != nil {
-				s.log.Errorf("数据处理失败: %v", err)
-				return
-			}
-		}
+// dispatchChannel 分发通道消息, 未设置 OnChannelData 时回退到 OnData
+func (s *NetQuicServer) dispatchChannel(cn conn.IConn, channel string, data []byte) error {
+	if s.cnf.OnChannelData != nil {
+		return s.cnf.OnChannelData(cn, channel, data)
+	}
+	if s.cnf.OnData != nil {
+		return s.cnf.OnData(cn, data)
+	}
+	return nil
+}
+
+// onReadFunc 主流读取数据处理函数
+func (s *NetQuicServer) onReadFunc(sc *streamConn) (int, []byte, error) {
+	if err := sc.waitStream(); err != nil {
+		return 0, nil, err
+	}
+
+	data, err := s.cnf.GetFramer().ReadFrame(sc.reader)
+	return len(data), data, err
+}
+
+// onWriteFunc 主流写数据处理函数
+func (s *NetQuicServer) onWriteFunc(sc *streamConn, data []byte) error {
+	if err := sc.waitStream(); err != nil {
+		return err
 	}
+
+	return s.cnf.GetFramer().WriteFrame(sc.stream, data)
+}
+
+// onCloseFunc 关闭连接处理函数
+func (s *NetQuicServer) onCloseFunc(sc *streamConn) error {
+	return sc.conn.CloseWithError(0, "")
 }
 
 // handleStop 处理停止信号
@@ -187,6 +265,31 @@ func (s *NetQuicServer) Stop() {
 	s.log.Infof("QUIC服务器已停止")
 }
 
+// Drain 关闭监听停止接受新连接, 已建立的连接不受影响
+func (s *NetQuicServer) Drain() {
+	if s.draining.Swap(true) || s.listener == nil {
+		return
+	}
+
+	s.log.Infof("开始下线，停止接受新连接，当前连接数:%d", s.GetConnectionCount())
+	_ = s.listener.Close()
+}
+
+// WaitIdle 等待已建立的连接全部关闭, ctx 结束时返回 ctx 的错误
+func (s *NetQuicServer) WaitIdle(ctx context.Context) error {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for s.GetConnectionCount() > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
 // GetStats 获取统计信息
 func (s *NetQuicServer) GetStats() ServerStats {
 	return ServerStats{
@@ -201,6 +304,44 @@ func (s *NetQuicServer) GetConnectionCount() int32 {
 	return atomic.LoadInt32(&s.connCount)
 }
 
+// GetConnection 获取指定连接
+func (s *NetQuicServer) GetConnection(id uint64) (conn.IConn, bool) {
+	value, ok := s.nets.Load(id)
+	if !ok {
+		return nil, false
+	}
+	return value.(conn.IConn), true
+}
+
+// SendTo 发送消息给指定连接
+func (s *NetQuicServer) SendTo(id uint64, data []byte) bool {
+	c, ok := s.GetConnection(id)
+	if !ok {
+		return false
+	}
+
+	c.Write(data)
+	return true
+}
+
+// BroadcastMessage 广播消息给所有连接
+func (s *NetQuicServer) BroadcastMessage(data []byte) {
+	s.nets.Range(func(key, value interface{}) bool {
+		value.(conn.IConn).Write(data)
+		return true
+	})
+}
+
+// BroadcastMessageExclude 广播消息给除指定连接外的所有连接
+func (s *NetQuicServer) BroadcastMessageExclude(data []byte, excludeConn conn.IConn) {
+	s.nets.Range(func(key, value interface{}) bool {
+		if c := value.(conn.IConn); c != excludeConn {
+			c.Write(data)
+		}
+		return true
+	})
+}
+
 // isNormalClose 判断是否为正常关闭
 func isNormalClose(err error) bool {
 	if err == nil {
@@ -9,17 +9,28 @@ import (
 
 // ServerConfig QUIC服务器配置
 type ServerConfig struct {
-	Name            string                         // 服务器名称
-	Ip              string                         // 监听IP
-	Port            int                            // 监听端口
-	TLSConfig       *tls.Config                    // TLS配置
-	OnConnect       func(conn.IConn)               // 连接建立回调
-	OnData          func(conn.IConn, []byte) error // 数据处理回调
-	OnClose         func(conn.IConn) error         // 连接关闭回调
-	MaxConnections  int                            // 最大连接数
-	IdleTimeout     time.Duration                  // 空闲超时
-	MaxStreamCount  int64                          // 最大流数量
-	KeepAlivePeriod time.Duration                  // 保活周期
+	Name            string                                 // 服务器名称
+	Ip              string                                 // 监听IP
+	Port            int                                    // 监听端口
+	TLSConfig       *tls.Config                            // TLS配置
+	OnConnect       func(conn.IConn)                       // 连接建立回调
+	OnData          func(conn.IConn, []byte) error         // 数据处理回调
+	OnChannelData   func(conn.IConn, string, []byte) error // 通道数据处理回调，未设置时通道数据交给 OnData
+	OnClose         func(conn.IConn) error                 // 连接关闭回调
+	MaxConnections  int                                    // 最大连接数
+	IdleTimeout     time.Duration                          // 空闲超时
+	MaxStreamCount  int64                                  // 每个连接允许对端同时打开的最大流数量(含主流)，默认100
+	KeepAlivePeriod time.Duration                          // 保活周期
+	Framer          conn.Framer                            // 流分帧器，优先于 FramerConfig
+	FramerConfig    conn.FramerConfig                      `yaml:"framer"` // 流分帧配置，未设置 Framer 时按配置创建，默认4字节大端序长度前缀(最大1MB)
+	EnableDatagrams bool                                   // 是否启用QUIC数据报(RFC 9221)
+	OnDatagram      func(conn.IConn, []byte) error         // 数据报处理回调(不可靠、无序)
+	ChannelPriority map[string]int                         `yaml:"channelPriority"` // 通道写入优先级，数值越大越优先，未配置的通道为0
+}
+
+// GetChannelPriority 获取通道的写入优先级, 未配置时为0
+func (c *ServerConfig) GetChannelPriority(channel string) int {
+	return c.ChannelPriority[channel]
 }
 
 // GetMaxConnections 获取最大连接数
@@ -31,3 +42,17 @@ func (c *ServerConfig) GetMaxConnections() int {
 func (c *ServerConfig) GetIdleTimeout() time.Duration {
 	return c.IdleTimeout
 }
+
+// initFramer 确定流分帧器, 见 conn.FramerConfig.Resolve
+func (c *ServerConfig) initFramer() (err error) {
+	c.Framer, err = c.FramerConfig.Resolve(c.Framer)
+	return err
+}
+
+// GetFramer 获取流分帧器, 未初始化时为默认分帧器
+func (c *ServerConfig) GetFramer() conn.Framer {
+	if c.Framer == nil {
+		return conn.DefaultFramer()
+	}
+	return c.Framer
+}
@@ -0,0 +1,89 @@
+package quic
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"sync"
+
+	"github.com/quic-go/quic-go"
+)
+
+// streamConn 服务端QUIC连接, 以客户端打开的第一个流作为主流收发数据
+type streamConn struct {
+	conn   *quic.Conn    // QUIC连接
+	stream *quic.Stream  // 主流
+	reader *bufio.Reader // 主流读缓冲
+	ready  chan struct{} // 主流就绪信号
+	once   sync.Once     // 保证主流只设置一次
+
+	channels map[string]*channelStream // 对端打开的通道流
+	chMu     sync.RWMutex              // 保护 channels
+
+	scheduler writeScheduler // 通道写入的优先级调度
+}
+
+// newStreamConn 创建服务端QUIC连接
+func newStreamConn(qconn *quic.Conn) *streamConn {
+	return &streamConn{
+		conn:     qconn,
+		ready:    make(chan struct{}),
+		channels: make(map[string]*channelStream),
+	}
+}
+
+// setStream 设置主流, 返回是否设置成功(已有主流时返回 false)
+func (c *streamConn) setStream(stream *quic.Stream) bool {
+	ok := false
+	c.once.Do(func() {
+		c.stream = stream
+		c.reader = bufio.NewReader(stream)
+		close(c.ready)
+		ok = true
+	})
+	return ok
+}
+
+// waitStream 等待主流就绪(QUIC 流在对端首次写入后才会被服务端接受)
+func (c *streamConn) waitStream() error {
+	select {
+	case <-c.ready:
+		return nil
+	case <-c.conn.Context().Done():
+		return context.Cause(c.conn.Context())
+	}
+}
+
+// RemoteAddr 获取对端地址
+func (c *streamConn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// addChannel 登记通道流, 同名通道以最新打开的流为准
+func (c *streamConn) addChannel(name string, stream *quic.Stream) *channelStream {
+	cs := &channelStream{stream: stream}
+
+	c.chMu.Lock()
+	c.channels[name] = cs
+	c.chMu.Unlock()
+
+	return cs
+}
+
+// removeChannel 注销通道流(仅当仍是登记的那个流时)
+func (c *streamConn) removeChannel(name string, cs *channelStream) {
+	c.chMu.Lock()
+	if c.channels[name] == cs {
+		delete(c.channels, name)
+	}
+	c.chMu.Unlock()
+}
+
+// getChannel 获取通道流
+func (c *streamConn) getChannel(name string) (*channelStream, bool) {
+	c.chMu.RLock()
+	defer c.chMu.RUnlock()
+
+	cs, ok := c.channels[name]
+	return cs, ok
+}
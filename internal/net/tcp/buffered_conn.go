@@ -0,0 +1,30 @@
+package tcp
+
+import (
+	"bufio"
+	"net"
+)
+
+// bufferedConn 带读缓冲的连接, 分帧器按字节读取(varint/分隔符)时避免逐字节系统调用
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// newBufferedConn 创建带读缓冲的连接
+func newBufferedConn(c net.Conn) *bufferedConn {
+	return &bufferedConn{
+		Conn:   c,
+		reader: bufio.NewReader(c),
+	}
+}
+
+// Read 从缓冲区读取
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// ReadByte 读取一个字节
+func (c *bufferedConn) ReadByte() (byte, error) {
+	return c.reader.ReadByte()
+}
@@ -0,0 +1,203 @@
+package tcp
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/spelens-gud/assert"
+	"github.com/spelens-gud/logger"
+	"github.com/spelens-gud/trunk/internal/net/conn"
+)
+
+// NetTcpClient TCP客户端
+type NetTcpClient struct {
+	conn           *conn.Conn[net.Conn] // 连接
+	log            logger.ILogger       // 日志
+	cnf            *ClientConfig        // 配置
+	stopChan       chan chan struct{}   // 停止通道
+	pingTicker     *time.Ticker         // ping定时器
+	isStop         bool                 // 是否停止
+	isFirstPing    bool                 // 是否首次ping
+	reconnectCount int                  // 重连次数
+}
+
+// New 创建tcp客户端
+func (c *NetTcpClient) New() {
+	c.stopChan = make(chan chan struct{})
+	c.isStop = true
+}
+
+// Start 运行(阻塞直到 Close 或连接断开)
+func (c *NetTcpClient) Start() {
+	c.conn.Start()
+	if c.cnf.PingTicker > 0 {
+		c.pingTicker = time.NewTicker(c.cnf.PingTicker)
+	} else {
+		// 如果没有设置 PingTicker，创建一个永不触发的 ticker
+		c.pingTicker = time.NewTicker(time.Hour * 24 * 365)
+		c.pingTicker.Stop()
+	}
+	for {
+		select {
+		case <-c.pingTicker.C:
+			assert.MayTrue(c.cnf.FirstPingFunc != nil && !c.isFirstPing, func() {
+				c.cnf.FirstPingFunc(c)
+				c.isFirstPing = true
+			})
+			assert.MayTrue(c.cnf.PingFunc != nil, func() {
+				c.cnf.PingFunc(c)
+			})
+		case <-c.conn.GetContext().Done():
+			// 连接被对端或读写错误关闭
+			c.pingTicker.Stop()
+			c.isStop = true
+			c.log.Infof("连接已断开: %s", c.cnf.Host)
+			return
+		case ch := <-c.stopChan:
+			if ch == nil {
+				return
+			}
+
+			c.pingTicker.Stop()
+			assert.ShouldCall0E(c.conn.Close, "conn关闭失败")
+			ch <- struct{}{}
+			return
+		}
+	}
+}
+
+// StartWithReconnect 启动客户端并支持自动重连
+func (c *NetTcpClient) StartWithReconnect() {
+	for {
+		if err := c.Daily(); err != nil {
+			c.log.Errorf("连接失败: %v", err)
+
+			// 如果未启用自动重连，则停止运行
+			if !c.cnf.ReconnectEnabled {
+				return
+			}
+
+			c.reconnectCount++
+			if c.cnf.MaxReconnect > 0 && c.reconnectCount > c.cnf.MaxReconnect {
+				c.log.Errorf("达到最大重连次数(%d)，停止重连", c.cnf.MaxReconnect)
+				return
+			}
+
+			c.log.Infof("将在 %v 后进行第 %d 次重连...", c.cnf.ReconnectDelay, c.reconnectCount)
+			time.Sleep(c.cnf.ReconnectDelay)
+			continue
+		}
+
+		// 连接成功，重置重连计数
+		if c.reconnectCount > 0 {
+			c.log.Infof("重连成功")
+			assert.MayTrue(c.cnf.OnReconnect != nil, func() {
+				c.cnf.OnReconnect(c)
+			})
+		}
+
+		c.reconnectCount = 0
+
+		// 启动客户端
+		c.Start()
+
+		// 如果连接断开且启用了自动重连，继续循环
+		if !c.cnf.ReconnectEnabled {
+			break
+		}
+
+		// 断线处理
+		assert.MayTrue(c.cnf.OnDisconnect != nil, func() {
+			c.cnf.OnDisconnect(c)
+		})
+
+		c.log.Infof("连接断开，准备重连...")
+		time.Sleep(c.cnf.ReconnectDelay)
+	}
+}
+
+// Daily 建立连接(拨号), 分帧配置错误时返回错误
+func (c *NetTcpClient) Daily() error {
+	if err := c.cnf.initFramer(); err != nil {
+		return fmt.Errorf("分帧配置错误: %w", err)
+	}
+
+	con, err := net.DialTimeout("tcp", c.cnf.Host, c.cnf.GetDialTimeout())
+	if err != nil {
+		return err
+	}
+
+	// 未自定义读写函数时使用 Framer 分帧
+	cnf := c.cnf.NetConfig
+	if cnf.OnWrite == nil {
+		cnf.OnWrite = c.onWriteFunc
+	}
+	if cnf.OnRead == nil {
+		cnf.OnRead = c.onReadFunc
+	}
+	if cnf.OnClose == nil {
+		cnf.OnClose = c.onCloseFunc
+	}
+
+	c.conn = conn.NewConn[net.Conn](newBufferedConn(con), cnf)
+	c.conn.SetLogger(c.log) // 设置 logger
+	c.isStop = false
+	c.isFirstPing = false
+	c.log.Infof("连接建立成功: %s", c.cnf.Host)
+	return nil
+}
+
+// Close 关闭客户端连接
+func (c *NetTcpClient) Close() error {
+	if c.isStop {
+		return nil
+	}
+	defer func() {
+		c.isStop = true
+	}()
+
+	done := make(chan struct{})
+	c.stopChan <- done
+	<-done
+	c.log.Infof("客户端连接已关闭")
+	return nil
+}
+
+// IsConnected 检查是否已连接
+func (c *NetTcpClient) IsConnected() bool {
+	return !c.isStop && c.conn != nil
+}
+
+// GetReconnectCount 获取重连次数
+func (c *NetTcpClient) GetReconnectCount() int {
+	return c.reconnectCount
+}
+
+// GetConn 获取连接
+func (c *NetTcpClient) GetConn() conn.IConn {
+	return c.conn
+}
+
+// SendMsg 发送消息
+func (c *NetTcpClient) SendMsg(bs []byte) {
+	c.conn.Write(bs)
+}
+
+// onCloseFunc 关闭连接处理函数
+func (c *NetTcpClient) onCloseFunc(cn net.Conn) error {
+	return cn.Close()
+}
+
+// onWriteFunc 写数据处理函数
+func (c *NetTcpClient) onWriteFunc(cn net.Conn, data []byte) error {
+	assert.ShouldCall1E(cn.SetWriteDeadline, time.Now().Add(c.cnf.GetWriteTimeout()), "SetWriteDeadline err:")
+	return c.cnf.GetFramer().WriteFrame(cn, data)
+}
+
+// onReadFunc 读取数据处理函数
+func (c *NetTcpClient) onReadFunc(cn net.Conn) (int, []byte, error) {
+	assert.ShouldCall1E(cn.SetReadDeadline, time.Now().Add(c.cnf.GetReadTimeout()), "SetReadDeadline err:")
+	data, err := c.cnf.GetFramer().ReadFrame(cn)
+	return len(data), data, err
+}
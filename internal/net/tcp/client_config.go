@@ -0,0 +1,46 @@
+package tcp
+
+import (
+	"net"
+	"time"
+
+	"github.com/spelens-gud/trunk/internal/net/conn"
+)
+
+// ClientConfig 客户端配置
+type ClientConfig struct {
+	conn.NetConfig[net.Conn]                     // 基础配置(OnWrite/OnRead/OnClose 未设置时使用 Framer 分帧)
+	DialTimeout              time.Duration       // 拨号超时，默认10s
+	Framer                   conn.Framer         // 分帧器，优先于 FramerConfig
+	FramerConfig             conn.FramerConfig   `yaml:"framer"` // 分帧配置，未设置 Framer 时按配置创建，默认4字节大端序长度前缀(最大1MB)
+	PingTicker               time.Duration       // 心跳间隔
+	PingFunc                 func(*NetTcpClient) // 心跳函数
+	FirstPingFunc            func(*NetTcpClient) // 首次心跳函数
+	ReconnectEnabled         bool                // 是否启用自动重连
+	ReconnectDelay           time.Duration       // 重连延迟
+	MaxReconnect             int                 // 最大重连次数，0表示无限重连
+	OnReconnect              func(*NetTcpClient) // 重连成功回调
+	OnDisconnect             func(*NetTcpClient) // 断开连接回调
+}
+
+// GetDialTimeout 获取拨号超时
+func (c *ClientConfig) GetDialTimeout() time.Duration {
+	if c.DialTimeout <= 0 {
+		return 10 * time.Second
+	}
+	return c.DialTimeout
+}
+
+// initFramer 确定分帧器, 见 conn.FramerConfig.Resolve
+func (c *ClientConfig) initFramer() (err error) {
+	c.Framer, err = c.FramerConfig.Resolve(c.Framer)
+	return err
+}
+
+// GetFramer 获取分帧器, 未初始化时为默认分帧器
+func (c *ClientConfig) GetFramer() conn.Framer {
+	if c.Framer == nil {
+		return conn.DefaultFramer()
+	}
+	return c.Framer
+}
@@ -0,0 +1,98 @@
+package tcp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spelens-gud/logger"
+	"github.com/spelens-gud/trunk/internal/net/conn"
+)
+
+// 测试辅助函数：创建测试客户端配置
+func createTestClientConfig(host string) *ClientConfig {
+	cfg := &ClientConfig{
+		PingTicker:       5 * time.Second,
+		ReconnectEnabled: false,
+		ReconnectDelay:   time.Second,
+	}
+	cfg.Name = "test-client"
+	cfg.Host = host
+	cfg.OnData = func(c conn.IConn, data []byte) error {
+		return nil
+	}
+	return cfg
+}
+
+// TestTcpNetClient_New 测试客户端创建
+func TestTcpNetClient_New(t *testing.T) {
+	log, _ := logger.NewLogger(&logger.Config{
+		Level:   "info",
+		Console: true,
+	})
+
+	client := &NetTcpClient{
+		log: log,
+		cnf: createTestClientConfig("127.0.0.1:19110"),
+	}
+
+	client.New()
+
+	if client.stopChan == nil {
+		t.Error("stopChan 未初始化")
+	}
+	if !client.isStop {
+		t.Error("isStop 应该为 true")
+	}
+	if client.IsConnected() {
+		t.Error("期望未连接，但返回已连接")
+	}
+	if err := client.Close(); err != nil {
+		t.Errorf("关闭已停止的客户端不应返回错误: %v", err)
+	}
+}
+
+// TestTcpNetClient_DailyFailed 测试连接失败
+func TestTcpNetClient_DailyFailed(t *testing.T) {
+	log, _ := logger.NewLogger(&logger.Config{
+		Level:   "info",
+		Console: true,
+	})
+
+	cfg := createTestClientConfig("127.0.0.1:1")
+	cfg.DialTimeout = 500 * time.Millisecond
+
+	client := &NetTcpClient{
+		log: log,
+		cnf: cfg,
+	}
+	client.New()
+
+	if err := client.Daily(); err == nil {
+		t.Error("连接不存在的服务应返回错误")
+	}
+	if client.IsConnected() {
+		t.Error("连接失败后不应处于已连接状态")
+	}
+}
+
+// TestTcpNetClient_DailyInvalidFramer 测试分帧配置错误时拨号返回错误
+func TestTcpNetClient_DailyInvalidFramer(t *testing.T) {
+	log, _ := logger.NewLogger(&logger.Config{
+		Level:   "info",
+		Console: true,
+	})
+
+	cfg := createTestClientConfig("127.0.0.1:1")
+	cfg.FramerConfig = conn.FramerConfig{Type: "unknown"}
+
+	client := &NetTcpClient{
+		log: log,
+		cnf: cfg,
+	}
+	client.New()
+
+	if err := client.Daily(); err == nil || !strings.Contains(err.Error(), "分帧配置错误") {
+		t.Errorf("期望分帧配置错误, 实际 %v", err)
+	}
+}
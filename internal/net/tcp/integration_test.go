@@ -0,0 +1,245 @@
+package tcp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spelens-gud/logger"
+	"github.com/spelens-gud/trunk/internal/net/conn"
+	"github.com/spelens-gud/trunk/internal/net/message"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// newIntegrationClient 创建集成测试客户端
+func newIntegrationClient(t *testing.T, log logger.ILogger, port int, received chan []byte) *NetTcpClient {
+	t.Helper()
+
+	cfg := &ClientConfig{}
+	cfg.Name = "integration-client"
+	cfg.Host = fmt.Sprintf("127.0.0.1:%d", port)
+	cfg.OnData = func(c conn.IConn, data []byte) error {
+		received <- data
+		return nil
+	}
+
+	client := &NetTcpClient{
+		cnf: cfg,
+		log: log,
+	}
+	client.New()
+
+	if err := client.Daily(); err != nil {
+		t.Fatalf("客户端连接失败: %v", err)
+	}
+	go client.Start()
+
+	return client
+}
+
+// TestIntegration_ServerClientCommunication 集成测试：服务器与客户端通信
+func TestIntegration_ServerClientCommunication(t *testing.T) {
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	port := 19120
+	log, _ := logger.NewLogger(&logger.Config{
+		Level:   "info",
+		Console: true,
+	})
+
+	server := &NetTcpServer{
+		cnf: createTestServerConfig(port),
+		log: log,
+	}
+	server.New()
+	go server.RunNet()
+
+	time.Sleep(100 * time.Millisecond)
+
+	received := make(chan []byte, 4)
+	client := newIntegrationClient(t, log, port, received)
+
+	time.Sleep(100 * time.Millisecond)
+
+	// 连续发送多条消息，验证分帧边界
+	messages := []string{"Hello, TCP!", "second", "third"}
+	for _, m := range messages {
+		client.SendMsg([]byte(m))
+	}
+
+	for _, m := range messages {
+		select {
+		case data := <-received:
+			if string(data) != m {
+				t.Errorf("期望收到 '%s', 实际收到 '%s'", m, string(data))
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("超时：未收到服务器响应")
+		}
+	}
+
+	if server.GetConnectionCount() != 1 {
+		t.Errorf("期望连接数 = 1, 实际 = %d", server.GetConnectionCount())
+	}
+
+	_ = client.Close()
+	server.Stop()
+}
+
+// TestIntegration_ConnectionLimit 集成测试：连接数限制
+func TestIntegration_ConnectionLimit(t *testing.T) {
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	port := 19121
+	log, _ := logger.NewLogger(&logger.Config{
+		Level:   "info",
+		Console: true,
+	})
+
+	cfg := createTestServerConfig(port)
+	cfg.MaxConnections = 1
+
+	server := &NetTcpServer{
+		cnf: cfg,
+		log: log,
+	}
+	server.New()
+	go server.RunNet()
+
+	time.Sleep(100 * time.Millisecond)
+
+	first := newIntegrationClient(t, log, port, make(chan []byte, 1))
+	time.Sleep(100 * time.Millisecond)
+
+	second := newIntegrationClient(t, log, port, make(chan []byte, 1))
+	time.Sleep(200 * time.Millisecond)
+
+	stats := server.GetStats()
+	if stats.CurrentConnections != 1 {
+		t.Errorf("期望当前连接数 = 1, 实际 = %d", stats.CurrentConnections)
+	}
+	if stats.TotalRejected != 1 {
+		t.Errorf("期望累计拒绝 = 1, 实际 = %d", stats.TotalRejected)
+	}
+
+	_ = first.Close()
+	_ = second.Close()
+	server.Stop()
+}
+
+// TestIntegration_BroadcastMessage 集成测试：广播消息
+func TestIntegration_BroadcastMessage(t *testing.T) {
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	port := 19122
+	log, _ := logger.NewLogger(&logger.Config{
+		Level:   "info",
+		Console: true,
+	})
+
+	server := &NetTcpServer{
+		cnf: createTestServerConfig(port),
+		log: log,
+	}
+	server.New()
+	go server.RunNet()
+
+	time.Sleep(100 * time.Millisecond)
+
+	const clientCount = 3
+	received := make(chan []byte, clientCount)
+	clients := make([]*NetTcpClient, 0, clientCount)
+	for i := 0; i < clientCount; i++ {
+		clients = append(clients, newIntegrationClient(t, log, port, received))
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	server.BroadcastMessage([]byte("broadcast"))
+
+	var wg sync.WaitGroup
+	wg.Add(clientCount)
+	go func() {
+		for i := 0; i < clientCount; i++ {
+			select {
+			case data := <-received:
+				if string(data) != "broadcast" {
+					t.Errorf("期望收到 'broadcast', 实际收到 '%s'", string(data))
+				}
+			case <-time.After(2 * time.Second):
+				t.Error("超时：未收到广播消息")
+			}
+			wg.Done()
+		}
+	}()
+	wg.Wait()
+
+	for _, c := range clients {
+		_ = c.Close()
+	}
+	server.Stop()
+}
+
+// TestIntegration_RPC 集成测试：基于序列号的请求/响应
+func TestIntegration_RPC(t *testing.T) {
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	port := 19123
+	log, _ := logger.NewLogger(&logger.Config{
+		Level:   "info",
+		Console: true,
+	})
+
+	router := message.NewRouter(log)
+	message.Handle(router, 1, 100, func(c *message.Context, req *wrapperspb.StringValue) (proto.Message, error) {
+		return wrapperspb.String("re:" + req.GetValue()), nil
+	})
+
+	cfg := createTestServerConfig(port)
+	cfg.OnData = router.OnData
+	server := &NetTcpServer{
+		cnf: cfg,
+		log: log,
+	}
+	server.New()
+	go server.RunNet()
+	defer server.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	received := make(chan []byte, 4)
+	client := newIntegrationClient(t, log, port, received)
+	defer client.Close()
+
+	rpc := message.NewRPCClient(func(data []byte) error {
+		client.SendMsg(data)
+		return nil
+	}, 1, 0)
+	go func() {
+		for data := range received {
+			_ = rpc.OnData(data)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	resp, err := message.CallTyped[*wrapperspb.StringValue](ctx, rpc, 100, wrapperspb.String("tcp"))
+	if err != nil {
+		t.Fatalf("调用失败: %v", err)
+	}
+	if resp.GetValue() != "re:tcp" {
+		t.Errorf("期望 're:tcp', 实际 '%s'", resp.GetValue())
+	}
+}
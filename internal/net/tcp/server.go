@@ -0,0 +1,269 @@
+package tcp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/spelens-gud/assert"
+	"github.com/spelens-gud/logger"
+	"github.com/spelens-gud/trunk/internal/net/conn"
+)
+
+// NetTcpServer TCP服务端
+type NetTcpServer struct {
+	cnf           *ServerConfig                 // tcp服务端配置
+	log           logger.ILogger                // 日志
+	stopChan      chan chan struct{}            // 停止信号
+	listener      net.Listener                  // 监听
+	lock          sync.RWMutex                  // 锁
+	nets          map[*conn.Conn[net.Conn]]bool // 所有连接, key: 连接, value: true
+	connCount     int                           // 当前连接数
+	totalAccepted uint64                        // 累计接受的连接数
+	totalRejected uint64                        // 累计拒绝的连接数
+}
+
+// ServerStats 服务器统计信息
+type ServerStats struct {
+	CurrentConnections int    // 当前连接数
+	TotalAccepted      uint64 // 累计接受的连接数
+	TotalRejected      uint64 // 累计拒绝的连接数
+}
+
+// New 创建tcp服务端, 分帧配置错误或监听失败时返回错误
+func (s *NetTcpServer) New() error {
+	s.stopChan = make(chan chan struct{})
+	s.nets = make(map[*conn.Conn[net.Conn]]bool)
+	if err := s.cnf.initFramer(); err != nil {
+		return fmt.Errorf("分帧配置错误: %w", err)
+	}
+
+	addr := fmt.Sprintf("%s:%d", s.cnf.Ip, s.cnf.Port)
+	s.log.Infof("监听地址:%s", addr)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("tcp监听失败: %w", err)
+	}
+	s.listener = listener
+	return nil
+}
+
+// RunNet 启动tcp服务端(阻塞直到 Stop 或监听异常)
+func (s *NetTcpServer) RunNet() {
+	errChan := make(chan error, 1)
+	go logger.WithRecover(s.log, func() {
+		errChan <- s.acceptLoop()
+	})
+
+	s.log.Infof("TCP服务启动成功")
+
+	select {
+	case stopFinished := <-s.stopChan:
+		s.log.Infof("开始关闭服务器...")
+		assert.ShouldCall0E(s.listener.Close, "tcp监听关闭失败")
+		s.closeAllConnections()
+		stopFinished <- struct{}{}
+	case err := <-errChan:
+		s.log.Errorf("TCP服务异常: %s", err)
+	}
+}
+
+// acceptLoop 接受连接循环
+func (s *NetTcpServer) acceptLoop() error {
+	for {
+		c, err := s.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				s.log.Infof("服务器已关闭，停止接受新连接")
+				return nil
+			}
+			return err
+		}
+
+		// 检查连接数限制
+		if s.checkConnectionsLimit() {
+			s.log.Warnf("连接数已达上限(%d)，拒绝新连接 来源:%s", s.cnf.GetMaxConnections(), c.RemoteAddr())
+			assert.ShouldCall0E(c.Close, "关闭连接失败")
+			continue
+		}
+
+		go logger.WithRecover(s.log, func() {
+			s.handleConn(c)
+		})
+	}
+}
+
+// handleConn 处理单个连接
+func (s *NetTcpServer) handleConn(c net.Conn) {
+	if tc, ok := c.(*net.TCPConn); ok && s.cnf.NoDelay {
+		assert.ShouldCall1E(tc.SetNoDelay, true, "SetNoDelay err:")
+	}
+
+	// 创建连接(读取经过缓冲, 供分帧器按字节读取)
+	cn := conn.NewConn[net.Conn](newBufferedConn(c), conn.NetConfig[net.Conn]{
+		Name:         s.cnf.Name,
+		Host:         c.RemoteAddr().String(),
+		OnWrite:      s.onWriteFunc,
+		OnRead:       s.onReadFunc,
+		OnClose:      s.onCloseFunc,
+		OnData:       s.cnf.OnData,
+		WriteTimeout: s.cnf.GetWriteTimeout(),
+		ReadTimeout:  s.cnf.GetReadTimeout(),
+		IdleTimeOut:  s.cnf.IdleTimeOut,
+	})
+	cn.SetLogger(s.log) // 设置 logger
+
+	s.lock.Lock()
+	s.nets[cn] = true // 添加连接
+	s.connCount++
+	s.totalAccepted++
+	cn.SetId(s.totalAccepted)
+	currentCount := s.connCount
+	s.log.Infof("新连接建立:%s 当前连接数:%d 累计接受:%d", c.RemoteAddr(), currentCount, s.totalAccepted)
+	s.lock.Unlock()
+
+	// 启动连接回调函数
+	assert.MayTrue(s.cnf.OnConnect != nil, func() {
+		s.cnf.OnConnect(cn)
+	})
+
+	// 启动连接的读写循环
+	cn.Start()
+
+	// 等待连接关闭（通过监听 context）
+	<-cn.GetContext().Done()
+
+	s.lock.Lock()
+	// 只有当连接还在 map 中时才删除和减少计数
+	if _, exists := s.nets[cn]; exists {
+		delete(s.nets, cn)
+		s.connCount--
+		s.log.Infof("连接断开:%s 当前连接数:%d", c.RemoteAddr(), s.connCount)
+	}
+	s.lock.Unlock()
+
+	// 启动关闭回调函数
+	assert.MayTrue(s.cnf.OnClose != nil, func() {
+		assert.ShouldCall1E[conn.IConn](s.cnf.OnClose, cn, "关闭连接失败")
+	})
+}
+
+// Stop 停止服务器
+func (s *NetTcpServer) Stop() {
+	stopDone := make(chan struct{}, 1)
+	s.stopChan <- stopDone
+	<-stopDone
+	s.log.Infof("服务器已停止")
+}
+
+// GetConnectionCount 获取当前连接数
+func (s *NetTcpServer) GetConnectionCount() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.connCount
+}
+
+// GetStats 获取服务器统计信息
+func (s *NetTcpServer) GetStats() ServerStats {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return ServerStats{
+		CurrentConnections: s.connCount,
+		TotalAccepted:      s.totalAccepted,
+		TotalRejected:      s.totalRejected,
+	}
+}
+
+// BroadcastMessage 广播消息给所有连接
+func (s *NetTcpServer) BroadcastMessage(data []byte) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	for cn := range s.nets {
+		cn.Write(data)
+	}
+}
+
+// BroadcastMessageExclude 广播消息给除指定连接外的所有连接
+func (s *NetTcpServer) BroadcastMessageExclude(data []byte, excludeConn conn.IConn) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	for cn := range s.nets {
+		if cn == excludeConn {
+			continue
+		}
+
+		cn.Write(data)
+	}
+}
+
+// SendTo 发送消息给指定连接
+func (s *NetTcpServer) SendTo(id uint64, data []byte) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	for cn := range s.nets {
+		if cn.GetId() == id {
+			cn.Write(data)
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkConnectionsLimit 检查连接数限制
+func (s *NetTcpServer) checkConnectionsLimit() bool {
+	if s.cnf.GetMaxConnections() <= 0 {
+		return false
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.connCount >= s.cnf.GetMaxConnections() {
+		s.totalRejected++
+		return true
+	}
+
+	return false
+}
+
+// onCloseFunc 关闭连接处理函数
+func (s *NetTcpServer) onCloseFunc(cn net.Conn) error {
+	return cn.Close()
+}
+
+// onWriteFunc 写数据处理函数
+func (s *NetTcpServer) onWriteFunc(cn net.Conn, data []byte) error {
+	assert.ShouldCall1E(cn.SetWriteDeadline, time.Now().Add(s.cnf.GetWriteTimeout()), "SetWriteDeadline err:")
+	return s.cnf.GetFramer().WriteFrame(cn, data)
+}
+
+// onReadFunc 读取数据处理函数
+func (s *NetTcpServer) onReadFunc(cn net.Conn) (int, []byte, error) {
+	assert.ShouldCall1E(cn.SetReadDeadline, time.Now().Add(s.cnf.GetReadTimeout()), "SetReadDeadline err:")
+	data, err := s.cnf.GetFramer().ReadFrame(cn)
+	return len(data), data, err
+}
+
+// closeAllConnections 关闭所有连接
+func (s *NetTcpServer) closeAllConnections() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.log.Infof("开始关闭所有连接，当前连接数:%d", len(s.nets))
+
+	for cn := range s.nets {
+		assert.ShouldCall0E(cn.Close, "关闭连接失败")
+	}
+
+	// 重置连接数
+	s.nets = make(map[*conn.Conn[net.Conn]]bool)
+	s.connCount = 0
+	s.log.Infof("所有连接已关闭")
+}
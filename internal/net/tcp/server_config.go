@@ -0,0 +1,59 @@
+package tcp
+
+import (
+	"time"
+
+	"github.com/spelens-gud/trunk/internal/net/conn"
+)
+
+// ServerConfig TCP服务端配置
+type ServerConfig struct {
+	Name           string                         // 服务名称
+	Ip             string                         // 监听ip
+	Port           int                            // 监听端口
+	OnConnect      func(conn.IConn)               // 连接建立时调用
+	OnData         func(conn.IConn, []byte) error // 数据处理
+	OnClose        func(conn.IConn) error         // 连接关闭时调用
+	WriteTimeout   time.Duration                  // 写超时
+	ReadTimeout    time.Duration                  // 读超时
+	IdleTimeOut    time.Duration                  // 空闲超时，0表示不检测
+	MaxConnections int                            // 最大连接数限制，0表示不限制
+	Framer         conn.Framer                    // 分帧器，优先于 FramerConfig
+	FramerConfig   conn.FramerConfig              `yaml:"framer"` // 分帧配置，未设置 Framer 时按配置创建，默认4字节大端序长度前缀(最大1MB)
+	NoDelay        bool                           // 是否禁用Nagle算法
+}
+
+// GetMaxConnections 获取最大连接数限制
+func (s *ServerConfig) GetMaxConnections() int {
+	return s.MaxConnections
+}
+
+// GetWriteTimeout 获取写超时
+func (s *ServerConfig) GetWriteTimeout() time.Duration {
+	if s.WriteTimeout <= 0 {
+		return 10 * time.Second
+	}
+	return s.WriteTimeout
+}
+
+// GetReadTimeout 获取读超时
+func (s *ServerConfig) GetReadTimeout() time.Duration {
+	if s.ReadTimeout <= 0 {
+		return 60 * time.Second
+	}
+	return s.ReadTimeout
+}
+
+// initFramer 确定分帧器, 见 conn.FramerConfig.Resolve
+func (s *ServerConfig) initFramer() (err error) {
+	s.Framer, err = s.FramerConfig.Resolve(s.Framer)
+	return err
+}
+
+// GetFramer 获取分帧器, 未初始化时为默认分帧器
+func (s *ServerConfig) GetFramer() conn.Framer {
+	if s.Framer == nil {
+		return conn.DefaultFramer()
+	}
+	return s.Framer
+}
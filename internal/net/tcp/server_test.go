@@ -0,0 +1,167 @@
+package tcp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spelens-gud/logger"
+	"github.com/spelens-gud/trunk/internal/net/conn"
+)
+
+// 测试辅助函数：创建测试服务器配置
+func createTestServerConfig(port int) *ServerConfig {
+	return &ServerConfig{
+		Name: "test-server",
+		Ip:   "127.0.0.1",
+		Port: port,
+		OnConnect: func(c conn.IConn) {
+			// 连接建立回调
+		},
+		OnData: func(c conn.IConn, data []byte) error {
+			c.Write(data) // 回显数据
+			return nil
+		},
+		OnClose: func(c conn.IConn) error {
+			return nil
+		},
+		MaxConnections: 100,
+	}
+}
+
+// TestServerConfig_GetMethods 测试配置获取方法
+func TestServerConfig_GetMethods(t *testing.T) {
+	cfg := &ServerConfig{}
+	if cfg.GetWriteTimeout() != 10*time.Second {
+		t.Errorf("期望 WriteTimeout = 10s, 实际 = %v", cfg.GetWriteTimeout())
+	}
+	if cfg.GetReadTimeout() != 60*time.Second {
+		t.Errorf("期望 ReadTimeout = 60s, 实际 = %v", cfg.GetReadTimeout())
+	}
+	if cfg.GetFramer() == nil {
+		t.Error("默认分帧器不应为空")
+	}
+
+	cfg = &ServerConfig{
+		WriteTimeout:   20 * time.Second,
+		ReadTimeout:    90 * time.Second,
+		MaxConnections: 50,
+		Framer:         conn.NewVarintFramer(1024),
+	}
+	if cfg.GetWriteTimeout() != 20*time.Second {
+		t.Errorf("期望 WriteTimeout = 20s, 实际 = %v", cfg.GetWriteTimeout())
+	}
+	if cfg.GetReadTimeout() != 90*time.Second {
+		t.Errorf("期望 ReadTimeout = 90s, 实际 = %v", cfg.GetReadTimeout())
+	}
+	if cfg.GetMaxConnections() != 50 {
+		t.Errorf("期望 MaxConnections = 50, 实际 = %d", cfg.GetMaxConnections())
+	}
+	if _, ok := cfg.GetFramer().(*conn.VarintFramer); !ok {
+		t.Errorf("期望使用自定义分帧器, 实际 = %T", cfg.GetFramer())
+	}
+}
+
+// TestServerConfig_FramerConfig 测试按分帧配置创建分帧器, Framer 优先
+func TestServerConfig_FramerConfig(t *testing.T) {
+	cfg := &ServerConfig{FramerConfig: conn.FramerConfig{Type: conn.FramerVarint}}
+	if err := cfg.initFramer(); err != nil {
+		t.Fatalf("创建分帧器失败: %v", err)
+	}
+	if _, ok := cfg.GetFramer().(*conn.VarintFramer); !ok {
+		t.Errorf("期望按配置创建 varint 分帧器, 实际 = %T", cfg.GetFramer())
+	}
+
+	cfg = &ServerConfig{Framer: conn.NewVarintFramer(1024), FramerConfig: conn.FramerConfig{Type: conn.FramerDelimiter}}
+	if err := cfg.initFramer(); err != nil {
+		t.Fatalf("创建分帧器失败: %v", err)
+	}
+	if _, ok := cfg.GetFramer().(*conn.VarintFramer); !ok {
+		t.Errorf("期望优先使用 Framer, 实际 = %T", cfg.GetFramer())
+	}
+
+	cfg = &ServerConfig{FramerConfig: conn.FramerConfig{Type: "unknown"}}
+	if err := cfg.initFramer(); err == nil {
+		t.Error("不支持的分帧方式应返回错误")
+	}
+}
+
+// TestTcpNetServer_New 测试服务器创建
+func TestTcpNetServer_New(t *testing.T) {
+	log, _ := logger.NewLogger(&logger.Config{
+		Level:   "info",
+		Console: true,
+	})
+
+	server := &NetTcpServer{
+		cnf: createTestServerConfig(19101),
+		log: log,
+	}
+
+	if err := server.New(); err != nil {
+		t.Fatalf("创建服务器失败: %v", err)
+	}
+	defer server.listener.Close()
+
+	if server.stopChan == nil {
+		t.Error("stopChan 未初始化")
+	}
+	if server.nets == nil {
+		t.Error("nets 未初始化")
+	}
+	if server.listener == nil {
+		t.Error("listener 未初始化")
+	}
+}
+
+// TestTcpNetServer_NewInvalidFramer 测试分帧配置错误时创建服务器返回错误, 不监听
+func TestTcpNetServer_NewInvalidFramer(t *testing.T) {
+	log, _ := logger.NewLogger(&logger.Config{
+		Level:   "info",
+		Console: true,
+	})
+
+	cfg := createTestServerConfig(19103)
+	cfg.FramerConfig = conn.FramerConfig{Type: "unknown"}
+	server := &NetTcpServer{
+		cnf: cfg,
+		log: log,
+	}
+
+	if err := server.New(); err == nil {
+		t.Error("不支持的分帧方式应返回错误")
+	}
+	if server.listener != nil {
+		server.listener.Close()
+		t.Error("分帧配置错误时不应监听")
+	}
+}
+
+// TestTcpNetServer_Stats 测试统计信息
+func TestTcpNetServer_Stats(t *testing.T) {
+	log, _ := logger.NewLogger(&logger.Config{
+		Level:   "info",
+		Console: true,
+	})
+
+	server := &NetTcpServer{
+		cnf:           createTestServerConfig(19102),
+		log:           log,
+		connCount:     3,
+		totalAccepted: 10,
+		totalRejected: 2,
+	}
+
+	stats := server.GetStats()
+	if stats.CurrentConnections != 3 {
+		t.Errorf("期望当前连接数 = 3, 实际 = %d", stats.CurrentConnections)
+	}
+	if stats.TotalAccepted != 10 {
+		t.Errorf("期望累计接受 = 10, 实际 = %d", stats.TotalAccepted)
+	}
+	if stats.TotalRejected != 2 {
+		t.Errorf("期望累计拒绝 = 2, 实际 = %d", stats.TotalRejected)
+	}
+	if server.GetConnectionCount() != 3 {
+		t.Errorf("期望连接数 = 3, 实际 = %d", server.GetConnectionCount())
+	}
+}
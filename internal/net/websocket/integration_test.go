@@ -1,6 +1,7 @@
 package webSocket
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"testing"
@@ -9,6 +10,9 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/spelens-gud/logger"
 	"github.com/spelens-gud/trunk/internal/net/conn"
+	"github.com/spelens-gud/trunk/internal/net/message"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 // TestIntegration_ServerClientCommunication 集成测试：服务器与客户端通信
@@ -426,3 +430,183 @@ func TestIntegration_BroadcastMessage(t *testing.T) {
 
 	server.Stop()
 }
+
+// TestIntegration_RPC 集成测试：基于序列号的请求/响应
+func TestIntegration_RPC(t *testing.T) {
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	port := 19004
+	log, _ := logger.NewLogger(&logger.Config{
+		Level:   "info",
+		Console: true,
+	})
+
+	router := message.NewRouter(log)
+	message.Handle(router, 1, 100, func(c *message.Context, req *wrapperspb.StringValue) (proto.Message, error) {
+		return wrapperspb.String("re:" + req.GetValue()), nil
+	})
+
+	server := &NetWsServer{
+		cnf: &ServerConfig{
+			Name:           "rpc-server",
+			Ip:             "127.0.0.1",
+			Port:           port,
+			Route:          "/ws",
+			OnConnect:      func(c conn.IConn) {},
+			OnData:         router.OnData,
+			OnClose:        func(c conn.IConn) error { return nil },
+			MaxConnections: 10,
+		},
+		log: log,
+	}
+	server.New()
+	go server.RunNet("")
+	defer server.Stop()
+
+	time.Sleep(500 * time.Millisecond)
+
+	var rpc *message.RPCClient
+	client := &NetWsClient{
+		cnf: &ClientConfig{
+			NetConfig: conn.NetConfig[*websocket.Conn]{
+				Name: "rpc-client",
+				Host: fmt.Sprintf("ws://127.0.0.1:%d/ws", port),
+				OnWrite: func(cn *websocket.Conn, data []byte) error {
+					return cn.WriteMessage(websocket.BinaryMessage, data)
+				},
+				OnRead: func(cn *websocket.Conn) (int, []byte, error) {
+					return cn.ReadMessage()
+				},
+				OnClose: func(cn *websocket.Conn) error {
+					return cn.Close()
+				},
+				OnData: func(_ conn.IConn, data []byte) error {
+					return rpc.OnData(data)
+				},
+			},
+			PingTicker: 10 * time.Second,
+		},
+		log: log,
+	}
+	rpc = message.NewRPCClient(func(data []byte) error {
+		client.SendMsg(data)
+		return nil
+	}, 1, 0)
+
+	client.New()
+	if err := client.Daily(); err != nil {
+		t.Fatalf("客户端连接失败: %v", err)
+	}
+	go client.Start()
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	resp, err := message.CallTyped[*wrapperspb.StringValue](ctx, rpc, 100, wrapperspb.String("ws"))
+	if err != nil {
+		t.Fatalf("调用失败: %v", err)
+	}
+	if resp.GetValue() != "re:ws" {
+		t.Errorf("期望 're:ws', 实际 '%s'", resp.GetValue())
+	}
+}
+
+// TestIntegration_Drain 集成测试：下线时拒绝新连接, 已建立的连接收到迁移通知并在关闭后结束等待
+func TestIntegration_Drain(t *testing.T) {
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	port := 19005
+	log, _ := logger.NewLogger(&logger.Config{
+		Level:   "info",
+		Console: true,
+	})
+
+	server := &NetWsServer{
+		cnf: &ServerConfig{
+			Name:      "drain-server",
+			Ip:        "127.0.0.1",
+			Port:      port,
+			Route:     "/ws",
+			OnConnect: func(c conn.IConn) {},
+			OnData:    func(c conn.IConn, data []byte) error { return nil },
+			OnClose:   func(c conn.IConn) error { return nil },
+		},
+		log: log,
+	}
+	server.New()
+	go server.RunNet("")
+	time.Sleep(500 * time.Millisecond)
+	defer server.Stop()
+
+	notices := make(chan string, 1)
+	newClient := func() *NetWsClient {
+		client := &NetWsClient{
+			cnf: &ClientConfig{
+				NetConfig: conn.NetConfig[*websocket.Conn]{
+					Name: "drain-client",
+					Host: fmt.Sprintf("ws://127.0.0.1:%d/ws", port),
+					OnWrite: func(cn *websocket.Conn, data []byte) error {
+						return cn.WriteMessage(websocket.BinaryMessage, data)
+					},
+					OnRead: func(cn *websocket.Conn) (int, []byte, error) {
+						return cn.ReadMessage()
+					},
+					OnClose: func(cn *websocket.Conn) error {
+						return cn.Close()
+					},
+					OnData: func(c conn.IConn, data []byte) error {
+						if reason, ok := message.IsMigrating(data); ok {
+							notices <- reason
+						}
+						return nil
+					},
+				},
+			},
+			log: log,
+		}
+		client.New()
+		return client
+	}
+
+	client := newClient()
+	if err := client.Daily(); err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	go client.Start()
+	time.Sleep(200 * time.Millisecond)
+
+	server.Drain()
+	if err := newClient().Daily(); err == nil {
+		t.Fatal("下线后应拒绝新连接")
+	}
+
+	notice, _ := message.EncodeMigrating("rolling restart")
+	server.BroadcastMessage(notice)
+	select {
+	case reason := <-notices:
+		if reason != "rolling restart" {
+			t.Errorf("期望 rolling restart, 实际 %s", reason)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("未收到迁移通知")
+	}
+
+	// 连接未关闭时等待超时
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := server.WaitIdle(ctx); err == nil {
+		t.Fatal("连接未关闭时应等待超时")
+	}
+
+	_ = client.Close()
+	ctx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := server.WaitIdle(ctx); err != nil {
+		t.Fatalf("连接关闭后应结束等待: %v", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/pprof"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -27,6 +28,7 @@ type NetWsServer struct {
 	connCount     int                                  // 当前连接数
 	totalAccepted uint64                               // 累计接受的连接数
 	totalRejected uint64                               // 累计拒绝的连接数
+	draining      atomic.Bool                          // 正在下线, 拒绝新连接
 }
 
 // New 创建ws服务端
@@ -78,6 +80,12 @@ func (s *NetWsServer) RunNet(route string) {
 			}
 		}()
 
+		// 下线中拒绝新连接, 客户端重连到其他实例
+		if s.draining.Load() {
+			http.Error(w, "服务器正在迁移", http.StatusServiceUnavailable)
+			return
+		}
+
 		// 检查连接数限制
 		if s.checkConnectionsLimit(w, r) {
 			s.log.Warnf("连接数已达上限(%d)，拒绝新连接 来源:%s", s.cnf.GetMaxConnections(), r.RemoteAddr)
@@ -163,6 +171,28 @@ func (s *NetWsServer) Stop() {
 	s.log.Infof("服务器已停止")
 }
 
+// Drain 停止接受新连接, 已建立的连接不受影响
+func (s *NetWsServer) Drain() {
+	if !s.draining.Swap(true) {
+		s.log.Infof("开始下线，停止接受新连接，当前连接数:%d", s.GetConnectionCount())
+	}
+}
+
+// WaitIdle 等待已建立的连接全部关闭, ctx 结束时返回 ctx 的错误
+func (s *NetWsServer) WaitIdle(ctx context.Context) error {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for s.GetConnectionCount() > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
 // GetConnectionCount 获取当前连接数
 func (s *NetWsServer) GetConnectionCount() int {
 	s.lock.RLock()
@@ -0,0 +1,336 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/spelens-gud/logger"
+	"github.com/spelens-gud/trunk/internal/config"
+)
+
+// cacheSnapshot 快照文件的内容
+type cacheSnapshot struct {
+	Instances map[string][]ServiceInstance `json:"instances"` // 服务名 -> 实例列表
+	Values    map[string]string            `json:"values"`    // 键 -> 值
+	UpdatedAt time.Time                    `json:"updatedAt"` // 最后一次从注册中心更新的时间
+}
+
+// CachedRegistry 注册中心缓存装饰器, 保存最近一次获取的实例列表和键值并写入本地快照文件
+//
+// 注册中心不健康(IsHealthy 返回 false)或查询失败时返回缓存的数据并标记为过期,
+// 注册中心恢复后重新查询缓存过的服务和键; 启动时注册中心不可用也能从快照文件中读取上次的数据。
+// 未覆盖的方法直接转发给被装饰的注册中心
+type CachedRegistry struct {
+	Registry
+	cnf      *CacheConfig
+	log      logger.ILogger
+	snapshot cacheSnapshot
+	healthy  bool // 最近一次检查注册中心是否健康
+	dirty    bool // 缓存有变化, 尚未写入快照文件
+	lock     sync.RWMutex
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{} // 检查协程退出
+}
+
+// 确保 CachedRegistry 实现了 Registry 接口
+var _ Registry = (*CachedRegistry)(nil)
+
+// NewCachedRegistry 创建缓存装饰器, 读取快照文件并启动健康检查, reg 需已初始化
+func NewCachedRegistry(reg Registry, config *CacheConfig, log logger.ILogger) (*CachedRegistry, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("注册中心缓存配置错误: %w", err)
+	}
+
+	c := &CachedRegistry{
+		Registry: reg,
+		cnf:      config,
+		log:      log,
+		snapshot: cacheSnapshot{
+			Instances: make(map[string][]ServiceInstance),
+			Values:    make(map[string]string),
+		},
+		done: make(chan struct{}),
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+
+	if err := c.load(); err != nil {
+		c.log.Warnf("读取注册中心快照失败，路径: %s, 错误: %v", config.GetPath(), err)
+	}
+	c.healthy = reg.IsHealthy()
+	if !c.healthy {
+		c.log.Warnf("注册中心不可用，使用本地快照，更新时间: %s", c.snapshot.UpdatedAt.Format(time.DateTime))
+	}
+
+	go logger.WithRecover(c.log, c.run)
+
+	return c, nil
+}
+
+// load 读取快照文件, 文件不存在时忽略
+func (c *CachedRegistry) load() error {
+	data, err := os.ReadFile(c.cnf.GetPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var snapshot cacheSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("解析快照失败: %w", err)
+	}
+	if snapshot.Instances != nil {
+		c.snapshot.Instances = snapshot.Instances
+	}
+	if snapshot.Values != nil {
+		c.snapshot.Values = snapshot.Values
+	}
+	c.snapshot.UpdatedAt = snapshot.UpdatedAt
+	return nil
+}
+
+// flush 缓存有变化时写入快照文件, 先写临时文件再改名, 避免进程退出时写入一半
+func (c *CachedRegistry) flush() error {
+	c.lock.Lock()
+	if !c.dirty {
+		c.lock.Unlock()
+		return nil
+	}
+	data, err := json.Marshal(&c.snapshot)
+	c.dirty = false
+	c.lock.Unlock()
+	if err != nil {
+		return fmt.Errorf("序列化快照失败: %w", err)
+	}
+
+	path := c.cnf.GetPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("创建快照目录失败: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("写入快照失败: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("替换快照失败: %w", err)
+	}
+	return nil
+}
+
+// run 定期检查注册中心健康并写入快照, 恢复后重新查询缓存过的数据
+func (c *CachedRegistry) run() {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.cnf.GetCheckInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.ctx.Done():
+			return
+		}
+
+		healthy := c.Registry.IsHealthy()
+
+		c.lock.Lock()
+		recovered := healthy && !c.healthy
+		if c.healthy && !healthy {
+			c.log.Warnf("注册中心不可用，使用本地缓存")
+		}
+		c.healthy = healthy
+		c.lock.Unlock()
+
+		if recovered {
+			c.reconcile()
+		}
+		if err := c.flush(); err != nil {
+			c.log.Errorf("写入注册中心快照失败，路径: %s, 错误: %v", c.cnf.GetPath(), err)
+		}
+	}
+}
+
+// reconcile 注册中心恢复后重新查询缓存过的服务和键
+func (c *CachedRegistry) reconcile() {
+	c.lock.RLock()
+	names := make([]string, 0, len(c.snapshot.Instances))
+	for name := range c.snapshot.Instances {
+		names = append(names, name)
+	}
+	keys := make([]string, 0, len(c.snapshot.Values))
+	for key := range c.snapshot.Values {
+		keys = append(keys, key)
+	}
+	c.lock.RUnlock()
+
+	for _, name := range names {
+		if _, _, err := c.DiscoverWithStale(c.ctx, name); err != nil {
+			c.log.Errorf("注册中心恢复后重新获取服务失败，服务: %s, 错误: %v", name, err)
+		}
+	}
+	for _, key := range keys {
+		c.GetValue(key)
+	}
+
+	c.log.Infof("注册中心已恢复，重新获取 %d 个服务和 %d 个键", len(names), len(keys))
+}
+
+// isHealthy 最近一次检查注册中心是否健康
+func (c *CachedRegistry) isHealthy() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.healthy
+}
+
+// setInstances 更新缓存的实例列表
+func (c *CachedRegistry) setInstances(name string, instances []ServiceInstance) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.snapshot.Instances[name] = instances
+	c.snapshot.UpdatedAt = time.Now()
+	c.dirty = true
+}
+
+// cachedInstances 获取缓存的实例列表
+func (c *CachedRegistry) cachedInstances(name string) ([]ServiceInstance, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	instances, ok := c.snapshot.Instances[name]
+	return instances, ok
+}
+
+// IsStale 注册中心是否不可用, 此时返回的数据来自缓存, 可能已过期
+func (c *CachedRegistry) IsStale() bool {
+	return !c.isHealthy()
+}
+
+// DiscoverWithStale 获取服务的全部实例, stale 为 true 表示注册中心不可用, 实例来自缓存
+func (c *CachedRegistry) DiscoverWithStale(ctx context.Context, name string) (instances []ServiceInstance, stale bool, err error) {
+	if c.isHealthy() {
+		instances, err = c.Registry.Discover(ctx, name)
+		if err == nil {
+			c.setInstances(name, instances)
+			return instances, false, nil
+		}
+	}
+
+	if cached, ok := c.cachedInstances(name); ok {
+		return cached, true, nil
+	}
+	if err == nil {
+		err = fmt.Errorf("注册中心不可用且没有服务 %s 的缓存", name)
+	}
+	return nil, true, err
+}
+
+// Discover 获取服务的全部实例, 注册中心不可用时返回缓存的实例
+func (c *CachedRegistry) Discover(ctx context.Context, name string) ([]ServiceInstance, error) {
+	instances, _, err := c.DiscoverWithStale(ctx, name)
+	return instances, err
+}
+
+// Subscribe 订阅服务实例变化, 先推送缓存的实例; 注册中心不可用时按检查间隔重试订阅, 恢复后推送最新的实例
+//
+// 首次订阅失败且没有该服务的缓存时返回错误
+func (c *CachedRegistry) Subscribe(ctx context.Context, name string) (<-chan []ServiceInstance, error) {
+	upstream, err := c.Registry.Subscribe(ctx, name)
+	cached, ok := c.cachedInstances(name)
+	if err != nil {
+		if !ok {
+			return nil, fmt.Errorf("订阅服务失败且没有服务 %s 的缓存: %w", name, err)
+		}
+		c.log.Warnf("订阅服务失败，使用本地缓存，服务: %s, 错误: %v", name, err)
+	}
+
+	ch := make(chan []ServiceInstance, 1)
+	if ok {
+		ch <- cached
+	}
+
+	go logger.WithRecover(c.log, func() {
+		defer close(ch)
+
+		// 注册中心关闭或订阅通道关闭后重新订阅
+		for {
+			if upstream != nil {
+				for instances := range upstream {
+					c.setInstances(name, instances)
+					if !sendInstances(ctx, ch, instances) {
+						return
+					}
+				}
+			}
+
+			select {
+			case <-time.After(c.cnf.GetCheckInterval()):
+			case <-ctx.Done():
+				return
+			case <-c.ctx.Done():
+				return
+			}
+
+			if upstream, err = c.Registry.Subscribe(ctx, name); err != nil {
+				c.log.Warnf("订阅服务失败，使用本地缓存，服务: %s, 错误: %v", name, err)
+			}
+		}
+	})
+
+	return ch, nil
+}
+
+// ConfigSource 创建被缓存注册中心的配置源
+func (c *CachedRegistry) ConfigSource(key string) (config.Source, error) {
+	return NewConfigSource(c.Registry, key)
+}
+
+// GetValue 获取单个值, 注册中心不可用时返回缓存的值; 带查询选项时不缓存
+func (c *CachedRegistry) GetValue(key string, opts ...any) string {
+	if len(opts) > 0 {
+		return c.Registry.GetValue(key, opts...)
+	}
+
+	if c.isHealthy() {
+		// 各注册中心查询失败时也返回空值, 无法与键不存在区分, 只缓存非空值
+		value := c.Registry.GetValue(key)
+		if value == "" {
+			return value
+		}
+
+		c.lock.Lock()
+		if old, ok := c.snapshot.Values[key]; !ok || old != value {
+			c.snapshot.Values[key] = value
+			c.snapshot.UpdatedAt = time.Now()
+			c.dirty = true
+		}
+		c.lock.Unlock()
+		return value
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.snapshot.Values[key]
+}
+
+// Close 停止健康检查, 写入快照后关闭注册中心
+func (c *CachedRegistry) Close() {
+	c.cancel()
+	<-c.done
+
+	if err := c.flush(); err != nil {
+		c.log.Errorf("写入注册中心快照失败，路径: %s, 错误: %v", c.cnf.GetPath(), err)
+	}
+
+	c.Registry.Close()
+}
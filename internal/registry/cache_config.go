@@ -0,0 +1,39 @@
+package registry
+
+import (
+	"errors"
+	"time"
+)
+
+// errNegativeCheckInterval 健康检查间隔为负数
+var errNegativeCheckInterval = errors.New("negative cache check interval")
+
+// CacheConfig 注册中心本地缓存配置
+type CacheConfig struct {
+	Path          string `yaml:"path"`          // 快照文件路径，默认 ./cache/registry_snapshot.json
+	CheckInterval int    `yaml:"checkInterval"` // 检查注册中心健康和写入快照的间隔（秒），默认5秒
+}
+
+// Validate 验证配置
+func (c *CacheConfig) Validate() error {
+	if c.CheckInterval < 0 {
+		return errNegativeCheckInterval
+	}
+	return nil
+}
+
+// GetPath 获取快照文件路径
+func (c *CacheConfig) GetPath() string {
+	if c.Path == "" {
+		return "./cache/registry_snapshot.json"
+	}
+	return c.Path
+}
+
+// GetCheckInterval 获取检查间隔，默认5秒
+func (c *CacheConfig) GetCheckInterval() time.Duration {
+	if c.CheckInterval <= 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(c.CheckInterval) * time.Second
+}
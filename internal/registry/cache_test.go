@@ -0,0 +1,166 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyRegistry 可以模拟不可用的注册中心
+type flakyRegistry struct {
+	*InMemoryRegistry
+	down atomic.Bool
+}
+
+func (f *flakyRegistry) IsHealthy() bool {
+	return !f.down.Load() && f.InMemoryRegistry.IsHealthy()
+}
+
+func (f *flakyRegistry) Discover(ctx context.Context, name string) ([]ServiceInstance, error) {
+	if f.down.Load() {
+		return nil, errors.New("注册中心不可用")
+	}
+	return f.InMemoryRegistry.Discover(ctx, name)
+}
+
+func (f *flakyRegistry) Subscribe(ctx context.Context, name string) (<-chan []ServiceInstance, error) {
+	if f.down.Load() {
+		return nil, errors.New("注册中心不可用")
+	}
+	return f.InMemoryRegistry.Subscribe(ctx, name)
+}
+
+func (f *flakyRegistry) GetValue(key string, opts ...any) string {
+	if f.down.Load() {
+		return ""
+	}
+	return f.InMemoryRegistry.GetValue(key, opts...)
+}
+
+// waitStale 等待缓存的过期状态
+func waitStale(t *testing.T, c *CachedRegistry, stale bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if c.IsStale() == stale {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("等待过期状态 %v 超时", stale)
+}
+
+// TestCachedRegistry_Stale 测试注册中心不可用时返回缓存并在恢复后更新
+func TestCachedRegistry_Stale(t *testing.T) {
+	store := NewMemoryStore()
+	backend := &flakyRegistry{InMemoryRegistry: newTestMemoryRegistry(t, store, "")}
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+
+	c, err := NewCachedRegistry(backend, &CacheConfig{Path: path, CheckInterval: 1}, backend.log)
+	if err != nil {
+		t.Fatalf("创建缓存失败: %v", err)
+	}
+
+	gate := newTestMemoryRegistry(t, store, "/services/gate")
+	defer gate.Close()
+	gate.Publisher("127.0.0.1:9001")
+	c.Put(context.Background(), "/config/gate", "v1")
+
+	ctx := context.Background()
+	instances, stale, err := c.DiscoverWithStale(ctx, "/services/gate")
+	if err != nil || stale || len(instances) != 1 {
+		t.Fatalf("期望 1 个最新实例, 实际 %+v, stale=%v, err=%v", instances, stale, err)
+	}
+	if value := c.GetValue("/config/gate"); value != "v1" {
+		t.Fatalf("期望 v1, 实际 %s", value)
+	}
+
+	// 不可用时返回缓存
+	backend.down.Store(true)
+	waitStale(t, c, true)
+
+	instances, stale, err = c.DiscoverWithStale(ctx, "/services/gate")
+	if err != nil || !stale || len(instances) != 1 || instances[0].Port != 9001 {
+		t.Fatalf("期望 1 个缓存实例, 实际 %+v, stale=%v, err=%v", instances, stale, err)
+	}
+	if value := c.GetValue("/config/gate"); value != "v1" {
+		t.Fatalf("期望缓存的 v1, 实际 %s", value)
+	}
+	if _, _, err := c.DiscoverWithStale(ctx, "/services/unknown"); err == nil {
+		t.Fatal("没有缓存的服务应返回错误")
+	}
+
+	// 恢复后重新获取
+	gate.Close()
+	backend.down.Store(false)
+	waitStale(t, c, false)
+
+	instances, stale, err = c.DiscoverWithStale(ctx, "/services/gate")
+	if err != nil || stale || len(instances) != 0 {
+		t.Fatalf("恢复后应无实例, 实际 %+v, stale=%v, err=%v", instances, stale, err)
+	}
+	c.Close()
+
+	// 启动时注册中心不可用, 从快照读取
+	down := &flakyRegistry{InMemoryRegistry: newTestMemoryRegistry(t, NewMemoryStore(), "")}
+	down.down.Store(true)
+	restored, err := NewCachedRegistry(down, &CacheConfig{Path: path, CheckInterval: 1}, down.log)
+	if err != nil {
+		t.Fatalf("创建缓存失败: %v", err)
+	}
+	defer restored.Close()
+
+	if !restored.IsStale() {
+		t.Fatal("注册中心不可用时应标记为过期")
+	}
+	if value := restored.GetValue("/config/gate"); value != "v1" {
+		t.Fatalf("期望快照中的 v1, 实际 %s", value)
+	}
+	if instances, err := restored.Discover(ctx, "/services/gate"); err != nil || len(instances) != 0 {
+		t.Fatalf("期望快照中的 0 个实例, 实际 %+v, err=%v", instances, err)
+	}
+}
+
+// TestCachedRegistry_SubscribeDown 测试注册中心不可用时订阅: 有缓存时推送缓存, 没有缓存时返回错误
+func TestCachedRegistry_SubscribeDown(t *testing.T) {
+	store := NewMemoryStore()
+	backend := &flakyRegistry{InMemoryRegistry: newTestMemoryRegistry(t, store, "")}
+
+	c, err := NewCachedRegistry(backend, &CacheConfig{Path: filepath.Join(t.TempDir(), "snapshot.json"), CheckInterval: 1}, backend.log)
+	if err != nil {
+		t.Fatalf("创建缓存失败: %v", err)
+	}
+	defer c.Close()
+
+	gate := newTestMemoryRegistry(t, store, "/services/gate")
+	defer gate.Close()
+	gate.Publisher("127.0.0.1:9001")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := c.Discover(ctx, "/services/gate"); err != nil {
+		t.Fatalf("服务发现失败: %v", err)
+	}
+
+	backend.down.Store(true)
+	if _, err := c.Subscribe(ctx, "/services/unknown"); err == nil {
+		t.Fatal("没有缓存的服务订阅失败时应返回错误")
+	}
+
+	ch, err := c.Subscribe(ctx, "/services/gate")
+	if err != nil {
+		t.Fatalf("有缓存时订阅不应失败: %v", err)
+	}
+	select {
+	case instances := <-ch:
+		if len(instances) != 1 || instances[0].Port != 9001 {
+			t.Fatalf("期望推送缓存的实例, 实际 %+v", instances)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("等待缓存的实例超时")
+	}
+}
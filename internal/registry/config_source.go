@@ -0,0 +1,34 @@
+package registry
+
+import (
+	"fmt"
+
+	"github.com/spelens-gud/trunk/internal/config"
+)
+
+// ConfigSourceProvider 可复用连接作为动态配置源的注册中心
+//
+// CachedRegistry、MultiRegistry 等组合其他注册中心的实现转发给被组合的注册中心
+type ConfigSourceProvider interface {
+	// ConfigSource 创建读取 key 及其子键的配置源
+	ConfigSource(key string) (config.Source, error)
+}
+
+// 确保注册中心实现了 ConfigSourceProvider 接口
+var (
+	_ ConfigSourceProvider = (*EtcdRegistry)(nil)
+	_ ConfigSourceProvider = (*ConsulRegistry)(nil)
+	_ ConfigSourceProvider = (*NacosRegistry)(nil)
+	_ ConfigSourceProvider = (*InMemoryRegistry)(nil)
+	_ ConfigSourceProvider = (*CachedRegistry)(nil)
+	_ ConfigSourceProvider = (*MultiRegistry)(nil)
+)
+
+// NewConfigSource 复用注册中心的连接创建配置源, 注册中心未实现 ConfigSourceProvider 时返回错误
+func NewConfigSource(reg Registry, key string) (config.Source, error) {
+	provider, ok := reg.(ConfigSourceProvider)
+	if !ok {
+		return nil, fmt.Errorf("注册中心 %T 不支持作为配置源", reg)
+	}
+	return provider.ConfigSource(key)
+}
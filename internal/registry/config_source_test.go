@@ -0,0 +1,58 @@
+package registry
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestNewConfigSource 测试内存注册中心以及组合它的缓存、多注册中心都能作为配置源
+func TestNewConfigSource(t *testing.T) {
+	primary, secondary := NewMemoryStore(), NewMemoryStore()
+	m, _ := newTestMultiRegistry(t, primary, secondary)
+	defer m.Close()
+
+	cached, err := NewCachedRegistry(newTestMemoryRegistry(t, primary, "/services/gate/c"),
+		&CacheConfig{Path: filepath.Join(t.TempDir(), "snapshot.json"), CheckInterval: 1}, m.log)
+	if err != nil {
+		t.Fatalf("创建缓存注册中心失败: %v", err)
+	}
+	defer cached.Close()
+
+	primary.put("/wsh/moba/common", "RateLimit:\n  qps: 100\n", 0)
+	primary.put("/wsh/moba/common/Logger/log_level", "debug", 0)
+	secondary.put("/wsh/moba/common", "RateLimit:\n  qps: 1\n", 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, reg := range []Registry{m, cached} {
+		source, err := NewConfigSource(reg, "/wsh/moba/common")
+		if err != nil {
+			t.Fatalf("%T 创建配置源失败: %v", reg, err)
+		}
+
+		settings, err := source.Load(ctx)
+		if err != nil {
+			t.Fatalf("%T 读取配置失败: %v", reg, err)
+		}
+		limit, _ := settings["ratelimit"].(map[string]any)
+		logger, _ := settings["logger"].(map[string]any)
+		if limit["qps"] != 100 || logger["log_level"] != "debug" {
+			t.Fatalf("%T 期望读取主注册中心的配置, 实际 %v", reg, settings)
+		}
+
+		ch, err := source.Watch(ctx)
+		if err != nil {
+			t.Fatalf("%T 监听配置失败: %v", reg, err)
+		}
+		primary.put("/wsh/moba/common/RateLimit/qps", "200", 0)
+		select {
+		case <-ch:
+		case <-time.After(3 * time.Second):
+			t.Fatalf("%T 等待配置变化通知超时", reg)
+		}
+		primary.delete("/wsh/moba/common/RateLimit/qps")
+	}
+}
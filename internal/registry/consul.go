@@ -4,11 +4,11 @@ import (
 	"context"
 	"fmt"
 	"sync"
-	"time"
 
 	"github.com/hashicorp/consul/api"
 	"github.com/spelens-gud/assert"
 	"github.com/spelens-gud/logger"
+	"github.com/spelens-gud/trunk/internal/config"
 )
 
 // ConsulRegistry consul注册中心实现
@@ -19,11 +19,19 @@ type ConsulRegistry struct {
 	lock       sync.RWMutex
 	ctx        context.Context
 	cancel     context.CancelFunc
-	registered bool // 标记服务是否已注册
+	registered bool                   // 标记服务是否已注册
+	val        string                 // 注册的值, 为注册记录时写入服务的元数据
+	elections  map[string]*consulLock // 已当选的选举
+	healthFunc HealthFunc             // TTL 健康检查上报的健康状态来源
+	ttlCancel  context.CancelFunc     // 停止 TTL 上报
 }
 
-// 确保 ConsulRegistry 实现了 Registry 接口
-var _ Registry = (*ConsulRegistry)(nil)
+// 确保 ConsulRegistry 实现了 Registry、Elector 和 Locker 接口
+var (
+	_ Registry = (*ConsulRegistry)(nil)
+	_ Elector  = (*ConsulRegistry)(nil)
+	_ Locker   = (*ConsulRegistry)(nil)
+)
 
 // New 初始化consul客户端
 func (c *ConsulRegistry) New() {
@@ -53,6 +61,16 @@ func (c *ConsulRegistry) New() {
 	c.log.Infof("初始化consul注册中心，地址: %s", c.cnf.Address)
 }
 
+// GetClient 获取consul客户端
+func (c *ConsulRegistry) GetClient() *api.Client {
+	return c.client
+}
+
+// ConfigSource 复用consul客户端创建配置源
+func (c *ConsulRegistry) ConfigSource(key string) (config.Source, error) {
+	return config.NewConsulSource(c.client, key, c.log), nil
+}
+
 // Publisher 注册服务
 func (c *ConsulRegistry) Publisher(value string) {
 	c.lock.Lock()
@@ -60,6 +78,11 @@ func (c *ConsulRegistry) Publisher(value string) {
 
 	c.log.Infof("注册consul服务: %s", c.cnf.ServiceName)
 
+	// 刷新时沿用上次注册的值
+	if value != "" {
+		c.val = value
+	}
+
 	registration := &api.AgentServiceRegistration{
 		ID:                c.cnf.GetServiceID(),
 		Name:              c.cnf.ServiceName,
@@ -70,6 +93,12 @@ func (c *ConsulRegistry) Publisher(value string) {
 		EnableTagOverride: c.cnf.EnableTagOverride,
 	}
 
+	// 注册记录写入元数据和权重
+	if record, err := ParseRegistration(c.val); err == nil {
+		registration.Meta = mergeMeta(c.cnf.ServiceMeta, record)
+		registration.Weights = &api.AgentWeights{Passing: max(int(record.Weight), 1), Warning: 1}
+	}
+
 	// 添加健康检查
 	assert.MayTrue(c.cnf.HaHealthCheckPath(), func() {
 		check := &api.AgentServiceCheck{
@@ -81,6 +110,11 @@ func (c *ConsulRegistry) Publisher(value string) {
 		registration.Check = check
 	})
 
+	// TTL 健康检查, 由进程定期上报, 适用于没有 HTTP 端口的 QUIC、TARS 服务
+	assert.MayTrue(c.cnf.HasCheckTTL(), func() {
+		registration.Checks = append(registration.Checks, c.ttlCheck())
+	})
+
 	// 企业版特性
 	assert.MayTrue(c.cnf.HasNamespace(), func() {
 		registration.Namespace = c.cnf.Namespace
@@ -89,7 +123,13 @@ func (c *ConsulRegistry) Publisher(value string) {
 		registration.Partition = c.cnf.Partition
 	})
 
-	assert.ShouldCall1E(c.client.Agent().ServiceRegister, registration, "注册consul服务失败")
+	// 注册失败时不启动 TTL 上报, 检查不存在时上报只会持续失败
+	if err := c.client.Agent().ServiceRegister(registration); err != nil {
+		c.log.Errorf("注册consul服务失败: %v", err)
+		return
+	}
+
+	assert.MayTrue(c.cnf.HasCheckTTL(), c.startTTL)
 
 	c.registered = true
 	c.log.Infof("consul服务注册成功")
@@ -107,6 +147,8 @@ func (c *ConsulRegistry) Deregister() {
 
 	c.log.Infof("注销consul服务: %s", c.cnf.GetServiceID())
 
+	c.stopTTL()
+
 	assert.ShouldCall1E(c.client.Agent().ServiceDeregister, c.cnf.GetServiceID(), "注销consul服务失败")
 
 	c.registered = false
@@ -178,41 +220,18 @@ func (c *ConsulRegistry) Put(ctx context.Context, key string, val string) {
 	assert.ShouldCall2RE(c.client.KV().Put, kv, nil, "写入consul KV失败")
 }
 
-// Watch 监听服务变化
+// Watch 监听服务实例变化, 返回 <-chan ServiceEvent, 监听键值请使用 WatchKV
 func (c *ConsulRegistry) Watch(ctx context.Context, prefix string) interface{} {
-	c.log.Infof("开始监听consul服务变化: %s", prefix)
-
-	go logger.WithRecover(c.log, func() {
-		var lastIndex uint64
-		for {
-			select {
-			case <-ctx.Done():
-				c.log.Infof("停止监听consul服务: %s", prefix)
-				return
-			default:
-				queryOpts := &api.QueryOptions{
-					WaitIndex: lastIndex,
-					WaitTime:  time.Minute,
-				}
-				services, meta, err := c.client.Health().Service(prefix, "", false, queryOpts)
-				if err != nil {
-					c.log.Errorf("监听consul服务失败: %v", err)
-					time.Sleep(time.Second)
-					continue
-				}
-				lastIndex = meta.LastIndex
-				c.log.Infof("consul服务变化: %v", services)
-			}
-		}
-	})
-
-	return nil
+	return c.WatchTyped(ctx, prefix)
 }
 
 // Close 关闭consul客户端
 func (c *ConsulRegistry) Close() {
 	c.log.Infof("关闭consul注册中心")
 
+	// 让出全部领导权
+	c.resignAll()
+
 	assert.MayTrue(c.client != nil, func() {
 		c.cancel()
 	})
@@ -249,3 +268,52 @@ func (c *ConsulRegistry) Refresh() {
 func (c *ConsulRegistry) GetLeaseID() uint64 {
 	return 0
 }
+
+// consulInstances 转换服务实例列表
+func consulInstances(entries []*api.ServiceEntry) []ServiceInstance {
+	instances := make([]ServiceInstance, 0, len(entries))
+	for _, entry := range entries {
+		instances = append(instances, consulInstance(entry))
+	}
+	return instances
+}
+
+// Discover 获取服务的全部实例(包括未通过健康检查的实例, 通过 Healthy 区分)
+func (c *ConsulRegistry) Discover(ctx context.Context, name string) ([]ServiceInstance, error) {
+	entries, _, err := c.client.Health().Service(name, "", false, (&api.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("获取consul服务实例失败: %w", err)
+	}
+	return consulInstances(entries), nil
+}
+
+// Subscribe 订阅服务实例变化, 基于阻塞查询实现
+func (c *ConsulRegistry) Subscribe(ctx context.Context, name string) (<-chan []ServiceInstance, error) {
+	entries, meta, err := c.client.Health().Service(name, "", false, (&api.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("获取consul服务实例失败: %w", err)
+	}
+
+	ch := make(chan []ServiceInstance, 1)
+	ch <- consulInstances(entries)
+
+	watchCtx, cancel := c.watchContext(ctx)
+	go logger.WithRecover(c.log, func() {
+		defer close(ch)
+		defer cancel()
+
+		c.blockingLoop(watchCtx, name, meta.LastIndex, func(opts *api.QueryOptions) (uint64, error) {
+			var queryMeta *api.QueryMeta
+			var err error
+			entries, queryMeta, err = c.client.Health().Service(name, "", false, opts)
+			if err != nil {
+				return 0, err
+			}
+			return queryMeta.LastIndex, nil
+		}, func() bool {
+			return sendInstances(watchCtx, ch, consulInstances(entries))
+		})
+	})
+
+	return ch, nil
+}
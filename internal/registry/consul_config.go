@@ -28,9 +28,11 @@ type ConsulConfig struct {
 	HealthCheckInterval string            `yaml:"healthCheckInterval"` // 健康检查间隔，如 "10s"
 	HealthCheckTimeout  string            `yaml:"healthCheckTimeout"`  // 健康检查超时，如 "5s"
 	DeregisterAfter     string            `yaml:"deregisterAfter"`     // 注销时间，如 "30s"
+	CheckTTL            string            `yaml:"checkTTL"`            // TTL健康检查的超时，如 "15s"，配置后由进程定期上报健康状态
 	EnableTagOverride   bool              `yaml:"enableTagOverride"`   // 是否允许标签覆盖
 	Namespace           string            `yaml:"namespace"`           // 命名空间（企业版）
 	Partition           string            `yaml:"partition"`           // 分区（企业版）
+	SessionTTL          string            `yaml:"sessionTTL"`          // 选主和分布式锁的会话TTL，如 "15s"
 	TLSConfig           *ConsulTLSConfig  `yaml:"tlsConfig"`           // TLS配置
 }
 
@@ -93,6 +95,33 @@ func (c *ConsulConfig) GetDeregisterAfter() string {
 	return c.DeregisterAfter
 }
 
+// GetSessionTTL 获取会话TTL，默认15秒
+func (c *ConsulConfig) GetSessionTTL() string {
+	if c.SessionTTL == "" {
+		return "15s"
+	}
+	return c.SessionTTL
+}
+
+// HasCheckTTL 是否配置了TTL健康检查
+func (c *ConsulConfig) HasCheckTTL() bool {
+	return c.GetCheckTTLDuration() > 0
+}
+
+// GetCheckTTLDuration 获取TTL健康检查的超时，未配置或格式错误时为0
+func (c *ConsulConfig) GetCheckTTLDuration() time.Duration {
+	duration, err := time.ParseDuration(c.CheckTTL)
+	if err != nil {
+		return 0
+	}
+	return duration
+}
+
+// GetCheckTTLInterval 获取TTL健康检查的上报间隔, 每个 TTL 周期上报3次, 偶尔一次失败不会导致检查过期; 最小100毫秒
+func (c *ConsulConfig) GetCheckTTLInterval() time.Duration {
+	return max(c.GetCheckTTLDuration()/3, 100*time.Millisecond)
+}
+
 // HasTLS 是否配置了TLS
 func (c *ConsulConfig) HasTLS() bool {
 	return c.TLSConfig != nil &&
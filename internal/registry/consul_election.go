@@ -0,0 +1,291 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/spelens-gud/logger"
+)
+
+// consulLockKey consul 的键不能以 / 开头, 与 etcd 的选举名保持一致时去掉前导 /
+func consulLockKey(name string) string {
+	return strings.TrimPrefix(name, "/")
+}
+
+// consulLock 基于会话的锁, 会话由注册中心创建并续约, 当选后可用同一会话更新公布的值
+type consulLock struct {
+	lock    *api.Lock
+	session string
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// newLock 创建基于会话的锁, 会话过期或进程退出时锁自动释放
+func (c *ConsulRegistry) newLock(name, value, sessionName string) (*consulLock, error) {
+	if c.client == nil {
+		return nil, errors.New("consul客户端为空")
+	}
+
+	ttl := c.cnf.GetSessionTTL()
+	session, _, err := c.client.Session().Create(&api.SessionEntry{
+		Name:     sessionName,
+		TTL:      ttl,
+		Behavior: api.SessionBehaviorRelease,
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建consul会话失败: %w", err)
+	}
+
+	lock, err := c.client.LockOpts(&api.LockOptions{
+		Key:     consulLockKey(name),
+		Value:   []byte(value),
+		Session: session,
+	})
+	if err != nil {
+		_, _ = c.client.Session().Destroy(session, nil)
+		return nil, fmt.Errorf("创建consul锁失败: %w", err)
+	}
+
+	l := &consulLock{lock: lock, session: session, stop: make(chan struct{})}
+	go logger.WithRecover(c.log, func() {
+		// 停止续约时销毁会话
+		if err := c.client.Session().RenewPeriodic(ttl, session, nil, l.stop); err != nil {
+			c.log.Warnf("consul会话续约失败，会话: %s, 错误: %v", session, err)
+		}
+	})
+
+	return l, nil
+}
+
+// acquire 获取锁, 阻塞直到获得锁或 ctx 取消, 返回失去锁时关闭的通道
+func (l *consulLock) acquire(ctx context.Context) (<-chan struct{}, error) {
+	stopCh := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() { close(stopCh) })
+	defer stop()
+
+	lostCh, err := l.lock.Lock(stopCh)
+	if err == nil && lostCh == nil {
+		// 获取被中止时返回空通道
+		err = ctx.Err()
+	}
+	if err != nil {
+		l.destroy()
+		return nil, err
+	}
+	return lostCh, nil
+}
+
+// release 释放锁并销毁会话
+func (l *consulLock) release() error {
+	err := l.lock.Unlock()
+	l.destroy()
+	return err
+}
+
+// destroy 停止续约并销毁会话
+func (l *consulLock) destroy() {
+	l.once.Do(func() {
+		close(l.stop)
+	})
+}
+
+// Campaign 参与选举, 已当选时更新公布的值
+func (c *ConsulRegistry) Campaign(ctx context.Context, election, value string) error {
+	c.lock.RLock()
+	held, ok := c.elections[election]
+	c.lock.RUnlock()
+	if ok {
+		return c.proclaim(ctx, held, election, value)
+	}
+
+	lock, err := c.newLock(election, value, "election:"+election)
+	if err != nil {
+		return err
+	}
+	lostCh, err := lock.acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("参与选举失败: %w", err)
+	}
+
+	c.lock.Lock()
+	if c.elections == nil {
+		c.elections = make(map[string]*consulLock)
+	}
+	c.elections[election] = lock
+	c.lock.Unlock()
+
+	// 会话失效时清理选举记录
+	go logger.WithRecover(c.log, func() {
+		<-lostCh
+
+		c.lock.Lock()
+		lost := c.elections[election] == lock
+		if lost {
+			delete(c.elections, election)
+		}
+		c.lock.Unlock()
+
+		if lost {
+			lock.destroy()
+			c.log.Warnf("失去领导权，选举: %s", election)
+		}
+	})
+
+	c.log.Infof("当选领导者，选举: %s, 值: %s", election, value)
+	return nil
+}
+
+// proclaim 使用持有锁的会话更新领导者的值
+func (c *ConsulRegistry) proclaim(ctx context.Context, lock *consulLock, election, value string) error {
+	kv := c.client.KV()
+	pair, _, err := kv.Get(consulLockKey(election), (&api.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("获取领导者失败: %w", err)
+	}
+	if pair == nil || pair.Session != lock.session {
+		return ErrNotLeader
+	}
+
+	pair.Value = []byte(value)
+	acquired, _, err := kv.Acquire(pair, (&api.WriteOptions{}).WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("更新领导者的值失败: %w", err)
+	}
+	if !acquired {
+		return ErrNotLeader
+	}
+	return nil
+}
+
+// Resign 主动让出领导权
+func (c *ConsulRegistry) Resign(ctx context.Context, election string) error {
+	c.lock.Lock()
+	lock, ok := c.elections[election]
+	delete(c.elections, election)
+	c.lock.Unlock()
+
+	if !ok {
+		return ErrNotLeader
+	}
+	if err := lock.release(); err != nil {
+		return fmt.Errorf("让出领导权失败: %w", err)
+	}
+
+	c.log.Infof("让出领导权，选举: %s", election)
+	return nil
+}
+
+// resignAll 让出全部领导权
+func (c *ConsulRegistry) resignAll() {
+	c.lock.Lock()
+	elections := c.elections
+	c.elections = nil
+	c.lock.Unlock()
+
+	for election, lock := range elections {
+		if err := lock.release(); err != nil {
+			c.log.Warnf("让出领导权失败，选举: %s, 错误: %v", election, err)
+		}
+	}
+}
+
+// Leader 获取当前领导者公布的值
+func (c *ConsulRegistry) Leader(ctx context.Context, election string) (string, error) {
+	pair, _, err := c.client.KV().Get(consulLockKey(election), (&api.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("获取领导者失败: %w", err)
+	}
+	// 锁被释放后键仍保留, 以是否持有会话判断
+	if pair == nil || pair.Session == "" {
+		return "", ErrNoLeader
+	}
+	return string(pair.Value), nil
+}
+
+// ObserveLeader 监听领导者变化, 基于阻塞查询实现
+func (c *ConsulRegistry) ObserveLeader(ctx context.Context, election string) (<-chan string, error) {
+	if c.client == nil {
+		return nil, errors.New("consul客户端为空")
+	}
+
+	key := consulLockKey(election)
+	ch := make(chan string)
+
+	go logger.WithRecover(c.log, func() {
+		defer close(ch)
+
+		var lastIndex uint64
+		var lastValue string
+		for {
+			queryOpts := (&api.QueryOptions{
+				WaitIndex: lastIndex,
+				WaitTime:  time.Minute,
+			}).WithContext(ctx)
+			pair, meta, err := c.client.KV().Get(key, queryOpts)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				c.log.Errorf("监听领导者失败，选举: %s, 错误: %v", election, err)
+				select {
+				case <-time.After(time.Second):
+				case <-ctx.Done():
+					return
+				}
+				continue
+			}
+
+			// 索引回退时重置, 避免阻塞查询立即返回导致空转
+			if meta.LastIndex < lastIndex {
+				lastIndex = 0
+				continue
+			}
+			lastIndex = meta.LastIndex
+
+			if pair == nil || pair.Session == "" {
+				lastValue = ""
+				continue
+			}
+			if value := string(pair.Value); value != lastValue {
+				lastValue = value
+				select {
+				case ch <- value:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	})
+
+	return ch, nil
+}
+
+// Lock 加锁, 会话过期时锁自动释放
+func (c *ConsulRegistry) Lock(ctx context.Context, name string) (Unlocker, error) {
+	lock, err := c.newLock(name, "", "lock:"+name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := lock.acquire(ctx); err != nil {
+		return nil, fmt.Errorf("加锁失败: %w", err)
+	}
+	return consulUnlocker{lock: lock}, nil
+}
+
+// consulUnlocker consul 分布式锁
+type consulUnlocker struct {
+	lock *consulLock
+}
+
+// Unlock 释放锁
+func (u consulUnlocker) Unlock(context.Context) error {
+	if err := u.lock.release(); err != nil {
+		return fmt.Errorf("释放锁失败: %w", err)
+	}
+	return nil
+}
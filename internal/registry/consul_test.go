@@ -3,7 +3,11 @@ package registry
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -59,10 +63,36 @@ func newTestConsulRegistry(t *testing.T) *ConsulRegistry {
 	if registry.client == nil {
 		t.Skip("跳过测试：无法连接到 consul")
 	}
+	if err := probeConsul(); err != nil {
+		t.Skipf("跳过测试：consul 未响应: %v", err)
+	}
 
 	return registry
 }
 
+var (
+	consulProbeOnce sync.Once
+	consulProbeErr  error
+)
+
+// probeConsul 探测测试用的 consul 是否可用, 只探测一次, 不可达时尽快返回
+func probeConsul() error {
+	consulProbeOnce.Do(func() {
+		config := api.DefaultConfig()
+		config.Address = testConsulConfig.Address
+		config.Scheme = testConsulConfig.Scheme
+		config.HttpClient = &http.Client{Timeout: 2 * time.Second}
+
+		client, err := api.NewClient(config)
+		if err != nil {
+			consulProbeErr = err
+			return
+		}
+		_, consulProbeErr = client.Agent().Self()
+	})
+	return consulProbeErr
+}
+
 // 清理测试数据
 func cleanupConsulTestData(t *testing.T, registry *ConsulRegistry, prefix string) {
 	t.Helper()
@@ -251,10 +281,10 @@ func TestConsulRegistry_Watch(t *testing.T) {
 	serviceName := "watch-test-service"
 
 	// 启动监听
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	go registry.Watch(ctx, serviceName)
+	events := registry.WatchTyped(ctx, serviceName)
 
 	// 等待监听启动
 	time.Sleep(200 * time.Millisecond)
@@ -271,11 +301,68 @@ func TestConsulRegistry_Watch(t *testing.T) {
 	testRegistry.New()
 	testRegistry.Publisher("test")
 
-	// 等待事件处理
-	time.Sleep(500 * time.Millisecond)
+	expectServiceEvent(t, ctx, events, ServiceAdded, testConfig.ServiceID)
 
-	// 清理
+	// 注销后收到移除事件
 	testRegistry.Close()
+	expectServiceEvent(t, ctx, events, ServiceRemoved, testConfig.ServiceID)
+}
+
+// expectServiceEvent 等待指定实例的变化事件
+func expectServiceEvent(t *testing.T, ctx context.Context, events <-chan ServiceEvent, eventType ServiceEventType, id string) {
+	t.Helper()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatal("监听通道被关闭")
+			}
+			if event.Type == eventType && event.Instance.ID == id {
+				return
+			}
+		case <-ctx.Done():
+			t.Fatalf("等待 %s 事件超时, 实例: %s", eventType, id)
+		}
+	}
+}
+
+// TestConsulRegistry_WatchKV 测试监听键值变化
+func TestConsulRegistry_WatchKV(t *testing.T) {
+	registry := newTestConsulRegistry(t)
+	defer registry.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	prefix := fmt.Sprintf("test/watch-%d/", time.Now().UnixNano())
+	events := registry.WatchKV(ctx, "/"+prefix)
+	defer registry.client.KV().DeleteTree(prefix, nil)
+
+	// 等待首次查询完成
+	time.Sleep(200 * time.Millisecond)
+
+	registry.Put(ctx, prefix+"key", "value1")
+	select {
+	case event := <-events:
+		if event.Type != KVPut || event.Key != prefix+"key" || event.Value != "value1" {
+			t.Fatalf("事件不符合预期: %+v", event)
+		}
+	case <-ctx.Done():
+		t.Fatal("等待写入事件超时")
+	}
+
+	if _, err := registry.client.KV().Delete(prefix+"key", nil); err != nil {
+		t.Fatalf("删除键失败: %v", err)
+	}
+	select {
+	case event := <-events:
+		if event.Type != KVDelete || event.Key != prefix+"key" {
+			t.Fatalf("事件不符合预期: %+v", event)
+		}
+	case <-ctx.Done():
+		t.Fatal("等待删除事件超时")
+	}
 }
 
 // TestConsulRegistry_IsHealthy 测试健康检查
@@ -719,3 +806,149 @@ func BenchmarkConsulRegistry_ConcurrentGetValue(b *testing.B) {
 		}
 	})
 }
+
+// TestConnectionHealth 测试按连接数判断健康状态
+func TestConnectionHealth(t *testing.T) {
+	var count int32
+	fn := ConnectionHealth(func() int32 { return count }, 80, 100)
+
+	tests := []struct {
+		count int32
+		want  HealthStatus
+	}{
+		{count: 10, want: HealthPass},
+		{count: 80, want: HealthWarn},
+		{count: 100, want: HealthFail},
+	}
+	for _, tt := range tests {
+		count = tt.count
+		if status, output := fn(); status != tt.want {
+			t.Errorf("连接数 %d 期望 %s, 实际 %s (%s)", tt.count, tt.want, status, output)
+		}
+	}
+
+	healthy := true
+	fn = HealthFromBool(func() bool { return healthy })
+	if status, _ := fn(); status != HealthPass {
+		t.Errorf("期望 %s, 实际 %s", HealthPass, status)
+	}
+	healthy = false
+	if status, _ := fn(); status != HealthFail {
+		t.Errorf("期望 %s, 实际 %s", HealthFail, status)
+	}
+}
+
+// TestConsulConfig_GetCheckTTLInterval 测试 TTL 上报间隔为 TTL 的 1/3, 过小时取最小值
+func TestConsulConfig_GetCheckTTLInterval(t *testing.T) {
+	tests := []struct {
+		ttl  string
+		want time.Duration
+	}{
+		{ttl: "15s", want: 5 * time.Second},
+		{ttl: "2ns", want: 100 * time.Millisecond},
+		{ttl: "200ms", want: 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		config := &ConsulConfig{CheckTTL: tt.ttl}
+		if got := config.GetCheckTTLInterval(); got != tt.want {
+			t.Errorf("TTL %s 期望间隔 %v, 实际 %v", tt.ttl, tt.want, got)
+		}
+	}
+}
+
+// fakeConsulAgent 模拟 consul agent 的服务注册和 TTL 上报接口
+type fakeConsulAgent struct {
+	failRegister atomic.Bool  // 注册是否返回错误
+	updates      atomic.Int32 // TTL 上报次数
+}
+
+// ServeHTTP 处理请求
+func (f *fakeConsulAgent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/v1/agent/service/register" && f.failRegister.Load():
+		http.Error(w, "register failed", http.StatusInternalServerError)
+	case strings.HasPrefix(r.URL.Path, "/v1/agent/check/update/"):
+		f.updates.Add(1)
+	}
+}
+
+// TestConsulRegistry_TTLAfterRegister 测试注册失败时不上报 TTL, 注册成功后按最小间隔上报
+func TestConsulRegistry_TTLAfterRegister(t *testing.T) {
+	log, err := logger.NewLogger(logger.DefaultConfig())
+	if err != nil {
+		t.Fatalf("创建 logger 失败: %v", err)
+	}
+	assert.SetLogger(log)
+
+	agent := &fakeConsulAgent{}
+	agent.failRegister.Store(true)
+	server := httptest.NewServer(agent)
+	defer server.Close()
+
+	registry := &ConsulRegistry{
+		cnf: &ConsulConfig{
+			Address:     strings.TrimPrefix(server.URL, "http://"),
+			Scheme:      "http",
+			ServiceName: "ttl-test-service",
+			CheckTTL:    "150ms", // 1/3 为 50ms, 上报间隔取最小值 100ms
+		},
+		log: log,
+	}
+	registry.New()
+	defer registry.Close()
+
+	// 注册失败, 不启动 TTL 上报
+	registry.Publisher("")
+	time.Sleep(300 * time.Millisecond)
+	if n := agent.updates.Load(); n != 0 || registry.registered {
+		t.Fatalf("注册失败后不应上报 TTL, 上报 %d 次, 已注册: %v", n, registry.registered)
+	}
+
+	// 注册成功, 按 100ms 的间隔上报
+	agent.failRegister.Store(false)
+	registry.Publisher("")
+	time.Sleep(time.Second)
+	if n := agent.updates.Load(); n < 5 || n > 13 {
+		t.Errorf("1 秒内期望按 100ms 间隔上报约 10 次, 实际 %d 次", n)
+	}
+}
+
+// TestConsulRegistry_CheckTTL 测试 TTL 健康检查按健康函数上报
+func TestConsulRegistry_CheckTTL(t *testing.T) {
+	testConfig := *testConsulConfig
+	testConfig.HealthCheckPath = ""
+	testConfig.CheckTTL = "3s"
+	testConfig.ServiceID = "ttl-test-service-1"
+
+	registry := newTestConsulRegistry(t)
+	registry.cnf = &testConfig
+	defer registry.Close()
+
+	var status atomic.Int32
+	registry.SetHealthFunc(func() (HealthStatus, string) {
+		return HealthStatus(status.Load()), "test"
+	})
+	registry.Publisher("")
+
+	waitCheckStatus(t, registry, api.HealthPassing)
+
+	status.Store(int32(HealthWarn))
+	waitCheckStatus(t, registry, api.HealthWarning)
+}
+
+// waitCheckStatus 等待 TTL 检查变为指定状态
+func waitCheckStatus(t *testing.T, registry *ConsulRegistry, want string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		checks, err := registry.client.Agent().Checks()
+		if err == nil {
+			if check, ok := checks[registry.ttlCheckID()]; ok && check.Status == want {
+				return
+			}
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	t.Fatalf("等待检查状态 %s 超时", want)
+}
@@ -0,0 +1,139 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/spelens-gud/logger"
+)
+
+// HealthStatus 进程上报的健康状态
+type HealthStatus int
+
+const (
+	// HealthPass 健康
+	HealthPass HealthStatus = iota
+	// HealthWarn 可用但需要关注, 如连接数接近上限
+	HealthWarn
+	// HealthFail 不可用
+	HealthFail
+)
+
+// String 健康状态名称, 与 consul 的检查状态一致
+func (s HealthStatus) String() string {
+	switch s {
+	case HealthPass:
+		return api.HealthPassing
+	case HealthWarn:
+		return api.HealthWarning
+	default:
+		return api.HealthCritical
+	}
+}
+
+// HealthFunc 返回进程当前的健康状态和说明
+type HealthFunc func() (HealthStatus, string)
+
+// HealthFromBool 把传输层的 IsHealthy 之类的判断转换为 HealthFunc
+func HealthFromBool(healthy func() bool) HealthFunc {
+	return func() (HealthStatus, string) {
+		if healthy() {
+			return HealthPass, "ok"
+		}
+		return HealthFail, "unhealthy"
+	}
+}
+
+// ConnectionHealth 按连接数判断健康状态, 达到 warn 时告警, 达到 fail 时不可用, 阈值不大于0时不检查
+func ConnectionHealth[T ~int | ~int32 | ~int64](count func() T, warn, fail T) HealthFunc {
+	return func() (HealthStatus, string) {
+		n := count()
+		output := fmt.Sprintf("connections: %d", n)
+		switch {
+		case fail > 0 && n >= fail:
+			return HealthFail, output
+		case warn > 0 && n >= warn:
+			return HealthWarn, output
+		default:
+			return HealthPass, output
+		}
+	}
+}
+
+// SetHealthFunc 设置 TTL 健康检查上报的健康状态来源, 未设置时始终上报健康
+func (c *ConsulRegistry) SetHealthFunc(fn HealthFunc) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.healthFunc = fn
+}
+
+// ttlCheckID TTL 健康检查的ID
+func (c *ConsulRegistry) ttlCheckID() string {
+	return "service:" + c.cnf.GetServiceID() + ":ttl"
+}
+
+// ttlCheck TTL 健康检查, 注册后立即上报一次, 之前的状态为严重
+func (c *ConsulRegistry) ttlCheck() *api.AgentServiceCheck {
+	return &api.AgentServiceCheck{
+		CheckID:                        c.ttlCheckID(),
+		Name:                           "ttl",
+		TTL:                            c.cnf.GetCheckTTLDuration().String(),
+		DeregisterCriticalServiceAfter: c.cnf.GetDeregisterAfter(),
+	}
+}
+
+// startTTL 启动 TTL 上报, 需持有 c.lock; 重复注册时先停止之前的上报
+func (c *ConsulRegistry) startTTL() {
+	c.stopTTL()
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	c.ttlCancel = cancel
+
+	interval := c.cnf.GetCheckTTLInterval()
+	checkID := c.ttlCheckID()
+	go logger.WithRecover(c.log, func() {
+		c.runTTL(ctx, checkID, interval)
+	})
+}
+
+// stopTTL 停止 TTL 上报, 需持有 c.lock
+func (c *ConsulRegistry) stopTTL() {
+	if c.ttlCancel != nil {
+		c.ttlCancel()
+		c.ttlCancel = nil
+	}
+}
+
+// runTTL 定期上报健康状态直到 ctx 取消
+func (c *ConsulRegistry) runTTL(ctx context.Context, checkID string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := HealthStatus(-1)
+	for {
+		c.lock.RLock()
+		fn := c.healthFunc
+		c.lock.RUnlock()
+
+		status, output := HealthPass, "ok"
+		if fn != nil {
+			status, output = fn()
+		}
+
+		if err := c.client.Agent().UpdateTTL(checkID, output, status.String()); err != nil {
+			c.log.Errorf("上报consul健康状态失败，检查: %s, 错误: %v", checkID, err)
+		} else if status != last {
+			c.log.Infof("consul健康状态变化，检查: %s, 状态: %s, 说明: %s", checkID, status, output)
+			last = status
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package registry
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/spelens-gud/assert"
+	"github.com/spelens-gud/logger"
+)
+
+const (
+	// consulWatchWait 阻塞查询的最长等待时间
+	consulWatchWait = time.Minute
+	// consulMinBackoff 查询失败后的首次重试间隔
+	consulMinBackoff = time.Second
+	// consulMaxBackoff 连续失败时的最大重试间隔
+	consulMaxBackoff = 30 * time.Second
+)
+
+// blockingLoop 从 index 开始循环执行阻塞查询, 直到 ctx 取消或 handle 返回 false
+//
+// query 执行查询并返回结果的索引, 索引变化时调用 handle 处理本次结果; 查询失败时按指数退避重试
+func (c *ConsulRegistry) blockingLoop(ctx context.Context, target string, index uint64,
+	query func(opts *api.QueryOptions) (uint64, error), handle func() bool) {
+	backoff := consulMinBackoff
+	for ctx.Err() == nil {
+		opts := (&api.QueryOptions{
+			WaitIndex: index,
+			WaitTime:  consulWatchWait,
+		}).WithContext(ctx)
+		lastIndex, err := query(opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			c.log.Errorf("consul阻塞查询失败，目标: %s, 错误: %v, %v后重试", target, err, backoff)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return
+			}
+			backoff = min(backoff*2, consulMaxBackoff)
+			continue
+		}
+		backoff = consulMinBackoff
+
+		// 阻塞查询超时返回时索引不变, 无需处理
+		if lastIndex == index && index != 0 {
+			continue
+		}
+		// 索引回退时重置, 避免阻塞查询立即返回导致空转
+		if lastIndex < index {
+			index = 0
+			continue
+		}
+		index = lastIndex
+
+		if !handle() {
+			return
+		}
+	}
+}
+
+// watchContext ctx 取消或注册中心关闭时取消的上下文
+func (c *ConsulRegistry) watchContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	watchCtx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(c.ctx, cancel)
+	return watchCtx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// WatchTyped 监听服务实例变化(类型安全版本), 对比前后两次阻塞查询的结果推送新增、移除和更新事件
+//
+// 首次查询到的实例作为新增事件推送; ctx 取消或注册中心关闭后关闭通道
+func (c *ConsulRegistry) WatchTyped(ctx context.Context, service string) <-chan ServiceEvent {
+	c.log.Infof("开始监听consul服务变化: %s", service)
+
+	ch := make(chan ServiceEvent, 16)
+	watchCtx, cancel := c.watchContext(ctx)
+
+	go logger.WithRecover(c.log, func() {
+		defer close(ch)
+		defer cancel()
+
+		var entries []*api.ServiceEntry
+		known := make(map[string]ServiceInstance)
+		c.blockingLoop(watchCtx, service, 0, func(opts *api.QueryOptions) (uint64, error) {
+			var meta *api.QueryMeta
+			var err error
+			entries, meta, err = c.client.Health().Service(service, "", false, opts)
+			if err != nil {
+				return 0, err
+			}
+			return meta.LastIndex, nil
+		}, func() bool {
+			var events []ServiceEvent
+			known, events = diffInstances(known, consulInstances(entries))
+			for _, event := range events {
+				select {
+				case ch <- event:
+				case <-watchCtx.Done():
+					return false
+				}
+			}
+			return true
+		})
+
+		c.log.Infof("停止监听consul服务: %s", service)
+	})
+
+	return ch
+}
+
+// WatchKV 监听键前缀下的键值变化, 首次查询的结果只作为比较基准, 与 etcd 一样只推送之后的变化
+//
+// consul 的键不以 / 开头, 前缀和事件的 Key 都去掉前导 /; ctx 取消或注册中心关闭后关闭通道
+func (c *ConsulRegistry) WatchKV(ctx context.Context, prefix string) <-chan KVEvent {
+	prefix = strings.TrimPrefix(prefix, "/")
+	c.log.Infof("开始监听consul KV变化: %s", prefix)
+
+	ch := make(chan KVEvent, 16)
+	watchCtx, cancel := c.watchContext(ctx)
+
+	go logger.WithRecover(c.log, func() {
+		defer close(ch)
+		defer cancel()
+
+		var pairs api.KVPairs
+		var known map[string]uint64 // 键 -> ModifyIndex
+		c.blockingLoop(watchCtx, prefix, 0, func(opts *api.QueryOptions) (uint64, error) {
+			var meta *api.QueryMeta
+			var err error
+			pairs, meta, err = c.client.KV().List(prefix, opts)
+			if err != nil {
+				return 0, err
+			}
+			return meta.LastIndex, nil
+		}, func() bool {
+			current := make(map[string]uint64, len(pairs))
+			var events []KVEvent
+			for _, pair := range pairs {
+				current[pair.Key] = pair.ModifyIndex
+				if index, ok := known[pair.Key]; known != nil && (!ok || index != pair.ModifyIndex) {
+					events = append(events, KVEvent{Type: KVPut, Key: pair.Key, Value: string(pair.Value)})
+				}
+			}
+			for key := range known {
+				if _, ok := current[key]; !ok {
+					events = append(events, KVEvent{Type: KVDelete, Key: key})
+				}
+			}
+			known = current
+
+			for _, event := range events {
+				select {
+				case ch <- event:
+				case <-watchCtx.Done():
+					return false
+				}
+			}
+			return true
+		})
+
+		c.log.Infof("停止监听consul KV: %s", prefix)
+	})
+
+	return ch
+}
+
+// WatchWithCallback 监听指定前缀的键值变化并执行回调, 注册中心关闭后停止
+func (c *ConsulRegistry) WatchWithCallback(prefix string, callback func(event KVEvent)) {
+	events := c.WatchKV(c.ctx, prefix)
+
+	go logger.WithRecover(c.log, func() {
+		c.log.Infof("启动Watch回调监听，前缀: %s", prefix)
+		for event := range events {
+			c.log.Debugf("收到事件 - 类型: %s, Key: %s, Value: %s", event.Type, event.Key, event.Value)
+			assert.MayTrue(callback != nil, func() {
+				callback(event)
+			})
+		}
+		c.log.Infof("停止Watch监听，前缀: %s", prefix)
+	})
+}
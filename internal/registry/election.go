@@ -0,0 +1,41 @@
+package registry
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrNoLeader 选举没有领导者
+	ErrNoLeader = errors.New("选举没有领导者")
+	// ErrNotLeader 未参与选举或已失去领导权
+	ErrNotLeader = errors.New("未参与选举或已失去领导权")
+)
+
+// Elector 选主, 基于注册中心的会话实现, 进程退出或会话过期时自动让出领导权
+//
+// etcd 与 consul 注册中心实现了该接口, 可通过类型断言使用:
+//
+//	if elector, ok := reg.(registry.Elector); ok { ... }
+type Elector interface {
+	// Campaign 参与选举, 阻塞直到当选或 ctx 取消, value 为当选后公布的值(如服务地址)
+	Campaign(ctx context.Context, election, value string) error
+	// Resign 主动让出领导权
+	Resign(ctx context.Context, election string) error
+	// Leader 获取当前领导者公布的值, 没有领导者时返回 ErrNoLeader
+	Leader(ctx context.Context, election string) (string, error)
+	// ObserveLeader 监听领导者变化, ctx 取消后关闭通道
+	ObserveLeader(ctx context.Context, election string) (<-chan string, error)
+}
+
+// Locker 分布式锁
+type Locker interface {
+	// Lock 加锁, 阻塞直到获得锁或 ctx 取消
+	Lock(ctx context.Context, name string) (Unlocker, error)
+}
+
+// Unlocker 已获得的锁
+type Unlocker interface {
+	// Unlock 释放锁
+	Unlock(ctx context.Context) error
+}
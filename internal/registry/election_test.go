@@ -0,0 +1,158 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// electionRegistry 支持选主和分布式锁的注册中心
+type electionRegistry interface {
+	Elector
+	Locker
+}
+
+// testElection 两个注册中心竞选同一选举: 先到者当选, 让出后另一方接任
+func testElection(t *testing.T, first, second electionRegistry, election string) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := first.Campaign(ctx, election, "node-1"); err != nil {
+		t.Fatalf("第一个节点参与选举失败: %v", err)
+	}
+
+	leader, err := second.Leader(ctx, election)
+	if err != nil || leader != "node-1" {
+		t.Fatalf("领导者应为 node-1, 实际: %q, 错误: %v", leader, err)
+	}
+
+	observeCtx, stopObserve := context.WithCancel(ctx)
+	defer stopObserve()
+	observeChan, err := second.ObserveLeader(observeCtx, election)
+	if err != nil {
+		t.Fatalf("监听领导者失败: %v", err)
+	}
+
+	// 第二个节点阻塞等待
+	elected := make(chan error, 1)
+	go func() {
+		elected <- second.Campaign(ctx, election, "node-2")
+	}()
+
+	select {
+	case err := <-elected:
+		t.Fatalf("第一个节点未让出时不应当选, 错误: %v", err)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	// 更新已当选节点的值
+	if err := first.Campaign(ctx, election, "node-1b"); err != nil {
+		t.Fatalf("更新领导者的值失败: %v", err)
+	}
+
+	if err := first.Resign(ctx, election); err != nil {
+		t.Fatalf("让出领导权失败: %v", err)
+	}
+	if err := first.Resign(ctx, election); !errors.Is(err, ErrNotLeader) {
+		t.Fatalf("重复让出应返回 ErrNotLeader, 实际: %v", err)
+	}
+
+	if err := <-elected; err != nil {
+		t.Fatalf("第二个节点参与选举失败: %v", err)
+	}
+
+	// 监听结果最终应为新领导者
+	for {
+		select {
+		case leader := <-observeChan:
+			if leader == "node-2" {
+				if err := second.Resign(ctx, election); err != nil {
+					t.Fatalf("让出领导权失败: %v", err)
+				}
+				return
+			}
+		case <-ctx.Done():
+			t.Fatal("未监听到新领导者")
+		}
+	}
+}
+
+// testLock 锁在释放前不能被另一方获取
+func testLock(t *testing.T, first, second electionRegistry, name string) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	unlocker, err := first.Lock(ctx, name)
+	if err != nil {
+		t.Fatalf("加锁失败: %v", err)
+	}
+
+	waitCtx, stopWait := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer stopWait()
+	if _, err := second.Lock(waitCtx, name); err == nil {
+		t.Fatal("锁未释放时不应获得锁")
+	}
+
+	if err := unlocker.Unlock(ctx); err != nil {
+		t.Fatalf("释放锁失败: %v", err)
+	}
+
+	unlocker, err = second.Lock(ctx, name)
+	if err != nil {
+		t.Fatalf("释放后加锁失败: %v", err)
+	}
+	if err := unlocker.Unlock(ctx); err != nil {
+		t.Fatalf("释放锁失败: %v", err)
+	}
+}
+
+// TestEtcdRegistry_Election 测试 etcd 选主与分布式锁
+func TestEtcdRegistry_Election(t *testing.T) {
+	first := newTestEtcdRegistry(t)
+	defer first.Close()
+	second := newTestEtcdRegistry(t)
+	defer second.Close()
+	defer cleanupTestData(t, first, "/test/election/")
+
+	if _, err := first.Leader(context.Background(), "/test/election/leader"); !errors.Is(err, ErrNoLeader) {
+		t.Fatalf("没有候选者时应返回 ErrNoLeader, 实际: %v", err)
+	}
+
+	testElection(t, first, second, "/test/election/leader")
+	testLock(t, first, second, "/test/election/lock")
+}
+
+// TestConsulRegistry_Election 测试 consul 选主与分布式锁
+func TestConsulRegistry_Election(t *testing.T) {
+	first := newTestConsulRegistry(t)
+	defer first.Close()
+	second := newTestConsulRegistry(t)
+	defer second.Close()
+	defer cleanupConsulTestData(t, first, "test/election/")
+
+	if !first.IsHealthy() {
+		t.Skip("跳过测试：无法连接到 consul")
+	}
+
+	if _, err := first.Leader(context.Background(), "/test/election/leader"); !errors.Is(err, ErrNoLeader) {
+		t.Fatalf("没有候选者时应返回 ErrNoLeader, 实际: %v", err)
+	}
+
+	testElection(t, first, second, "/test/election/leader")
+	testLock(t, first, second, "/test/election/lock")
+}
+
+// TestConsulLockKey 测试 consul 锁键去掉前导 /
+func TestConsulLockKey(t *testing.T) {
+	if key := consulLockKey("/wsh/moba/master"); key != "wsh/moba/master" {
+		t.Fatalf("期望 wsh/moba/master, 实际: %s", key)
+	}
+	if key := consulLockKey("master"); key != "master" {
+		t.Fatalf("期望 master, 实际: %s", key)
+	}
+}
@@ -83,7 +83,7 @@ func (c *EtcdConfig) Validate() error {
 	if len(c.Hosts) == 0 {
 		return errors.New("etcd的host为空")
 	}
-	if len(c.Key) > 0 {
+	if len(c.Key) == 0 {
 		return errors.New("注册etcd键值不能为空")
 	}
 	return nil
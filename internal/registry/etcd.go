@@ -3,13 +3,17 @@ package registry
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/spelens-gud/assert"
 	"github.com/spelens-gud/logger"
+	"github.com/spelens-gud/trunk/internal/config"
 	"go.etcd.io/etcd/api/v3/mvccpb"
 	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
 )
 
 var (
@@ -29,10 +33,19 @@ type EtcdRegistry struct {
 	cnf           *EtcdConfig                             // registry 配置
 	ctx           context.Context                         // 上下文
 	cancel        context.CancelFunc                      // 取消函数
+	session       *concurrency.Session                    // 选主和分布式锁使用的会话
+	elections     map[string]*concurrency.Election        // 已当选的选举
+	electionLock  sync.Mutex                              // 会话和选举的锁
+	leaseFunc     LeaseStateFunc                          // 租约状态变化回调
+	supervising   bool                                    // 是否已启动租约监听
 }
 
-// 确保 EtcdRegistry 实现了 Registry 接口
-var _ Registry = (*EtcdRegistry)(nil)
+// 确保 EtcdRegistry 实现了 Registry、Elector 和 Locker 接口
+var (
+	_ Registry = (*EtcdRegistry)(nil)
+	_ Elector  = (*EtcdRegistry)(nil)
+	_ Locker   = (*EtcdRegistry)(nil)
+)
 
 // New 创建服务缓存
 func (s *EtcdRegistry) New() {
@@ -72,10 +85,37 @@ func (s *EtcdRegistry) New() {
 func (s *EtcdRegistry) Publisher(value string) {
 	s.lock.Lock()
 	s.val = value
+	leaseID := s.leaseID
 	s.lock.Unlock()
 
-	// 使用配置的租约TTL
-	s.putKeyWithLease(s.cnf.GetLeaseTTL())
+	// 已注册时在原租约下更新值, 如更新注册记录的生命周期状态; 租约失效时重新注册
+	if leaseID != 0 && s.updateValue(leaseID, value) {
+		return
+	}
+
+	// 使用配置的租约TTL, 失败时由租约监听按退避重试
+	if err := s.putKeyWithLease(s.cnf.GetLeaseTTL()); err != nil {
+		s.log.Errorf("注册服务失败，稍后重试: %v", err)
+	} else {
+		s.notifyLeaseState(LeaseRegistered, nil)
+	}
+
+	s.startSuperviseLease()
+}
+
+// updateValue 在原租约下更新注册的值
+func (s *EtcdRegistry) updateValue(leaseID clientv3.LeaseID, value string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultContextTimeout)
+	defer cancel()
+
+	serviceKey := fmt.Sprintf("%s/%d", s.key, leaseID)
+	if _, err := s.cli.Put(ctx, serviceKey, value, clientv3.WithLease(leaseID)); err != nil {
+		s.log.Warnf("更新注册的值失败，重新注册，Key: %s, 错误: %v", serviceKey, err)
+		return false
+	}
+
+	s.log.Infof("更新注册的值成功 - Key: %s, Value: %s", serviceKey, value)
+	return true
 }
 
 // GetCacheClient 获取缓存客户端
@@ -83,6 +123,11 @@ func (s *EtcdRegistry) GetCacheClient() *clientv3.Client {
 	return s.cli
 }
 
+// ConfigSource 复用缓存客户端创建配置源
+func (s *EtcdRegistry) ConfigSource(key string) (config.Source, error) {
+	return config.NewEtcdSource(s.cli, key, s.log), nil
+}
+
 // Put 添加服务(KV分布式缓存)
 func (s *EtcdRegistry) Put(ctx context.Context, key string, val string) {
 	s.log.Infof("put key:%s val:%s", key, val)
@@ -167,37 +212,25 @@ func convertToInterface(opts []clientv3.OpOption) []any {
 
 // GetLeaseID 获取租约ID
 func (s *EtcdRegistry) GetLeaseID() uint64 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
 	return uint64(s.leaseID)
 }
 
-// ListenLeaseRespChan 监听续租情况
+// ListenLeaseRespChan 监听续租情况, 阻塞直到注册中心关闭
+//
+// Publisher 已自动启动租约监听, 租约丢失后按退避重新注册, 无需再调用
 func (s *EtcdRegistry) ListenLeaseRespChan() {
-	s.log.Infof("开始监听租约续约，租约ID: %d", s.leaseID)
-
-	for {
-		select {
-		case resp, ok := <-s.keepAliveChan:
-			if !ok {
-				s.log.Errorf("租约续约通道已关闭，租约ID: %d", s.leaseID)
-				return
-			}
-			if resp == nil {
-				s.log.Errorf("租约续约失败，可能需要重新注册，租约ID: %d", s.leaseID)
-
-				s.Refresh()
-				return
-			}
-			s.log.Debugf("租约续约成功，租约ID: %d, TTL: %d", resp.ID, resp.TTL)
-
-		case <-s.ctx.Done():
-			s.log.Infof("停止监听租约续约，租约ID: %d", s.leaseID)
-			return
-		}
-	}
+	s.startSuperviseLease()
+	<-s.ctx.Done()
 }
 
 // Close 注销服务
 func (s *EtcdRegistry) Close() {
+	// 关闭会话, 让出领导权并释放锁
+	s.closeSession()
+
 	// 取消上下文，停止所有监听
 	assert.MayTrue(s.cancel != nil, func() {
 		s.cancel()
@@ -228,13 +261,16 @@ func (s *EtcdRegistry) Close() {
 	})
 }
 
-// putKeyWithLease 设置租约
-func (s *EtcdRegistry) putKeyWithLease(lease int64) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultContextTimeout)
+// putKeyWithLease 创建租约并注册服务, 成功后撤销旧租约
+func (s *EtcdRegistry) putKeyWithLease(lease int64) error {
+	ctx, cancel := context.WithTimeout(s.ctx, defaultContextTimeout)
 	defer cancel()
 
 	// 创建一个新的租约，并设置ttl时间
-	resp := assert.ShouldCall2RE(s.cli.Grant, ctx, lease, "创建租约失败")
+	resp, err := s.cli.Grant(ctx, lease)
+	if err != nil {
+		return fmt.Errorf("创建租约失败: %w", err)
+	}
 
 	s.lock.RLock()
 	val := s.val
@@ -243,22 +279,33 @@ func (s *EtcdRegistry) putKeyWithLease(lease int64) {
 	// 注册服务并绑定租约
 	serviceKey := fmt.Sprintf("%s/%d", s.key, resp.ID)
 	if _, err := s.cli.Put(ctx, serviceKey, val, clientv3.WithLease(resp.ID)); err != nil {
-		s.log.Errorf("注册服务失败: %v", err)
-		return
+		return fmt.Errorf("注册服务失败: %w", err)
 	}
 
 	// 设置续租 定期发送续约请求
 	// KeepAlive使给定的租约永远有效。如果发布到通道的keepalive响应没有立即被使用，
 	// 则租约客户端将至少每秒钟继续向etcd服务器发送保持活动请求，直到获取最新的响应为止。
 	// registry client会自动发送ttl到etcd server，从而保证该租约一直有效
-	leaseRespChan := assert.ShouldCall2RE(s.cli.KeepAlive, s.ctx, resp.ID, "启动租约续约失败")
+	leaseRespChan, err := s.cli.KeepAlive(s.ctx, resp.ID)
+	if err != nil {
+		return fmt.Errorf("启动租约续约失败: %w", err)
+	}
 
 	s.lock.Lock()
+	oldLeaseID := s.leaseID
 	s.leaseID = resp.ID
 	s.keepAliveChan = leaseRespChan
 	s.lock.Unlock()
 
+	// 撤销旧租约, 停止旧租约的续约并删除旧的注册
+	if oldLeaseID != 0 && oldLeaseID != resp.ID {
+		if _, err := s.cli.Revoke(ctx, oldLeaseID); err != nil {
+			s.log.Debugf("撤销旧租约失败，租约ID: %d, 错误: %v", oldLeaseID, err)
+		}
+	}
+
 	s.log.Infof("服务注册成功 - Key: %s, Value: %s, 租约ID: %d, TTL: %d秒", serviceKey, val, resp.ID, lease)
+	return nil
 }
 
 // Deregister 注销服务
@@ -344,7 +391,10 @@ func (s *EtcdRegistry) Refresh() {
 	})
 
 	// 重新注册
-	s.putKeyWithLease(s.cnf.GetLeaseTTL())
+	if err := s.putKeyWithLease(s.cnf.GetLeaseTTL()); err != nil {
+		s.log.Errorf("刷新服务注册失败: %v", err)
+		return
+	}
 	s.log.Infof("刷新服务注册成功")
 }
 
@@ -361,3 +411,95 @@ func (s *EtcdRegistry) IsHealthy() bool {
 	_, err := s.cli.Get(ctx, s.key, clientv3.WithLimit(1))
 	return err == nil
 }
+
+// etcdServicePrefix 服务实例的键前缀
+func etcdServicePrefix(name string) string {
+	return strings.TrimSuffix(name, "/") + "/"
+}
+
+// etcdInstances 转换键值为服务实例
+func etcdInstances(name, prefix string, kvs []*mvccpb.KeyValue) []ServiceInstance {
+	instances := make([]ServiceInstance, 0, len(kvs))
+	for _, kv := range kvs {
+		instances = append(instances, parseEtcdInstance(name, strings.TrimPrefix(string(kv.Key), prefix), kv.Value))
+	}
+	return instances
+}
+
+// Discover 获取服务的全部实例, name 为服务注册的键(如 /services/gate), 实例注册在 name/租约ID 下
+func (s *EtcdRegistry) Discover(ctx context.Context, name string) ([]ServiceInstance, error) {
+	prefix := etcdServicePrefix(name)
+	resp, err := s.cli.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("获取etcd服务实例失败: %w", err)
+	}
+	return etcdInstances(name, prefix, resp.Kvs), nil
+}
+
+// Subscribe 订阅服务实例变化
+func (s *EtcdRegistry) Subscribe(ctx context.Context, name string) (<-chan []ServiceInstance, error) {
+	prefix := etcdServicePrefix(name)
+	resp, err := s.cli.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("获取etcd服务实例失败: %w", err)
+	}
+
+	// 从快照的下一个版本开始监听, 不丢失中间的变化
+	watchCtx, cancel := context.WithCancel(ctx)
+	watchChan := s.cli.Watch(watchCtx, prefix, clientv3.WithPrefix(), clientv3.WithRev(resp.Header.Revision+1))
+
+	return subscribeKVs(watchCtx, cancel, s.ctx.Done(), s.log, name, prefix, resp.Kvs, watchChan), nil
+}
+
+// subscribeKVs 根据键值快照和后续的监听事件维护服务实例列表, ctx 或 done 结束后关闭通道
+func subscribeKVs(ctx context.Context, cancel context.CancelFunc, done <-chan struct{}, log logger.ILogger,
+	name, prefix string, kvs []*mvccpb.KeyValue, watchChan clientv3.WatchChan) <-chan []ServiceInstance {
+	current := make(map[string]*mvccpb.KeyValue, len(kvs))
+	for _, kv := range kvs {
+		current[string(kv.Key)] = kv
+	}
+
+	ch := make(chan []ServiceInstance, 1)
+	ch <- etcdInstances(name, prefix, kvs)
+
+	go logger.WithRecover(log, func() {
+		defer close(ch)
+		defer cancel()
+
+		for {
+			select {
+			case watchResp, ok := <-watchChan:
+				if !ok {
+					return
+				}
+				if watchResp.Err() != nil {
+					log.Errorf("订阅服务失败，服务: %s, 错误: %v", name, watchResp.Err())
+					continue
+				}
+
+				for _, event := range watchResp.Events {
+					if event.Type == clientv3.EventTypeDelete {
+						delete(current, string(event.Kv.Key))
+					} else {
+						current[string(event.Kv.Key)] = event.Kv
+					}
+				}
+
+				snapshot := make([]*mvccpb.KeyValue, 0, len(current))
+				for _, kv := range current {
+					snapshot = append(snapshot, kv)
+				}
+				sort.Slice(snapshot, func(i, j int) bool { return string(snapshot[i].Key) < string(snapshot[j].Key) })
+
+				if !sendInstances(ctx, ch, etcdInstances(name, prefix, snapshot)) {
+					return
+				}
+
+			case <-done:
+				return
+			}
+		}
+	})
+
+	return ch
+}
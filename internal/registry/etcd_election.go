@@ -0,0 +1,179 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spelens-gud/logger"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+// getSession 获取会话, 会话复用注册中心的客户端, 租约时长与服务注册一致; 会话过期后重新创建
+//
+// 会话使用独立的租约, 不通过 concurrency.WithLease 绑定服务注册的租约: 注册租约在 Refresh、重新 Publisher
+// 和租约恢复时会被替换, 绑定后已当选的选举和持有的锁会随之失效; 关闭会话会撤销租约, 绑定后会连带注销服务
+func (s *EtcdRegistry) getSession() (*concurrency.Session, error) {
+	s.electionLock.Lock()
+	defer s.electionLock.Unlock()
+
+	if s.session != nil {
+		select {
+		case <-s.session.Done():
+			// 租约过期后已当选的选举全部失效
+			s.log.Warnf("etcd会话已过期，租约ID: %d", s.session.Lease())
+			s.session = nil
+			s.elections = nil
+		default:
+			return s.session, nil
+		}
+	}
+
+	if s.cli == nil {
+		return nil, errors.New("etcd v3客户端为空")
+	}
+
+	session, err := concurrency.NewSession(s.cli, concurrency.WithTTL(int(s.cnf.GetLeaseTTL())))
+	if err != nil {
+		return nil, fmt.Errorf("创建etcd会话失败: %w", err)
+	}
+	s.session = session
+	s.elections = make(map[string]*concurrency.Election)
+
+	return session, nil
+}
+
+// closeSession 关闭会话, 撤销会话租约后领导权和锁随之释放
+func (s *EtcdRegistry) closeSession() {
+	s.electionLock.Lock()
+	defer s.electionLock.Unlock()
+
+	if s.session == nil {
+		return
+	}
+	if err := s.session.Close(); err != nil {
+		s.log.Warnf("关闭etcd会话失败: %v", err)
+	}
+	s.session = nil
+	s.elections = nil
+}
+
+// Campaign 参与选举, 已当选时更新公布的值
+func (s *EtcdRegistry) Campaign(ctx context.Context, election, value string) error {
+	session, err := s.getSession()
+	if err != nil {
+		return err
+	}
+
+	s.electionLock.Lock()
+	e, ok := s.elections[election]
+	s.electionLock.Unlock()
+	if ok {
+		if err := e.Proclaim(ctx, value); err != nil {
+			return fmt.Errorf("更新领导者的值失败: %w", err)
+		}
+		return nil
+	}
+
+	e = concurrency.NewElection(session, election)
+	if err := e.Campaign(ctx, value); err != nil {
+		return fmt.Errorf("参与选举失败: %w", err)
+	}
+
+	s.electionLock.Lock()
+	if s.session == session {
+		s.elections[election] = e
+	}
+	s.electionLock.Unlock()
+
+	s.log.Infof("当选领导者，选举: %s, 值: %s", election, value)
+	return nil
+}
+
+// Resign 主动让出领导权
+func (s *EtcdRegistry) Resign(ctx context.Context, election string) error {
+	s.electionLock.Lock()
+	e, ok := s.elections[election]
+	delete(s.elections, election)
+	s.electionLock.Unlock()
+
+	if !ok {
+		return ErrNotLeader
+	}
+	if err := e.Resign(ctx); err != nil {
+		return fmt.Errorf("让出领导权失败: %w", err)
+	}
+
+	s.log.Infof("让出领导权，选举: %s", election)
+	return nil
+}
+
+// Leader 获取当前领导者公布的值
+func (s *EtcdRegistry) Leader(ctx context.Context, election string) (string, error) {
+	session, err := s.getSession()
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := concurrency.NewElection(session, election).Leader(ctx)
+	if errors.Is(err, concurrency.ErrElectionNoLeader) {
+		return "", ErrNoLeader
+	}
+	if err != nil {
+		return "", fmt.Errorf("获取领导者失败: %w", err)
+	}
+	return string(resp.Kvs[0].Value), nil
+}
+
+// ObserveLeader 监听领导者变化
+func (s *EtcdRegistry) ObserveLeader(ctx context.Context, election string) (<-chan string, error) {
+	session, err := s.getSession()
+	if err != nil {
+		return nil, err
+	}
+
+	observeChan := concurrency.NewElection(session, election).Observe(ctx)
+	ch := make(chan string)
+	go logger.WithRecover(s.log, func() {
+		defer close(ch)
+		for resp := range observeChan {
+			if len(resp.Kvs) == 0 {
+				continue
+			}
+			select {
+			case ch <- string(resp.Kvs[0].Value):
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+
+	return ch, nil
+}
+
+// Lock 加锁, 会话过期时锁自动释放
+func (s *EtcdRegistry) Lock(ctx context.Context, name string) (Unlocker, error) {
+	session, err := s.getSession()
+	if err != nil {
+		return nil, err
+	}
+
+	mutex := concurrency.NewMutex(session, name)
+	if err := mutex.Lock(ctx); err != nil {
+		return nil, fmt.Errorf("加锁失败: %w", err)
+	}
+	return etcdUnlocker{mutex: mutex}, nil
+}
+
+// etcdUnlocker etcd 分布式锁
+type etcdUnlocker struct {
+	mutex *concurrency.Mutex
+}
+
+// Unlock 释放锁
+func (u etcdUnlocker) Unlock(ctx context.Context) error {
+	if err := u.mutex.Unlock(ctx); err != nil {
+		return fmt.Errorf("释放锁失败: %w", err)
+	}
+	return nil
+}
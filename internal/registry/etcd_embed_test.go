@@ -0,0 +1,172 @@
+//go:build integration
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/spelens-gud/assert"
+	"github.com/spelens-gud/logger"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+// freeURL 获取本机空闲端口的地址
+func freeURL(t *testing.T) url.URL {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("获取空闲端口失败: %v", err)
+	}
+	defer l.Close()
+
+	return url.URL{Scheme: "http", Host: l.Addr().String()}
+}
+
+// startEmbedEtcd 启动内嵌的单节点 etcd, 相同的 dir 和地址重启时沿用原有数据
+func startEmbedEtcd(t *testing.T, dir string, clientURL, peerURL url.URL) *embed.Etcd {
+	t.Helper()
+
+	cfg := embed.NewConfig()
+	cfg.Name = "registry-test"
+	cfg.Dir = dir
+	cfg.LogLevel = "error"
+	cfg.ListenClientUrls = []url.URL{clientURL}
+	cfg.AdvertiseClientUrls = []url.URL{clientURL}
+	cfg.ListenPeerUrls = []url.URL{peerURL}
+	cfg.AdvertisePeerUrls = []url.URL{peerURL}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+
+	e, err := embed.StartEtcd(cfg)
+	if err != nil {
+		t.Fatalf("启动内嵌 etcd 失败: %v", err)
+	}
+
+	select {
+	case <-e.Server.ReadyNotify():
+	case err := <-e.Err():
+		e.Close()
+		t.Fatalf("内嵌 etcd 运行失败: %v", err)
+	case <-time.After(10 * time.Second):
+		e.Close()
+		t.Fatal("等待内嵌 etcd 就绪超时")
+	}
+	return e
+}
+
+// waitLeaseState 等待指定的租约状态, 跳过之前的其他状态
+func waitLeaseState(t *testing.T, states <-chan LeaseState, want LeaseState, timeout time.Duration) {
+	t.Helper()
+
+	deadline := time.After(timeout)
+	for {
+		select {
+		case state := <-states:
+			if state == want {
+				return
+			}
+		case <-deadline:
+			t.Fatalf("等待状态 %s 超时", want)
+		}
+	}
+}
+
+// TestEtcdRegistry_EmbedLease 基于内嵌的 etcd 测试租约被撤销、过期后重新申请租约并注册
+//
+// 运行: go test -tags integration -run TestEtcdRegistry_EmbedLease ./internal/registry
+func TestEtcdRegistry_EmbedLease(t *testing.T) {
+	log, err := logger.NewLogger(logger.DefaultConfig())
+	if err != nil {
+		t.Fatalf("创建 logger 失败: %v", err)
+	}
+	assert.SetLogger(log)
+
+	dir := t.TempDir()
+	clientURL, peerURL := freeURL(t), freeURL(t)
+	e := startEmbedEtcd(t, dir, clientURL, peerURL)
+	defer func() { e.Close() }()
+
+	reg, err := NewRegistryFactory(log).CreateEtcdRegistry(&EtcdConfig{
+		Hosts:    []string{clientURL.Host},
+		Key:      "/services/gate",
+		LeaseTTL: 2,
+	})
+	if err != nil {
+		t.Fatalf("创建注册中心失败: %v", err)
+	}
+	defer reg.Close()
+	registry := reg.(*EtcdRegistry)
+
+	states := make(chan LeaseState, 16)
+	registry.SetLeaseStateFunc(func(state LeaseState, err error) {
+		states <- state
+	})
+
+	cli, err := clientv3.New(clientv3.Config{Endpoints: []string{clientURL.Host}, DialTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("创建 etcd 客户端失败: %v", err)
+	}
+	defer cli.Close()
+
+	ctx := context.Background()
+	// registered 返回租约下注册的值
+	registered := func(leaseID uint64) string {
+		t.Helper()
+
+		reqCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+		defer cancel()
+		resp, err := cli.Get(reqCtx, fmt.Sprintf("/services/gate/%d", leaseID))
+		if err != nil {
+			t.Fatalf("读取注册失败: %v", err)
+		}
+		if len(resp.Kvs) == 0 {
+			return ""
+		}
+		return string(resp.Kvs[0].Value)
+	}
+
+	registry.Publisher("127.0.0.1:9001")
+	waitLeaseState(t, states, LeaseRegistered, 5*time.Second)
+	first := registry.GetLeaseID()
+	if value := registered(first); value != "127.0.0.1:9001" {
+		t.Fatalf("期望注册在租约 %d 下, 实际 %q", first, value)
+	}
+
+	// 租约被撤销, 注册随租约删除后重新申请租约
+	if _, err := cli.Revoke(ctx, clientv3.LeaseID(first)); err != nil {
+		t.Fatalf("撤销租约失败: %v", err)
+	}
+	waitLeaseState(t, states, LeaseLost, 5*time.Second)
+	waitLeaseState(t, states, LeaseRegistered, 10*time.Second)
+	second := registry.GetLeaseID()
+	if second == first || registered(second) != "127.0.0.1:9001" {
+		t.Fatalf("撤销后期望注册在新租约下, 原租约 %d, 新租约 %d", first, second)
+	}
+
+	// etcd 不可用超过 TTL, 续约超时后租约过期, 恢复后重新申请租约
+	e.Close()
+	waitLeaseState(t, states, LeaseLost, 10*time.Second)
+	waitLeaseState(t, states, LeaseRecovering, time.Second)
+
+	e = startEmbedEtcd(t, dir, clientURL, peerURL)
+	waitLeaseState(t, states, LeaseRegistered, 40*time.Second)
+	third := registry.GetLeaseID()
+	if third == second || registered(third) != "127.0.0.1:9001" {
+		t.Fatalf("恢复后期望注册在新租约下, 原租约 %d, 新租约 %d", second, third)
+	}
+
+	// 没有续约的旧租约在服务端过期, 旧注册被删除
+	deadline := time.Now().Add(15 * time.Second)
+	for registered(second) != "" {
+		if time.Now().After(deadline) {
+			t.Fatalf("旧租约 %d 未过期", second)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
@@ -0,0 +1,154 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spelens-gud/logger"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// 租约丢失后重新注册的退避时间
+const (
+	minLeaseBackoff = time.Second
+	maxLeaseBackoff = 30 * time.Second
+)
+
+// errNotRegistered 尚未注册成功
+var errNotRegistered = errors.New("service not registered")
+
+// LeaseState etcd 注册租约的状态
+type LeaseState int
+
+const (
+	LeaseRegistered LeaseState = iota // 已注册, 租约正常续约
+	LeaseLost                         // 租约丢失, 服务已不在注册中心
+	LeaseRecovering                   // 正在重新创建租约并注册
+)
+
+// String 状态名称
+func (s LeaseState) String() string {
+	switch s {
+	case LeaseRegistered:
+		return "registered"
+	case LeaseLost:
+		return "lost"
+	case LeaseRecovering:
+		return "recovering"
+	}
+	return fmt.Sprintf("LeaseState(%d)", int(s))
+}
+
+// LeaseStateFunc 租约状态变化回调, 租约丢失时 err 为丢失的原因
+type LeaseStateFunc func(state LeaseState, err error)
+
+// SetLeaseStateFunc 设置租约状态变化回调, 在 Publisher 之前设置
+func (s *EtcdRegistry) SetLeaseStateFunc(f LeaseStateFunc) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.leaseFunc = f
+}
+
+// notifyLeaseState 通知租约状态变化
+func (s *EtcdRegistry) notifyLeaseState(state LeaseState, err error) {
+	s.lock.RLock()
+	f := s.leaseFunc
+	s.lock.RUnlock()
+
+	if f != nil {
+		f(state, err)
+	}
+}
+
+// startSuperviseLease 启动租约监听, 重复调用时忽略
+func (s *EtcdRegistry) startSuperviseLease() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.supervising {
+		return
+	}
+	s.supervising = true
+
+	go logger.WithRecover(s.log, s.superviseLease)
+}
+
+// superviseLease 监听当前租约的续约, 租约丢失后按指数退避重新注册, 直到注册中心关闭
+//
+// 续约通道在租约过期、被撤销或 etcd 不可用超过 TTL 时关闭, 此时注册已随租约删除
+func (s *EtcdRegistry) superviseLease() {
+	for {
+		s.lock.RLock()
+		leaseID, ch := s.leaseID, s.keepAliveChan
+		s.lock.RUnlock()
+
+		err := errNotRegistered
+		if ch != nil {
+			if !s.waitLease(leaseID, ch) {
+				return
+			}
+			err = fmt.Errorf("租约 %d 续约通道已关闭", leaseID)
+		}
+
+		// 已被 Publisher 或 Refresh 替换为新租约时监听新租约
+		s.lock.RLock()
+		replaced := s.leaseID != leaseID
+		s.lock.RUnlock()
+		if replaced {
+			continue
+		}
+
+		s.log.Errorf("租约丢失，开始重新注册，租约ID: %d, 原因: %v", leaseID, err)
+		s.notifyLeaseState(LeaseLost, err)
+
+		if !s.recoverLease() {
+			return
+		}
+	}
+}
+
+// waitLease 等待续约通道关闭, 注册中心关闭时返回 false
+func (s *EtcdRegistry) waitLease(leaseID clientv3.LeaseID, ch <-chan *clientv3.LeaseKeepAliveResponse) bool {
+	s.log.Infof("开始监听租约续约，租约ID: %d", leaseID)
+
+	for {
+		select {
+		case resp, ok := <-ch:
+			if !ok || resp == nil {
+				return true
+			}
+			s.log.Debugf("租约续约成功，租约ID: %d, TTL: %d", resp.ID, resp.TTL)
+
+		case <-s.ctx.Done():
+			s.log.Infof("停止监听租约续约，租约ID: %d", leaseID)
+			return false
+		}
+	}
+}
+
+// recoverLease 创建新租约重新注册, 失败时按指数退避重试, 注册中心关闭时返回 false
+func (s *EtcdRegistry) recoverLease() bool {
+	s.notifyLeaseState(LeaseRecovering, nil)
+
+	backoff := minLeaseBackoff
+	for {
+		err := s.putKeyWithLease(s.cnf.GetLeaseTTL())
+		if err == nil {
+			s.notifyLeaseState(LeaseRegistered, nil)
+			return true
+		}
+		if s.ctx.Err() != nil {
+			return false
+		}
+
+		s.log.Warnf("重新注册失败，%s 后重试: %v", backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-s.ctx.Done():
+			return false
+		}
+		backoff = min(backoff*2, maxLeaseBackoff)
+	}
+}
@@ -0,0 +1,184 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spelens-gud/assert"
+	"github.com/spelens-gud/logger"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// fakeEtcd 模拟 etcd 的租约和键值, down 为 true 时模拟集群不可用
+type fakeEtcd struct {
+	clientv3.KV
+	clientv3.Lease
+
+	mu      sync.Mutex
+	down    bool
+	nextID  clientv3.LeaseID
+	leases  map[clientv3.LeaseID]chan *clientv3.LeaseKeepAliveResponse
+	entries map[string]string
+}
+
+func newFakeEtcd() *fakeEtcd {
+	return &fakeEtcd{
+		leases:  make(map[clientv3.LeaseID]chan *clientv3.LeaseKeepAliveResponse),
+		entries: make(map[string]string),
+	}
+}
+
+var errFakeDown = errors.New("etcd 不可用")
+
+func (f *fakeEtcd) setDown(down bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.down = down
+}
+
+func (f *fakeEtcd) Grant(ctx context.Context, ttl int64) (*clientv3.LeaseGrantResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down {
+		return nil, errFakeDown
+	}
+	f.nextID++
+	f.leases[f.nextID] = make(chan *clientv3.LeaseKeepAliveResponse, 1)
+	return &clientv3.LeaseGrantResponse{ID: f.nextID, TTL: ttl}, nil
+}
+
+func (f *fakeEtcd) KeepAlive(ctx context.Context, id clientv3.LeaseID) (<-chan *clientv3.LeaseKeepAliveResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ch, ok := f.leases[id]
+	if !ok {
+		return nil, fmt.Errorf("租约 %d 不存在", id)
+	}
+	return ch, nil
+}
+
+func (f *fakeEtcd) Revoke(ctx context.Context, id clientv3.LeaseID) (*clientv3.LeaseRevokeResponse, error) {
+	f.expire(id)
+	return &clientv3.LeaseRevokeResponse{}, nil
+}
+
+// expire 租约过期, 关闭续约通道并删除绑定的键
+func (f *fakeEtcd) expire(id clientv3.LeaseID) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if ch, ok := f.leases[id]; ok {
+		close(ch)
+		delete(f.leases, id)
+	}
+	for key := range f.entries {
+		if strings.HasSuffix(key, fmt.Sprintf("/%d", id)) {
+			delete(f.entries, key)
+		}
+	}
+}
+
+func (f *fakeEtcd) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down {
+		return nil, errFakeDown
+	}
+	f.entries[key] = val
+	return &clientv3.PutResponse{}, nil
+}
+
+func (f *fakeEtcd) Delete(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.entries, key)
+	return &clientv3.DeleteResponse{}, nil
+}
+
+func (f *fakeEtcd) get(key string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	val, ok := f.entries[key]
+	return val, ok
+}
+
+// expectLeaseState 等待租约状态回调
+func expectLeaseState(t *testing.T, states <-chan LeaseState, want LeaseState) {
+	t.Helper()
+
+	select {
+	case state := <-states:
+		if state != want {
+			t.Fatalf("期望状态 %s, 实际 %s", want, state)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("等待状态 %s 超时", want)
+	}
+}
+
+// TestEtcdRegistry_SuperviseLease 测试租约丢失后按退避重新注册, 并通过回调通知状态变化
+func TestEtcdRegistry_SuperviseLease(t *testing.T) {
+	log, err := logger.NewLogger(logger.DefaultConfig())
+	if err != nil {
+		t.Fatalf("创建 logger 失败: %v", err)
+	}
+	assert.SetLogger(log)
+
+	fake := newFakeEtcd()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	registry := &EtcdRegistry{
+		cli:    &clientv3.Client{KV: fake, Lease: fake},
+		cnf:    &EtcdConfig{Key: "/services/gate", LeaseTTL: 1},
+		key:    "/services/gate",
+		log:    log,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	states := make(chan LeaseState, 10)
+	registry.SetLeaseStateFunc(func(state LeaseState, err error) {
+		states <- state
+	})
+
+	registry.Publisher("127.0.0.1:9001")
+	expectLeaseState(t, states, LeaseRegistered)
+	if val, ok := fake.get("/services/gate/1"); !ok || val != "127.0.0.1:9001" {
+		t.Fatalf("期望注册在租约 1 下, 实际 %q, %v", val, ok)
+	}
+
+	// 集群不可用超过 TTL, 租约过期
+	fake.setDown(true)
+	fake.expire(1)
+	expectLeaseState(t, states, LeaseLost)
+	expectLeaseState(t, states, LeaseRecovering)
+
+	// 恢复后重新注册
+	time.Sleep(500 * time.Millisecond)
+	fake.setDown(false)
+	expectLeaseState(t, states, LeaseRegistered)
+	if registry.GetLeaseID() != 2 {
+		t.Fatalf("期望新租约 2, 实际 %d", registry.GetLeaseID())
+	}
+	if val, ok := fake.get("/services/gate/2"); !ok || val != "127.0.0.1:9001" {
+		t.Fatalf("期望重新注册在租约 2 下, 实际 %q, %v", val, ok)
+	}
+
+	// Refresh 替换租约不视为丢失
+	registry.Refresh()
+	if _, ok := fake.get("/services/gate/3"); !ok {
+		t.Fatal("刷新后应注册在租约 3 下")
+	}
+	select {
+	case state := <-states:
+		t.Fatalf("刷新不应通知状态变化, 实际 %s", state)
+	case <-time.After(300 * time.Millisecond):
+	}
+	if _, ok := fake.get("/services/gate/2"); ok {
+		t.Error("刷新后应撤销旧租约")
+	}
+}
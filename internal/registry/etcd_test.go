@@ -334,6 +334,57 @@ func TestEtcdRegistry_WatchTyped(t *testing.T) {
 	}
 }
 
+// TestEtcdRegistry_DiscoverAndSubscribe 测试服务发现与订阅
+func TestEtcdRegistry_DiscoverAndSubscribe(t *testing.T) {
+	registry := newTestEtcdRegistry(t)
+	defer registry.Close()
+	defer cleanupTestData(t, registry, "/test/discover/")
+
+	name := "/test/discover/gate"
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	registry.Put(ctx, name+"/1", "127.0.0.1:9001")
+
+	instances, err := registry.Discover(ctx, name)
+	if err != nil {
+		t.Fatalf("服务发现失败: %v", err)
+	}
+	if len(instances) != 1 || instances[0].Port != 9001 || instances[0].ID != "1" {
+		t.Fatalf("服务实例错误: %+v", instances)
+	}
+
+	ch, err := registry.Subscribe(ctx, name)
+	if err != nil {
+		t.Fatalf("订阅失败: %v", err)
+	}
+	if initial := <-ch; len(initial) != 1 {
+		t.Fatalf("初始实例数错误: %+v", initial)
+	}
+
+	registry.Put(ctx, name+"/2", "127.0.0.1:9002")
+
+	select {
+	case got := <-ch:
+		if len(got) != 2 {
+			t.Errorf("期望 2 个实例, 实际 %+v", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("超时：未收到实例变化")
+	}
+
+	// 取消后关闭通道
+	cancel()
+	select {
+	case _, ok := <-ch:
+		for ok {
+			_, ok = <-ch
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("超时：取消订阅后通道未关闭")
+	}
+}
+
 // TestEtcdRegistry_Deregister 测试服务注销
 func TestEtcdRegistry_Deregister(t *testing.T) {
 	registry := newTestEtcdRegistry(t)
@@ -437,6 +488,39 @@ func TestEtcdRegistry_Refresh(t *testing.T) {
 	}
 }
 
+// TestEtcdRegistry_LeaseRecovery 测试租约被撤销后自动重新注册
+func TestEtcdRegistry_LeaseRecovery(t *testing.T) {
+	registry := newTestEtcdRegistry(t)
+	defer registry.Close()
+
+	states := make(chan LeaseState, 10)
+	registry.SetLeaseStateFunc(func(state LeaseState, err error) {
+		states <- state
+	})
+
+	registry.Publisher("test-service")
+	expectLeaseState(t, states, LeaseRegistered)
+	oldLeaseID := clientv3.LeaseID(registry.GetLeaseID())
+
+	// 撤销租约, 模拟租约过期
+	ctx := context.Background()
+	if _, err := registry.cli.Revoke(ctx, oldLeaseID); err != nil {
+		t.Fatalf("撤销租约失败: %v", err)
+	}
+	expectLeaseState(t, states, LeaseLost)
+	expectLeaseState(t, states, LeaseRecovering)
+	expectLeaseState(t, states, LeaseRegistered)
+
+	newLeaseID := clientv3.LeaseID(registry.GetLeaseID())
+	if newLeaseID == oldLeaseID {
+		t.Fatal("重新注册后租约ID应该改变")
+	}
+	resp, err := registry.cli.Get(ctx, fmt.Sprintf("%s/%d", registry.key, newLeaseID))
+	if err != nil || len(resp.Kvs) == 0 || string(resp.Kvs[0].Value) != "test-service" {
+		t.Fatalf("重新注册的服务不存在, resp=%v, err=%v", resp, err)
+	}
+}
+
 // TestEtcdRegistry_IsHealthy 测试健康检查
 func TestEtcdRegistry_IsHealthy(t *testing.T) {
 	registry := newTestEtcdRegistry(t)
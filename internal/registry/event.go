@@ -0,0 +1,87 @@
+package registry
+
+import "reflect"
+
+// KVEventType 键值变化类型
+type KVEventType int
+
+const (
+	// KVPut 创建或更新
+	KVPut KVEventType = iota
+	// KVDelete 删除
+	KVDelete
+)
+
+// String 变化类型名称
+func (t KVEventType) String() string {
+	switch t {
+	case KVPut:
+		return "PUT"
+	case KVDelete:
+		return "DELETE"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// KVEvent 键值变化事件, 用于不使用 etcd 类型的注册中心
+type KVEvent struct {
+	Type  KVEventType // 变化类型
+	Key   string      // 键
+	Value string      // 值, 删除时为空
+}
+
+// ServiceEventType 服务实例变化类型
+type ServiceEventType int
+
+const (
+	// ServiceAdded 新增实例
+	ServiceAdded ServiceEventType = iota
+	// ServiceRemoved 移除实例
+	ServiceRemoved
+	// ServiceUpdated 实例的地址、权重、元数据或健康状态变化
+	ServiceUpdated
+)
+
+// String 变化类型名称
+func (t ServiceEventType) String() string {
+	switch t {
+	case ServiceAdded:
+		return "ADDED"
+	case ServiceRemoved:
+		return "REMOVED"
+	case ServiceUpdated:
+		return "UPDATED"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// ServiceEvent 服务实例变化事件
+type ServiceEvent struct {
+	Type     ServiceEventType // 变化类型
+	Instance ServiceInstance  // 变化后的实例, 移除时为移除前的实例
+}
+
+// diffInstances 按实例 ID 比较前后两次的实例列表, 得到变化事件
+func diffInstances(old map[string]ServiceInstance, instances []ServiceInstance) (map[string]ServiceInstance, []ServiceEvent) {
+	current := make(map[string]ServiceInstance, len(instances))
+	var events []ServiceEvent
+	for _, instance := range instances {
+		current[instance.ID] = instance
+
+		prev, ok := old[instance.ID]
+		switch {
+		case !ok:
+			events = append(events, ServiceEvent{Type: ServiceAdded, Instance: instance})
+		case !reflect.DeepEqual(prev, instance):
+			events = append(events, ServiceEvent{Type: ServiceUpdated, Instance: instance})
+		}
+	}
+	for id, instance := range old {
+		if _, ok := current[id]; !ok {
+			events = append(events, ServiceEvent{Type: ServiceRemoved, Instance: instance})
+		}
+	}
+	return current, events
+}
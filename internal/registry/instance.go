@@ -0,0 +1,116 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/nacos-group/nacos-sdk-go/v2/model"
+)
+
+// ServiceInstance 服务实例, 与具体注册中心无关
+type ServiceInstance struct {
+	ID       string            `json:"id"`                 // 实例ID
+	Name     string            `json:"name"`               // 服务名称
+	Address  string            `json:"address"`            // 地址
+	Port     int               `json:"port"`               // 端口
+	Weight   float64           `json:"weight"`             // 权重
+	Metadata map[string]string `json:"metadata,omitempty"` // 元数据
+	Healthy  bool              `json:"healthy"`            // 是否健康
+}
+
+// Endpoint 获取 host:port 形式的地址
+func (s ServiceInstance) Endpoint() string {
+	return net.JoinHostPort(s.Address, strconv.Itoa(s.Port))
+}
+
+// Discovery 服务发现
+type Discovery interface {
+	// Discover 获取服务的全部实例
+	Discover(ctx context.Context, name string) ([]ServiceInstance, error)
+	// Subscribe 订阅服务实例变化, 每次变化推送完整的实例列表; ctx 取消后关闭通道
+	Subscribe(ctx context.Context, name string) (<-chan []ServiceInstance, error)
+}
+
+// sendInstances 推送最新的实例列表, 消费方未取走的旧列表会被丢弃
+func sendInstances(ctx context.Context, ch chan []ServiceInstance, instances []ServiceInstance) bool {
+	select {
+	case <-ch:
+	default:
+	}
+
+	select {
+	case ch <- instances:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// parseEtcdInstance 解析 etcd 中注册的值, 支持 ServiceInstance 的 JSON 或 host:port
+func parseEtcdInstance(name, id string, value []byte) ServiceInstance {
+	instance := ServiceInstance{}
+
+	if err := json.Unmarshal(value, &instance); err != nil {
+		instance = ServiceInstance{}
+		raw := strings.TrimSpace(string(value))
+		if host, port, err := net.SplitHostPort(raw); err == nil {
+			instance.Address = host
+			instance.Port, _ = strconv.Atoi(port)
+		} else {
+			instance.Address = raw
+		}
+		// 租约有效即视为健康
+		instance.Healthy = true
+	}
+
+	if instance.ID == "" {
+		instance.ID = id
+	}
+	if instance.Name == "" {
+		instance.Name = name
+	}
+	if instance.Weight <= 0 {
+		instance.Weight = 1
+	}
+	return instance
+}
+
+// consulInstance 转换 consul 服务实例
+func consulInstance(entry *api.ServiceEntry) ServiceInstance {
+	address := entry.Service.Address
+	if address == "" && entry.Node != nil {
+		address = entry.Node.Address
+	}
+
+	weight := float64(entry.Service.Weights.Passing)
+	if weight <= 0 {
+		weight = 1
+	}
+
+	return ServiceInstance{
+		ID:       entry.Service.ID,
+		Name:     entry.Service.Service,
+		Address:  address,
+		Port:     entry.Service.Port,
+		Weight:   weight,
+		Metadata: entry.Service.Meta,
+		Healthy:  entry.Checks.AggregatedStatus() == api.HealthPassing,
+	}
+}
+
+// nacosInstance 转换 nacos 服务实例
+func nacosInstance(name string, instance model.Instance) ServiceInstance {
+	return ServiceInstance{
+		ID:       instance.InstanceId,
+		Name:     name,
+		Address:  instance.Ip,
+		Port:     int(instance.Port),
+		Weight:   instance.Weight,
+		Metadata: instance.Metadata,
+		Healthy:  instance.Healthy && instance.Enable,
+	}
+}
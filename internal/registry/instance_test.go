@@ -0,0 +1,128 @@
+package registry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/nacos-group/nacos-sdk-go/v2/model"
+)
+
+// TestParseEtcdInstance 测试解析 etcd 注册值
+func TestParseEtcdInstance(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  ServiceInstance
+	}{
+		{
+			name:  "host:port",
+			value: "192.168.1.100:8080",
+			want:  ServiceInstance{ID: "123", Name: "/services/gate", Address: "192.168.1.100", Port: 8080, Weight: 1, Healthy: true},
+		},
+		{
+			name:  "仅地址",
+			value: "gate-1",
+			want:  ServiceInstance{ID: "123", Name: "/services/gate", Address: "gate-1", Weight: 1, Healthy: true},
+		},
+		{
+			name:  "JSON",
+			value: `{"id":"gate-1","name":"gate","address":"10.0.0.1","port":9000,"weight":5,"healthy":true,"metadata":{"zone":"a"}}`,
+			want:  ServiceInstance{ID: "gate-1", Name: "gate", Address: "10.0.0.1", Port: 9000, Weight: 5, Healthy: true, Metadata: map[string]string{"zone": "a"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseEtcdInstance("/services/gate", "123", []byte(tt.value))
+			if got.ID != tt.want.ID || got.Name != tt.want.Name || got.Address != tt.want.Address ||
+				got.Port != tt.want.Port || got.Weight != tt.want.Weight || got.Healthy != tt.want.Healthy ||
+				got.Metadata["zone"] != tt.want.Metadata["zone"] {
+				t.Errorf("期望 %+v, 实际 %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestConsulInstance 测试转换 consul 服务实例
+func TestConsulInstance(t *testing.T) {
+	entry := &api.ServiceEntry{
+		Node: &api.Node{Address: "10.0.0.2"},
+		Service: &api.AgentService{
+			ID:      "gate-1",
+			Service: "gate",
+			Port:    9000,
+			Meta:    map[string]string{"zone": "a"},
+			Weights: api.AgentWeights{Passing: 3},
+		},
+		Checks: api.HealthChecks{{Status: api.HealthCritical}},
+	}
+
+	got := consulInstance(entry)
+	if got.ID != "gate-1" || got.Name != "gate" || got.Address != "10.0.0.2" || got.Port != 9000 || got.Weight != 3 {
+		t.Errorf("转换结果错误: %+v", got)
+	}
+	if got.Healthy {
+		t.Error("健康检查失败的实例不应标记为健康")
+	}
+	if got.Endpoint() != "10.0.0.2:9000" {
+		t.Errorf("期望 10.0.0.2:9000, 实际 %s", got.Endpoint())
+	}
+}
+
+// TestNacosInstance 测试转换 nacos 服务实例
+func TestNacosInstance(t *testing.T) {
+	got := nacosInstance("gate", model.Instance{InstanceId: "1", Ip: "10.0.0.3", Port: 9000, Weight: 2, Healthy: true, Enable: false})
+	if got.ID != "1" || got.Name != "gate" || got.Address != "10.0.0.3" || got.Port != 9000 || got.Weight != 2 {
+		t.Errorf("转换结果错误: %+v", got)
+	}
+	if got.Healthy {
+		t.Error("未启用的实例不应标记为健康")
+	}
+}
+
+// TestSendInstances 测试推送时丢弃未被消费的旧列表
+func TestSendInstances(t *testing.T) {
+	ch := make(chan []ServiceInstance, 1)
+	sendInstances(context.Background(), ch, []ServiceInstance{{ID: "old"}})
+	sendInstances(context.Background(), ch, []ServiceInstance{{ID: "new"}})
+
+	got := <-ch
+	if len(got) != 1 || got[0].ID != "new" {
+		t.Errorf("期望最新列表, 实际 %+v", got)
+	}
+}
+
+// TestDiffInstances 测试比较前后两次的实例列表
+func TestDiffInstances(t *testing.T) {
+	a := ServiceInstance{ID: "a", Address: "10.0.0.1", Port: 8080, Healthy: true}
+	b := ServiceInstance{ID: "b", Address: "10.0.0.2", Port: 8080, Healthy: true}
+	c := ServiceInstance{ID: "c", Address: "10.0.0.3", Port: 8080, Healthy: true}
+
+	known, events := diffInstances(nil, []ServiceInstance{a, b})
+	if len(events) != 2 || events[0].Type != ServiceAdded || events[1].Type != ServiceAdded {
+		t.Fatalf("首次比较应全部为新增, 实际: %+v", events)
+	}
+
+	unhealthy := b
+	unhealthy.Healthy = false
+	known, events = diffInstances(known, []ServiceInstance{unhealthy, c})
+
+	got := make(map[string]ServiceEventType)
+	for _, event := range events {
+		got[event.Instance.ID] = event.Type
+	}
+	want := map[string]ServiceEventType{"a": ServiceRemoved, "b": ServiceUpdated, "c": ServiceAdded}
+	if len(got) != len(want) {
+		t.Fatalf("期望 %v, 实际 %v", want, got)
+	}
+	for id, eventType := range want {
+		if got[id] != eventType {
+			t.Errorf("实例 %s 期望 %s, 实际 %s", id, eventType, got[id])
+		}
+	}
+
+	if _, events = diffInstances(known, []ServiceInstance{unhealthy, c}); len(events) != 0 {
+		t.Errorf("实例未变化不应有事件, 实际: %+v", events)
+	}
+}
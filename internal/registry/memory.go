@@ -0,0 +1,280 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/spelens-gud/assert"
+	"github.com/spelens-gud/logger"
+	"github.com/spelens-gud/trunk/internal/config"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// InMemoryRegistry 内存注册中心实现, 语义与 EtcdRegistry 一致, 用于测试和单进程部署
+type InMemoryRegistry struct {
+	store           *MemoryStore       // 存储
+	leaseID         clientv3.LeaseID   // 租约ID
+	keepAliveCancel context.CancelFunc // 停止续约
+	key             string             // key
+	val             string             // value
+	closed          bool               // 是否已关闭
+	lock            sync.RWMutex       // 读写锁
+	log             logger.ILogger     // 日志句柄
+	cnf             *MemoryConfig      // registry 配置
+	ctx             context.Context    // 上下文
+	cancel          context.CancelFunc // 取消函数
+}
+
+// 确保 InMemoryRegistry 实现了 Registry 接口
+var _ Registry = (*InMemoryRegistry)(nil)
+
+// New 初始化
+func (m *InMemoryRegistry) New() {
+	m.store = m.cnf.GetStore()
+	m.key = m.cnf.Key
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+
+	m.log.Infof("内存注册中心创建成功")
+}
+
+// GetStore 获取存储
+func (m *InMemoryRegistry) GetStore() *MemoryStore {
+	return m.store
+}
+
+// Publisher 发布服务
+func (m *InMemoryRegistry) Publisher(value string) {
+	m.lock.Lock()
+	m.val = value
+	leaseID := m.leaseID
+	m.lock.Unlock()
+
+	// 已注册时在原租约下更新值, 租约失效时重新注册
+	if leaseID != 0 {
+		serviceKey := fmt.Sprintf("%s/%d", m.key, leaseID)
+		if err := m.store.put(serviceKey, value, leaseID); err == nil {
+			m.log.Infof("更新注册的值成功 - Key: %s, Value: %s", serviceKey, value)
+			return
+		}
+	}
+
+	m.putKeyWithLease(m.cnf.GetLeaseTTL())
+}
+
+// putKeyWithLease 创建租约并注册服务
+func (m *InMemoryRegistry) putKeyWithLease(lease int64) {
+	ttl := time.Duration(lease) * time.Second
+	leaseID := m.store.grant(ttl)
+
+	m.lock.Lock()
+	val := m.val
+	serviceKey := fmt.Sprintf("%s/%d", m.key, leaseID)
+	if err := m.store.put(serviceKey, val, leaseID); err != nil {
+		m.lock.Unlock()
+		m.log.Errorf("注册服务失败: %v", err)
+		return
+	}
+
+	// 停止旧租约的续约
+	assert.MayTrue(m.keepAliveCancel != nil, func() {
+		m.keepAliveCancel()
+	})
+	keepAliveCtx, cancel := context.WithCancel(m.ctx)
+	m.leaseID = leaseID
+	m.keepAliveCancel = cancel
+	m.lock.Unlock()
+
+	go logger.WithRecover(m.log, func() {
+		m.keepAlive(keepAliveCtx, leaseID, ttl)
+	})
+
+	m.log.Infof("服务注册成功 - Key: %s, Value: %s, 租约ID: %d, TTL: %d秒", serviceKey, val, leaseID, lease)
+}
+
+// keepAlive 定期续约, 租约丢失时重新注册
+func (m *InMemoryRegistry) keepAlive(ctx context.Context, leaseID clientv3.LeaseID, ttl time.Duration) {
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := m.store.keepAlive(leaseID); err != nil {
+				m.log.Errorf("租约续约失败，重新注册，租约ID: %d, 错误: %v", leaseID, err)
+				m.Refresh()
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// GetLeaseID 获取租约ID
+func (m *InMemoryRegistry) GetLeaseID() uint64 {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return uint64(m.leaseID)
+}
+
+// Deregister 注销服务
+func (m *InMemoryRegistry) Deregister() {
+	m.lock.RLock()
+	leaseID := m.leaseID
+	m.lock.RUnlock()
+
+	if leaseID == 0 {
+		m.log.Warnf("租约ID为0，无需注销")
+		return
+	}
+
+	key := fmt.Sprintf("%s/%d", m.key, leaseID)
+	m.store.delete(key)
+
+	m.log.Infof("服务注销成功，Key: %s", key)
+}
+
+// Put 创建或更新键值
+func (m *InMemoryRegistry) Put(ctx context.Context, key string, val string) {
+	m.log.Infof("put key:%s val:%s", key, val)
+	if err := m.store.put(key, val, 0); err != nil {
+		m.log.Errorf(err.Error())
+	}
+}
+
+// GetValue 获取单个值, opts 支持 clientv3.OpOption
+func (m *InMemoryRegistry) GetValue(key string, opts ...any) string {
+	kvs, _ := m.store.get(key, toEtcdOpts(opts)...)
+	if len(kvs) == 0 {
+		return ""
+	}
+	return string(kvs[0].Value)
+}
+
+// GetValues 获取多个值, 返回 []*mvccpb.KeyValue
+func (m *InMemoryRegistry) GetValues(key string, opts ...any) any {
+	kvs, _ := m.store.get(key, toEtcdOpts(opts)...)
+	return kvs
+}
+
+// GetValuesTyped 获取多个值(类型安全版本)
+func (m *InMemoryRegistry) GetValuesTyped(key string, opts ...clientv3.OpOption) []*mvccpb.KeyValue {
+	kvs, _ := m.store.get(key, opts...)
+	return kvs
+}
+
+// toEtcdOpts 转换 opts 为 clientv3.OpOption
+func toEtcdOpts(opts []any) []clientv3.OpOption {
+	etcdOpts := make([]clientv3.OpOption, 0, len(opts))
+	for _, opt := range opts {
+		if etcdOpt, ok := opt.(clientv3.OpOption); ok {
+			etcdOpts = append(etcdOpts, etcdOpt)
+		}
+	}
+	return etcdOpts
+}
+
+// Watch 监听指定前缀的键变化, 返回 clientv3.WatchChan
+func (m *InMemoryRegistry) Watch(ctx context.Context, prefix string) any {
+	return m.WatchTyped(ctx, prefix)
+}
+
+// WatchTyped 监听指定前缀的键变化(类型安全版本)
+func (m *InMemoryRegistry) WatchTyped(ctx context.Context, prefix string) clientv3.WatchChan {
+	m.log.Infof("开始监听键变化，前缀: %s", prefix)
+	_, watchChan := m.store.watch(ctx, prefix)
+	return watchChan
+}
+
+// ConfigSource 创建读取内存存储的配置源, 格式与 etcd 配置源一致
+func (m *InMemoryRegistry) ConfigSource(key string) (config.Source, error) {
+	return config.NewEtcdSource(memoryKV{store: m.store}, key, m.log), nil
+}
+
+// memoryKV 内存存储适配 config.EtcdKV
+type memoryKV struct {
+	store *MemoryStore
+}
+
+// Get 读取键值
+func (kv memoryKV) Get(_ context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	kvs, _ := kv.store.get(key, opts...)
+	return &clientv3.GetResponse{Kvs: kvs}, nil
+}
+
+// Watch 监听前缀的键变化
+func (kv memoryKV) Watch(ctx context.Context, key string, _ ...clientv3.OpOption) clientv3.WatchChan {
+	_, watchChan := kv.store.watch(ctx, key)
+	return watchChan
+}
+
+// Discover 获取服务的全部实例, name 为服务注册的键, 实例注册在 name/租约ID 下
+func (m *InMemoryRegistry) Discover(ctx context.Context, name string) ([]ServiceInstance, error) {
+	prefix := etcdServicePrefix(name)
+	kvs, _ := m.store.get(prefix, clientv3.WithPrefix())
+	return etcdInstances(name, prefix, kvs), nil
+}
+
+// Subscribe 订阅服务实例变化
+func (m *InMemoryRegistry) Subscribe(ctx context.Context, name string) (<-chan []ServiceInstance, error) {
+	prefix := etcdServicePrefix(name)
+	watchCtx, cancel := context.WithCancel(ctx)
+	kvs, watchChan := m.store.watch(watchCtx, prefix)
+
+	return subscribeKVs(watchCtx, cancel, m.ctx.Done(), m.log, name, prefix, kvs, watchChan), nil
+}
+
+// Close 注销服务并撤销租约
+func (m *InMemoryRegistry) Close() {
+	m.lock.Lock()
+	if m.closed {
+		m.lock.Unlock()
+		return
+	}
+	m.closed = true
+	leaseID := m.leaseID
+	m.lock.Unlock()
+
+	m.cancel()
+
+	assert.MayTrue(leaseID != 0, func() {
+		m.Deregister()
+		m.store.revoke(leaseID)
+	})
+
+	m.log.Infof("内存注册中心关闭成功")
+}
+
+// IsHealthy 未关闭即健康
+func (m *InMemoryRegistry) IsHealthy() bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return !m.closed
+}
+
+// Refresh 刷新服务注册(重新注册)
+func (m *InMemoryRegistry) Refresh() {
+	m.log.Infof("开始刷新服务注册")
+
+	m.lock.RLock()
+	leaseID := m.leaseID
+	closed := m.closed
+	m.lock.RUnlock()
+
+	if closed {
+		return
+	}
+
+	assert.MayTrue(leaseID != 0, func() {
+		m.Deregister()
+		m.store.revoke(leaseID)
+	})
+
+	m.putKeyWithLease(m.cnf.GetLeaseTTL())
+	m.log.Infof("刷新服务注册成功")
+}
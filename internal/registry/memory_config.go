@@ -0,0 +1,39 @@
+package registry
+
+import (
+	"errors"
+)
+
+// errNegativeLeaseTTL 租约TTL为负数
+var errNegativeLeaseTTL = errors.New("negative lease ttl")
+
+// MemoryConfig 内存注册中心配置
+type MemoryConfig struct {
+	Key      string       `yaml:"key"`      // 服务注册的键, 实例注册在 Key/租约ID 下
+	LeaseTTL int64        `yaml:"leaseTTL"` // 租约TTL（秒），默认6秒
+	Store    *MemoryStore `yaml:"-"`        // 存储, 为空时使用进程内共享的默认存储
+}
+
+// Validate 验证配置
+func (c *MemoryConfig) Validate() error {
+	if c.LeaseTTL < 0 {
+		return errNegativeLeaseTTL
+	}
+	return nil
+}
+
+// GetLeaseTTL 获取租约TTL，如果未设置则返回默认值6秒
+func (c *MemoryConfig) GetLeaseTTL() int64 {
+	if c.LeaseTTL <= 0 {
+		return 6
+	}
+	return c.LeaseTTL
+}
+
+// GetStore 获取存储, 未设置时返回进程内共享的默认存储
+func (c *MemoryConfig) GetStore() *MemoryStore {
+	if c.Store == nil {
+		return defaultMemoryStore
+	}
+	return c.Store
+}
@@ -0,0 +1,314 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// errLeaseNotFound 租约不存在或已过期
+var errLeaseNotFound = errors.New("租约不存在或已过期")
+
+// defaultMemoryStore 进程内共享的默认存储, 同一进程内的内存注册中心可互相发现
+var defaultMemoryStore = NewMemoryStore()
+
+// MemoryStore 内存键值存储, 语义与 etcd 一致: 全局递增的版本号、绑定租约的键、前缀监听
+type MemoryStore struct {
+	mu        sync.Mutex
+	revision  int64                             // 当前版本号
+	kvs       map[string]*mvccpb.KeyValue       // 键值
+	leases    map[clientv3.LeaseID]*memoryLease // 租约
+	nextLease clientv3.LeaseID                  // 下一个租约ID
+	watchers  map[*memoryWatcher]struct{}       // 监听者
+}
+
+// memoryLease 内存租约, 到期后删除绑定的键
+type memoryLease struct {
+	ttl      time.Duration       // 租约时长
+	deadline time.Time           // 到期时间
+	timer    *time.Timer         // 到期定时器
+	keys     map[string]struct{} // 绑定的键
+}
+
+// NewMemoryStore 创建内存存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		kvs:      make(map[string]*mvccpb.KeyValue),
+		leases:   make(map[clientv3.LeaseID]*memoryLease),
+		watchers: make(map[*memoryWatcher]struct{}),
+	}
+}
+
+// grant 创建租约
+func (m *MemoryStore) grant(ttl time.Duration) clientv3.LeaseID {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextLease++
+	id := m.nextLease
+	lease := &memoryLease{
+		ttl:      ttl,
+		deadline: time.Now().Add(ttl),
+		keys:     make(map[string]struct{}),
+	}
+	lease.timer = time.AfterFunc(ttl, func() { m.expire(id) })
+	m.leases[id] = lease
+
+	return id
+}
+
+// keepAlive 续约
+func (m *MemoryStore) keepAlive(id clientv3.LeaseID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lease, ok := m.leases[id]
+	if !ok {
+		return errLeaseNotFound
+	}
+	lease.deadline = time.Now().Add(lease.ttl)
+	lease.timer.Reset(lease.ttl)
+	return nil
+}
+
+// revoke 撤销租约并删除绑定的键
+func (m *MemoryStore) revoke(id clientv3.LeaseID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.revokeLocked(id)
+}
+
+// expire 租约到期
+func (m *MemoryStore) expire(id clientv3.LeaseID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// 定时器触发与续约并发时, 以到期时间为准
+	if lease, ok := m.leases[id]; ok && !time.Now().Before(lease.deadline) {
+		m.revokeLocked(id)
+	}
+}
+
+// revokeLocked 撤销租约, 调用方需持有锁
+func (m *MemoryStore) revokeLocked(id clientv3.LeaseID) {
+	lease, ok := m.leases[id]
+	if !ok {
+		return
+	}
+	lease.timer.Stop()
+	delete(m.leases, id)
+
+	keys := make([]string, 0, len(lease.keys))
+	for key := range lease.keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		m.deleteLocked(key)
+	}
+}
+
+// put 写入键值, lease 为 0 时不绑定租约
+func (m *MemoryStore) put(key, val string, lease clientv3.LeaseID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if lease != 0 {
+		if _, ok := m.leases[lease]; !ok {
+			return errLeaseNotFound
+		}
+	}
+
+	m.revision++
+	kv := &mvccpb.KeyValue{
+		Key:            []byte(key),
+		Value:          []byte(val),
+		CreateRevision: m.revision,
+		ModRevision:    m.revision,
+		Version:        1,
+		Lease:          int64(lease),
+	}
+
+	prev, exists := m.kvs[key]
+	if exists {
+		kv.CreateRevision = prev.CreateRevision
+		kv.Version = prev.Version + 1
+		// 更换租约时解除旧租约的绑定
+		if old, ok := m.leases[clientv3.LeaseID(prev.Lease)]; ok && prev.Lease != int64(lease) {
+			delete(old.keys, key)
+		}
+	}
+	if l, ok := m.leases[lease]; ok {
+		l.keys[key] = struct{}{}
+	}
+	m.kvs[key] = kv
+
+	m.notifyLocked(&clientv3.Event{Type: mvccpb.PUT, Kv: kv, PrevKv: prev})
+	return nil
+}
+
+// delete 删除键
+func (m *MemoryStore) delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deleteLocked(key)
+}
+
+// deleteLocked 删除键, 调用方需持有锁
+func (m *MemoryStore) deleteLocked(key string) {
+	prev, ok := m.kvs[key]
+	if !ok {
+		return
+	}
+	delete(m.kvs, key)
+	if lease, ok := m.leases[clientv3.LeaseID(prev.Lease)]; ok {
+		delete(lease.keys, key)
+	}
+
+	m.revision++
+	m.notifyLocked(&clientv3.Event{
+		Type:   mvccpb.DELETE,
+		Kv:     &mvccpb.KeyValue{Key: prev.Key, ModRevision: m.revision},
+		PrevKv: prev,
+	})
+}
+
+// get 查询键值, 支持 clientv3.WithPrefix、WithRange、WithFromKey、WithLimit、WithKeysOnly
+func (m *MemoryStore) get(key string, opts ...clientv3.OpOption) ([]*mvccpb.KeyValue, int64) {
+	op := clientv3.OpGet(key, opts...)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.rangeLocked(op), m.revision
+}
+
+// rangeLocked 按操作的范围查询, 结果按键排序, 调用方需持有锁
+func (m *MemoryStore) rangeLocked(op clientv3.Op) []*mvccpb.KeyValue {
+	start, end := op.KeyBytes(), op.RangeBytes()
+
+	var result []*mvccpb.KeyValue
+	if len(end) == 0 {
+		if kv, ok := m.kvs[string(start)]; ok {
+			result = append(result, kv)
+		}
+	} else {
+		for k, kv := range m.kvs {
+			// end 为 "\x00" 表示大于等于 start 的全部键
+			if bytes.Compare([]byte(k), start) >= 0 && (bytes.Equal(end, []byte{0}) || bytes.Compare([]byte(k), end) < 0) {
+				result = append(result, kv)
+			}
+		}
+		sort.Slice(result, func(i, j int) bool { return bytes.Compare(result[i].Key, result[j].Key) < 0 })
+	}
+
+	if limit := op.Limit(); limit > 0 && int64(len(result)) > limit {
+		result = result[:limit]
+	}
+
+	if op.IsKeysOnly() {
+		for i, kv := range result {
+			result[i] = &mvccpb.KeyValue{
+				Key:            kv.Key,
+				CreateRevision: kv.CreateRevision,
+				ModRevision:    kv.ModRevision,
+				Version:        kv.Version,
+				Lease:          kv.Lease,
+			}
+		}
+	}
+	return result
+}
+
+// watch 监听前缀, 同时返回注册监听时的快照, 快照之后的变化都会推送
+func (m *MemoryStore) watch(ctx context.Context, prefix string) ([]*mvccpb.KeyValue, clientv3.WatchChan) {
+	w := &memoryWatcher{
+		prefix: prefix,
+		notify: make(chan struct{}, 1),
+		out:    make(chan clientv3.WatchResponse),
+	}
+
+	m.mu.Lock()
+	kvs := m.rangeLocked(clientv3.OpGet(prefix, clientv3.WithPrefix()))
+	m.watchers[w] = struct{}{}
+	m.mu.Unlock()
+
+	go func() {
+		defer func() {
+			m.mu.Lock()
+			delete(m.watchers, w)
+			m.mu.Unlock()
+		}()
+		w.run(ctx)
+	}()
+
+	return kvs, w.out
+}
+
+// notifyLocked 推送事件给匹配的监听者, 调用方需持有锁
+func (m *MemoryStore) notifyLocked(event *clientv3.Event) {
+	for w := range m.watchers {
+		if bytes.HasPrefix(event.Kv.Key, []byte(w.prefix)) {
+			w.push(clientv3.WatchResponse{
+				Header: pb.ResponseHeader{Revision: m.revision},
+				Events: []*clientv3.Event{event},
+			})
+		}
+	}
+}
+
+// memoryWatcher 内存监听者, 事件先进入无界队列, 不阻塞写入方
+type memoryWatcher struct {
+	prefix  string
+	mu      sync.Mutex
+	pending []clientv3.WatchResponse
+	notify  chan struct{}
+	out     chan clientv3.WatchResponse
+}
+
+// push 事件入队
+func (w *memoryWatcher) push(resp clientv3.WatchResponse) {
+	w.mu.Lock()
+	w.pending = append(w.pending, resp)
+	w.mu.Unlock()
+
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+// run 按顺序投递事件, ctx 取消后关闭通道
+func (w *memoryWatcher) run(ctx context.Context) {
+	defer close(w.out)
+
+	for {
+		select {
+		case <-w.notify:
+		case <-ctx.Done():
+			return
+		}
+
+		w.mu.Lock()
+		pending := w.pending
+		w.pending = nil
+		w.mu.Unlock()
+
+		for _, resp := range pending {
+			select {
+			case w.out <- resp:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
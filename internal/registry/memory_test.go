@@ -0,0 +1,220 @@
+package registry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spelens-gud/assert"
+	"github.com/spelens-gud/logger"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// newTestMemoryRegistry 创建测试用的内存注册中心, 同一测试内的实例共享 store
+func newTestMemoryRegistry(t *testing.T, store *MemoryStore, key string) *InMemoryRegistry {
+	t.Helper()
+
+	log, err := logger.NewLogger(logger.DefaultConfig())
+	if err != nil {
+		t.Fatalf("创建 logger 失败: %v", err)
+	}
+	assert.SetLogger(log)
+
+	reg, err := NewRegistryFactory(log).CreateMemoryRegistry(&MemoryConfig{Key: key, LeaseTTL: 1, Store: store})
+	if err != nil {
+		t.Fatalf("创建内存注册中心失败: %v", err)
+	}
+	return reg.(*InMemoryRegistry)
+}
+
+// TestInMemoryRegistry_PutAndGet 测试 KV 读写与 etcd 查询选项
+func TestInMemoryRegistry_PutAndGet(t *testing.T) {
+	registry := newTestMemoryRegistry(t, NewMemoryStore(), "/test/service")
+	defer registry.Close()
+
+	ctx := context.Background()
+	registry.Put(ctx, "/test/kv/a", "1")
+	registry.Put(ctx, "/test/kv/b", "2")
+	registry.Put(ctx, "/test/kv/b", "3")
+	registry.Put(ctx, "/test/other", "x")
+
+	if v := registry.GetValue("/test/kv/b"); v != "3" {
+		t.Errorf("期望 3, 实际 %s", v)
+	}
+	if v := registry.GetValue("/test/kv/none"); v != "" {
+		t.Errorf("不存在的键应返回空, 实际 %s", v)
+	}
+
+	kvs := registry.GetValuesTyped("/test/kv/", clientv3.WithPrefix())
+	if len(kvs) != 2 || string(kvs[0].Key) != "/test/kv/a" || kvs[1].Version != 2 {
+		t.Fatalf("前缀查询结果错误: %v", kvs)
+	}
+
+	if kvs := registry.GetValuesTyped("/test/kv/", clientv3.WithPrefix(), clientv3.WithLimit(1)); len(kvs) != 1 {
+		t.Errorf("期望 1 条, 实际 %d", len(kvs))
+	}
+
+	// GetValues 返回与 etcd 相同的类型
+	if _, ok := registry.GetValues("/test/kv/", clientv3.WithPrefix()).([]*mvccpb.KeyValue); !ok {
+		t.Error("GetValues 应返回 []*mvccpb.KeyValue")
+	}
+}
+
+// TestInMemoryRegistry_PublishAndDiscover 测试注册、发现与注销
+func TestInMemoryRegistry_PublishAndDiscover(t *testing.T) {
+	store := NewMemoryStore()
+	gate := newTestMemoryRegistry(t, store, "/services/gate")
+	client := newTestMemoryRegistry(t, store, "")
+	defer client.Close()
+
+	gate.Publisher("127.0.0.1:9001")
+	if gate.GetLeaseID() == 0 {
+		t.Fatal("注册后租约ID不应为0")
+	}
+
+	instances, err := client.Discover(context.Background(), "/services/gate")
+	if err != nil {
+		t.Fatalf("服务发现失败: %v", err)
+	}
+	if len(instances) != 1 || instances[0].Endpoint() != "127.0.0.1:9001" || !instances[0].Healthy {
+		t.Fatalf("服务实例错误: %+v", instances)
+	}
+
+	gate.Close()
+	if gate.IsHealthy() {
+		t.Error("关闭后不应健康")
+	}
+
+	instances, _ = client.Discover(context.Background(), "/services/gate")
+	if len(instances) != 0 {
+		t.Errorf("关闭后应注销实例, 实际 %+v", instances)
+	}
+}
+
+// TestInMemoryRegistry_KeepAlive 测试续约使实例在 TTL 之后仍然存在
+func TestInMemoryRegistry_KeepAlive(t *testing.T) {
+	registry := newTestMemoryRegistry(t, NewMemoryStore(), "/services/node")
+	defer registry.Close()
+
+	registry.Publisher("127.0.0.1:9002")
+	time.Sleep(1500 * time.Millisecond)
+
+	instances, _ := registry.Discover(context.Background(), "/services/node")
+	if len(instances) != 1 {
+		t.Errorf("续约后实例应存在, 实际 %+v", instances)
+	}
+}
+
+// TestMemoryStore_LeaseExpire 测试租约到期删除绑定的键并推送删除事件
+func TestMemoryStore_LeaseExpire(t *testing.T) {
+	store := NewMemoryStore()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, watchChan := store.watch(ctx, "/lease/")
+
+	leaseID := store.grant(50 * time.Millisecond)
+	if err := store.put("/lease/a", "1", leaseID); err != nil {
+		t.Fatalf("写入失败: %v", err)
+	}
+	_ = store.put("/lease/b", "2", 0)
+
+	var events []*clientv3.Event
+	timeout := time.After(time.Second)
+	for len(events) < 3 {
+		select {
+		case resp := <-watchChan:
+			events = append(events, resp.Events...)
+		case <-timeout:
+			t.Fatalf("超时：只收到 %d 个事件", len(events))
+		}
+	}
+
+	if events[2].Type != clientv3.EventTypeDelete || string(events[2].Kv.Key) != "/lease/a" {
+		t.Errorf("期望删除 /lease/a, 实际 %s %s", events[2].Type, events[2].Kv.Key)
+	}
+	if kvs, _ := store.get("/lease/", clientv3.WithPrefix()); len(kvs) != 1 || string(kvs[0].Key) != "/lease/b" {
+		t.Errorf("未绑定租约的键应保留: %v", kvs)
+	}
+	if err := store.keepAlive(leaseID); err == nil {
+		t.Error("过期的租约不应续约成功")
+	}
+	if err := store.put("/lease/c", "3", leaseID); err == nil {
+		t.Error("过期的租约不能绑定键")
+	}
+}
+
+// TestInMemoryRegistry_Watch 测试前缀监听
+func TestInMemoryRegistry_Watch(t *testing.T) {
+	registry := newTestMemoryRegistry(t, NewMemoryStore(), "")
+	defer registry.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	watchChan, ok := registry.Watch(ctx, "/test/watch/").(clientv3.WatchChan)
+	if !ok {
+		t.Fatal("Watch 应返回 clientv3.WatchChan")
+	}
+
+	registry.Put(ctx, "/test/other", "x")
+	registry.Put(ctx, "/test/watch/key1", "v1")
+
+	select {
+	case resp := <-watchChan:
+		event := resp.Events[0]
+		if string(event.Kv.Key) != "/test/watch/key1" || !event.IsCreate() {
+			t.Errorf("事件错误: %s %s", event.Type, event.Kv.Key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("超时：未收到事件")
+	}
+
+	cancel()
+	select {
+	case _, ok := <-watchChan:
+		if ok {
+			t.Error("取消后不应再有事件")
+		}
+	case <-time.After(time.Second):
+		t.Error("超时：取消后通道未关闭")
+	}
+}
+
+// TestInMemoryRegistry_Subscribe 测试订阅服务实例变化
+func TestInMemoryRegistry_Subscribe(t *testing.T) {
+	store := NewMemoryStore()
+	client := newTestMemoryRegistry(t, store, "")
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := client.Subscribe(ctx, "/services/fight")
+	if err != nil {
+		t.Fatalf("订阅失败: %v", err)
+	}
+	if initial := <-ch; len(initial) != 0 {
+		t.Fatalf("初始应无实例: %+v", initial)
+	}
+
+	next := func() []ServiceInstance {
+		select {
+		case instances := <-ch:
+			return instances
+		case <-time.After(time.Second):
+			t.Fatal("超时：未收到实例变化")
+			return nil
+		}
+	}
+
+	fight := newTestMemoryRegistry(t, store, "/services/fight")
+	fight.Publisher("127.0.0.1:9003")
+	if got := next(); len(got) != 1 || got[0].Port != 9003 {
+		t.Errorf("期望 1 个实例, 实际 %+v", got)
+	}
+
+	fight.Close()
+	if got := next(); len(got) != 0 {
+		t.Errorf("注销后应无实例, 实际 %+v", got)
+	}
+}
@@ -0,0 +1,431 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spelens-gud/logger"
+	"github.com/spelens-gud/trunk/internal/config"
+)
+
+// errNoBackends 没有可组合的注册中心
+var errNoBackends = errors.New("no registry backends")
+
+// MultiBackend 参与镜像的注册中心
+type MultiBackend struct {
+	Name     string   // 名称，用于日志和指定主注册中心
+	Registry Registry // 已初始化的注册中心
+	Prefix   string   // 服务发现时拼接在服务名前的前缀，如 etcd 的 /wsh/moba/servers，服务名以 / 开头时不拼接
+}
+
+// serviceName 服务在该注册中心中的名称
+func (b *MultiBackend) serviceName(name string) string {
+	if b.Prefix == "" || strings.HasPrefix(name, "/") {
+		return name
+	}
+	return path.Join(b.Prefix, name)
+}
+
+// MultiRegistry 多注册中心镜像, 用于注册中心迁移期间同时使用多个注册中心
+//
+// Publisher、Deregister、Put、Refresh 并发写入全部注册中心, 单个注册中心失败或 panic 不影响其他注册中心;
+// 服务发现合并各注册中心的实例并按地址去重, 主注册中心的实例优先; GetValue、GetValues、Watch 读取主注册中心,
+// 主注册中心不健康时读取第一个健康的注册中心。各注册中心的健康状态单独检查, 恢复后补发最近一次 Publisher 的值
+type MultiRegistry struct {
+	cnf       *MultiConfig
+	log       logger.ILogger
+	backends  []MultiBackend
+	primary   int    // 主注册中心的下标
+	healthy   []bool // 各注册中心最近一次检查是否健康
+	val       string // 最近一次发布的值
+	published bool   // 是否已发布且未注销
+	lock      sync.RWMutex
+	pubLock   sync.Mutex // 串行化 Publisher、Deregister 和恢复后的补发, 避免注销后又补发注册
+	ctx       context.Context
+	cancel    context.CancelFunc
+	done      chan struct{} // 检查协程退出
+}
+
+// 确保 MultiRegistry 实现了 Registry 接口
+var _ Registry = (*MultiRegistry)(nil)
+
+// NewMultiRegistry 组合多个已初始化的注册中心, config.Primary 为空时第一个注册中心为主注册中心
+func NewMultiRegistry(config *MultiConfig, log logger.ILogger, backends ...MultiBackend) (*MultiRegistry, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("多注册中心配置错误: %w", err)
+	}
+	if len(backends) == 0 {
+		return nil, errNoBackends
+	}
+
+	primary := 0
+	if config.Primary != "" {
+		primary = slices.IndexFunc(backends, func(b MultiBackend) bool {
+			return b.Name == config.Primary
+		})
+		if primary < 0 {
+			return nil, fmt.Errorf("主注册中心不存在: %s", config.Primary)
+		}
+	}
+
+	m := &MultiRegistry{
+		cnf:      config,
+		log:      log,
+		backends: backends,
+		primary:  primary,
+		healthy:  make([]bool, len(backends)),
+	}
+	m.New()
+
+	return m, nil
+}
+
+// New 检查各注册中心的健康并启动定期检查, 各注册中心需已初始化
+func (m *MultiRegistry) New() {
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+	m.done = make(chan struct{})
+
+	// 初始视为健康, 首次检查不可用时输出日志
+	for i := range m.healthy {
+		m.healthy[i] = true
+	}
+	m.checkHealth()
+
+	go logger.WithRecover(m.log, m.run)
+
+	m.log.Infof("初始化多注册中心，注册中心: %v, 主注册中心: %s", m.names(), m.backends[m.primary].Name)
+}
+
+// names 各注册中心的名称
+func (m *MultiRegistry) names() []string {
+	names := make([]string, 0, len(m.backends))
+	for _, b := range m.backends {
+		names = append(names, b.Name)
+	}
+	return names
+}
+
+// run 定期检查各注册中心的健康
+func (m *MultiRegistry) run() {
+	defer close(m.done)
+
+	ticker := time.NewTicker(m.cnf.GetCheckInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.checkHealth()
+		case <-m.ctx.Done():
+			return
+		}
+	}
+}
+
+// checkHealth 检查各注册中心的健康, 恢复的注册中心补发最近一次发布的值
+func (m *MultiRegistry) checkHealth() {
+	results := make([]bool, len(m.backends))
+	m.forEach(m.all(), func(i int, b MultiBackend) {
+		results[i] = b.Registry.IsHealthy()
+	})
+
+	var recovered []int
+	m.lock.Lock()
+	for i, healthy := range results {
+		switch {
+		case healthy && !m.healthy[i]:
+			m.log.Infof("注册中心已恢复: %s", m.backends[i].Name)
+			recovered = append(recovered, i)
+		case !healthy && m.healthy[i]:
+			m.log.Warnf("注册中心不可用: %s", m.backends[i].Name)
+		}
+		m.healthy[i] = healthy
+	}
+	m.lock.Unlock()
+
+	if len(recovered) == 0 {
+		return
+	}
+
+	m.pubLock.Lock()
+	defer m.pubLock.Unlock()
+
+	// 持有 pubLock 后再读取, 检查期间注销或重新发布时以最新的值为准
+	m.lock.RLock()
+	val, published := m.val, m.published
+	m.lock.RUnlock()
+	if !published {
+		return
+	}
+
+	m.forEach(recovered, func(i int, b MultiBackend) {
+		b.Registry.Publisher(val)
+		m.log.Infof("注册中心恢复后重新注册服务: %s", b.Name)
+	})
+}
+
+// all 全部注册中心的下标, 主注册中心在前
+func (m *MultiRegistry) all() []int {
+	indices := make([]int, 0, len(m.backends))
+	indices = append(indices, m.primary)
+	for i := range m.backends {
+		if i != m.primary {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// healthyBackends 健康的注册中心的下标, 主注册中心在前; 全部不健康时返回全部注册中心
+func (m *MultiRegistry) healthyBackends() []int {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	indices := make([]int, 0, len(m.backends))
+	for _, i := range m.all() {
+		if m.healthy[i] {
+			indices = append(indices, i)
+		}
+	}
+	if len(indices) == 0 {
+		return m.all()
+	}
+	return indices
+}
+
+// forEach 并发对指定的注册中心执行 fn, 单个注册中心 panic 不影响其他注册中心
+func (m *MultiRegistry) forEach(indices []int, fn func(i int, b MultiBackend)) {
+	var wg sync.WaitGroup
+	for _, i := range indices {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.WithRecover(m.log, func() {
+				fn(i, m.backends[i])
+			})
+		}()
+	}
+	wg.Wait()
+}
+
+// reader 读取使用的注册中心, 主注册中心不健康时为第一个健康的注册中心
+func (m *MultiRegistry) reader() Registry {
+	return m.backends[m.healthyBackends()[0]].Registry
+}
+
+// BackendHealth 各注册中心最近一次检查是否健康, key 为注册中心名称
+func (m *MultiRegistry) BackendHealth() map[string]bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	health := make(map[string]bool, len(m.backends))
+	for i, b := range m.backends {
+		health[b.Name] = m.healthy[i]
+	}
+	return health
+}
+
+// Publisher 发布服务到全部注册中心, 不可用的注册中心恢复后补发
+func (m *MultiRegistry) Publisher(value string) {
+	m.pubLock.Lock()
+	defer m.pubLock.Unlock()
+
+	m.lock.Lock()
+	m.val = value
+	m.published = true
+	m.lock.Unlock()
+
+	m.forEach(m.all(), func(_ int, b MultiBackend) {
+		b.Registry.Publisher(value)
+	})
+}
+
+// Deregister 从全部注册中心注销服务
+func (m *MultiRegistry) Deregister() {
+	m.pubLock.Lock()
+	defer m.pubLock.Unlock()
+
+	m.lock.Lock()
+	m.published = false
+	m.lock.Unlock()
+
+	m.forEach(m.all(), func(_ int, b MultiBackend) {
+		b.Registry.Deregister()
+	})
+}
+
+// Put 写入全部注册中心
+func (m *MultiRegistry) Put(ctx context.Context, key string, val string) {
+	m.forEach(m.all(), func(_ int, b MultiBackend) {
+		b.Registry.Put(ctx, key, val)
+	})
+}
+
+// Refresh 刷新全部注册中心的服务注册
+func (m *MultiRegistry) Refresh() {
+	m.forEach(m.all(), func(_ int, b MultiBackend) {
+		b.Registry.Refresh()
+	})
+}
+
+// GetValue 从主注册中心获取单个值
+func (m *MultiRegistry) GetValue(key string, opts ...any) string {
+	return m.reader().GetValue(key, opts...)
+}
+
+// GetValues 从主注册中心获取多个值
+func (m *MultiRegistry) GetValues(key string, opts ...any) any {
+	return m.reader().GetValues(key, opts...)
+}
+
+// Watch 监听主注册中心的键变化
+func (m *MultiRegistry) Watch(ctx context.Context, prefix string) any {
+	return m.reader().Watch(ctx, prefix)
+}
+
+// ConfigSource 创建主注册中心的配置源
+//
+// 配置源长期监听, 不随健康状态切换, 主注册中心不可用期间保留最近一次加载的配置
+func (m *MultiRegistry) ConfigSource(key string) (config.Source, error) {
+	return NewConfigSource(m.backends[m.primary].Registry, key)
+}
+
+// IsHealthy 是否有健康的注册中心
+func (m *MultiRegistry) IsHealthy() bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return slices.Contains(m.healthy, true)
+}
+
+// GetLeaseID 获取主注册中心的租约ID
+func (m *MultiRegistry) GetLeaseID() uint64 {
+	return m.backends[m.primary].Registry.GetLeaseID()
+}
+
+// Discover 合并健康的注册中心中服务的全部实例, 全部查询失败时返回错误
+func (m *MultiRegistry) Discover(ctx context.Context, name string) ([]ServiceInstance, error) {
+	results := make([][]ServiceInstance, len(m.backends))
+	errs := make([]error, len(m.backends))
+
+	indices := m.healthyBackends()
+	m.forEach(indices, func(i int, b MultiBackend) {
+		results[i], errs[i] = b.Registry.Discover(ctx, b.serviceName(name))
+	})
+
+	lists := make([][]ServiceInstance, 0, len(indices))
+	var failed []error
+	for _, i := range indices {
+		if errs[i] != nil {
+			m.log.Debugf("注册中心服务发现失败: %s, 服务: %s, 错误: %v", m.backends[i].Name, name, errs[i])
+			failed = append(failed, fmt.Errorf("%s: %w", m.backends[i].Name, errs[i]))
+			continue
+		}
+		lists = append(lists, results[i])
+	}
+	if len(lists) == 0 {
+		return nil, errors.Join(failed...)
+	}
+	return mergeInstances(lists...), nil
+}
+
+// Subscribe 订阅全部注册中心的实例变化, 任一注册中心变化时推送合并后的实例列表; 单个注册中心的订阅结束后不再合并它的实例
+func (m *MultiRegistry) Subscribe(ctx context.Context, name string) (<-chan []ServiceInstance, error) {
+	type update struct {
+		index     int
+		instances []ServiceInstance
+	}
+	updates := make(chan update)
+
+	var wg sync.WaitGroup
+	var failed []error
+	for i, b := range m.backends {
+		ch, err := b.Registry.Subscribe(ctx, b.serviceName(name))
+		if err != nil {
+			m.log.Warnf("订阅注册中心失败: %s, 服务: %s, 错误: %v", b.Name, name, err)
+			failed = append(failed, fmt.Errorf("%s: %w", b.Name, err))
+			continue
+		}
+
+		wg.Add(1)
+		go logger.WithRecover(m.log, func() {
+			defer wg.Done()
+			for instances := range ch {
+				select {
+				case updates <- update{index: i, instances: instances}:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			// 订阅结束, 清除该注册中心的实例
+			select {
+			case updates <- update{index: i}:
+			case <-ctx.Done():
+			}
+		})
+	}
+	if len(failed) == len(m.backends) {
+		return nil, errors.Join(failed...)
+	}
+
+	go func() {
+		wg.Wait()
+		close(updates)
+	}()
+
+	out := make(chan []ServiceInstance, 1)
+	go logger.WithRecover(m.log, func() {
+		defer close(out)
+
+		latest := make([][]ServiceInstance, len(m.backends))
+		for u := range updates {
+			latest[u.index] = u.instances
+
+			lists := make([][]ServiceInstance, 0, len(latest))
+			for _, i := range m.all() {
+				lists = append(lists, latest[i])
+			}
+			if !sendInstances(ctx, out, mergeInstances(lists...)) {
+				return
+			}
+		}
+	})
+
+	return out, nil
+}
+
+// Close 停止健康检查并关闭全部注册中心
+func (m *MultiRegistry) Close() {
+	m.cancel()
+	<-m.done
+
+	m.forEach(m.all(), func(_ int, b MultiBackend) {
+		b.Registry.Close()
+	})
+
+	m.log.Infof("多注册中心关闭成功")
+}
+
+// mergeInstances 合并实例列表, 按 host:port 去重, 先出现的实例优先
+func mergeInstances(lists ...[]ServiceInstance) []ServiceInstance {
+	seen := make(map[string]struct{})
+	merged := make([]ServiceInstance, 0)
+	for _, instances := range lists {
+		for _, instance := range instances {
+			endpoint := instance.Endpoint()
+			if _, ok := seen[endpoint]; ok {
+				continue
+			}
+			seen[endpoint] = struct{}{}
+			merged = append(merged, instance)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,83 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// errNegativeMultiCheckInterval 健康检查间隔为负数
+var errNegativeMultiCheckInterval = errors.New("negative multi registry check interval")
+
+// MultiConfig 多注册中心配置
+//
+//	Registry:
+//	  type: "multi"
+//	  name: "gate"
+//	  primary: "consul"
+//	  backends:
+//	    - type: "consul"
+//	      address: "127.0.0.1:8500"
+//	    - type: "etcd"
+//	      hosts: [ "127.0.0.1:2379" ]
+type MultiConfig struct {
+	Primary       string            `yaml:"primary"`       // 读取使用的主注册中心名称，从配置文件创建时为 type，同类型的第二个起为 type-2、type-3，默认第一个
+	CheckInterval int               `yaml:"checkInterval"` // 检查各注册中心健康的间隔（秒），默认5秒
+	Backends      []*RegistryConfig `yaml:"-"`             // 各注册中心的配置，未配置服务名和键的层级时继承外层配置
+}
+
+// Validate 验证配置
+func (c *MultiConfig) Validate() error {
+	if c.CheckInterval < 0 {
+		return errNegativeMultiCheckInterval
+	}
+	return nil
+}
+
+// validateBackends 验证各注册中心的配置, 从配置文件创建时使用
+func (c *MultiConfig) validateBackends() error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	if len(c.Backends) == 0 {
+		return errNoBackends
+	}
+
+	for i, backend := range c.Backends {
+		if backend.Type == TypeMulti {
+			return errNestedMulti
+		}
+		if err := backend.Validate(); err != nil {
+			return fmt.Errorf("第%d个注册中心: %w", i+1, err)
+		}
+	}
+
+	if c.Primary != "" && !slices.Contains(c.backendNames(), c.Primary) {
+		return fmt.Errorf("主注册中心不存在: %s", c.Primary)
+	}
+	return nil
+}
+
+// backendNames 各注册中心的名称, 为 type, 同类型的第二个起为 type-2、type-3
+func (c *MultiConfig) backendNames() []string {
+	names := make([]string, 0, len(c.Backends))
+	count := make(map[string]int, len(c.Backends))
+	for _, backend := range c.Backends {
+		count[backend.Type]++
+		if n := count[backend.Type]; n > 1 {
+			names = append(names, fmt.Sprintf("%s-%d", backend.Type, n))
+		} else {
+			names = append(names, backend.Type)
+		}
+	}
+	return names
+}
+
+// GetCheckInterval 获取健康检查间隔，默认5秒
+func (c *MultiConfig) GetCheckInterval() time.Duration {
+	if c.CheckInterval <= 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(c.CheckInterval) * time.Second
+}
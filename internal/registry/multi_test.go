@@ -0,0 +1,267 @@
+package registry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spelens-gud/logger"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// newTestMultiRegistry 组合主注册中心和可模拟不可用的从注册中心
+func newTestMultiRegistry(t *testing.T, primary, secondary *MemoryStore) (*MultiRegistry, *flakyRegistry) {
+	t.Helper()
+
+	first := newTestMemoryRegistry(t, primary, "/services/gate/a")
+	second := &flakyRegistry{InMemoryRegistry: newTestMemoryRegistry(t, secondary, "/services/gate/a")}
+
+	m, err := NewMultiRegistry(&MultiConfig{Primary: "primary", CheckInterval: 1}, first.log,
+		MultiBackend{Name: "secondary", Registry: second},
+		MultiBackend{Name: "primary", Registry: first},
+	)
+	if err != nil {
+		t.Fatalf("创建多注册中心失败: %v", err)
+	}
+	return m, second
+}
+
+// waitBackendHealth 等待注册中心的健康状态
+func waitBackendHealth(t *testing.T, m *MultiRegistry, name string, healthy bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if m.BackendHealth()[name] == healthy {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("等待注册中心 %s 健康状态 %v 超时", name, healthy)
+}
+
+// TestMultiRegistry_Mirror 测试写入全部注册中心、合并去重服务发现和读取主注册中心
+func TestMultiRegistry_Mirror(t *testing.T) {
+	primary, secondary := NewMemoryStore(), NewMemoryStore()
+	m, _ := newTestMultiRegistry(t, primary, secondary)
+	defer m.Close()
+
+	// 只注册在从注册中心的实例
+	other := newTestMemoryRegistry(t, secondary, "/services/gate/b")
+	defer other.Close()
+	other.Publisher("127.0.0.1:9002")
+
+	ctx := context.Background()
+	m.Publisher("127.0.0.1:9001")
+	m.Put(ctx, "/config/gate", "v1")
+
+	for _, store := range []*MemoryStore{primary, secondary} {
+		if kvs, _ := store.get("/services/gate/a/", clientv3.WithPrefix()); len(kvs) != 1 {
+			t.Fatalf("服务未注册到全部注册中心: %v", kvs)
+		}
+		if kvs, _ := store.get("/config/gate"); len(kvs) != 1 || string(kvs[0].Value) != "v1" {
+			t.Fatalf("键值未写入全部注册中心: %v", kvs)
+		}
+	}
+
+	instances, err := m.Discover(ctx, "/services/gate")
+	if err != nil || len(instances) != 2 {
+		t.Fatalf("期望合并去重后 2 个实例, 实际 %+v, err=%v", instances, err)
+	}
+	if instances[0].Port != 9001 {
+		t.Errorf("主注册中心的实例应在前, 实际 %+v", instances)
+	}
+
+	// 读取主注册中心
+	secondary.put("/config/gate", "v2", 0)
+	if value := m.GetValue("/config/gate"); value != "v1" {
+		t.Errorf("期望读取主注册中心的 v1, 实际 %s", value)
+	}
+
+	m.Deregister()
+	for _, store := range []*MemoryStore{primary, secondary} {
+		if kvs, _ := store.get("/services/gate/a/", clientv3.WithPrefix()); len(kvs) != 0 {
+			t.Fatalf("服务未从全部注册中心注销: %v", kvs)
+		}
+	}
+}
+
+// TestMultiRegistry_Health 测试单个注册中心不可用时继续服务并在恢复后补发注册
+func TestMultiRegistry_Health(t *testing.T) {
+	primary, secondary := NewMemoryStore(), NewMemoryStore()
+	m, second := newTestMultiRegistry(t, primary, secondary)
+	defer m.Close()
+
+	other := newTestMemoryRegistry(t, secondary, "/services/gate/b")
+	defer other.Close()
+	other.Publisher("127.0.0.1:9002")
+	m.Publisher("127.0.0.1:9001")
+
+	second.down.Store(true)
+	waitBackendHealth(t, m, "secondary", false)
+	if !m.IsHealthy() || !m.BackendHealth()["primary"] {
+		t.Fatalf("主注册中心健康时应健康: %v", m.BackendHealth())
+	}
+
+	ctx := context.Background()
+	instances, err := m.Discover(ctx, "/services/gate")
+	if err != nil || len(instances) != 1 || instances[0].Port != 9001 {
+		t.Fatalf("期望只返回主注册中心的实例, 实际 %+v, err=%v", instances, err)
+	}
+
+	// 不可用期间注册丢失, 恢复后补发
+	kvs, _ := secondary.get("/services/gate/a/", clientv3.WithPrefix())
+	for _, kv := range kvs {
+		secondary.delete(string(kv.Key))
+	}
+	second.down.Store(false)
+	waitBackendHealth(t, m, "secondary", true)
+
+	if kvs, _ = secondary.get("/services/gate/a/", clientv3.WithPrefix()); len(kvs) != 1 {
+		t.Fatalf("恢复后未重新注册: %v", kvs)
+	}
+	if instances, err = m.Discover(ctx, "/services/gate"); err != nil || len(instances) != 2 {
+		t.Fatalf("恢复后期望 2 个实例, 实际 %+v, err=%v", instances, err)
+	}
+}
+
+// TestMultiRegistry_Subscribe 测试订阅推送合并后的实例列表
+func TestMultiRegistry_Subscribe(t *testing.T) {
+	primary, secondary := NewMemoryStore(), NewMemoryStore()
+	m, _ := newTestMultiRegistry(t, primary, secondary)
+	defer m.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := m.Subscribe(ctx, "/services/gate")
+	if err != nil {
+		t.Fatalf("订阅失败: %v", err)
+	}
+
+	m.Publisher("127.0.0.1:9001")
+	other := newTestMemoryRegistry(t, secondary, "/services/gate/b")
+	defer other.Close()
+	other.Publisher("127.0.0.1:9002")
+
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case instances := <-ch:
+			if len(instances) == 2 {
+				return
+			}
+		case <-timeout:
+			t.Fatal("等待合并后的实例超时")
+		}
+	}
+}
+
+// TestMultiRegistry_SubscribeClosed 测试单个注册中心的订阅结束后不再合并它的实例
+func TestMultiRegistry_SubscribeClosed(t *testing.T) {
+	primary, secondary := NewMemoryStore(), NewMemoryStore()
+	m, second := newTestMultiRegistry(t, primary, secondary)
+	defer m.Close()
+
+	other := newTestMemoryRegistry(t, secondary, "/services/gate/b")
+	defer other.Close()
+	other.Publisher("127.0.0.1:9002")
+	m.Publisher("127.0.0.1:9001")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := m.Subscribe(ctx, "/services/gate")
+	if err != nil {
+		t.Fatalf("订阅失败: %v", err)
+	}
+
+	waitInstances := func(want int) {
+		t.Helper()
+		timeout := time.After(3 * time.Second)
+		for {
+			select {
+			case instances := <-ch:
+				if len(instances) == want {
+					return
+				}
+			case <-timeout:
+				t.Fatalf("等待 %d 个实例超时", want)
+			}
+		}
+	}
+	waitInstances(2)
+
+	// 关闭从注册中心结束它的订阅, 只在从注册中心的实例不再推送
+	second.InMemoryRegistry.Close()
+	waitInstances(1)
+}
+
+// TestMultiRegistry_DeregisterWhileDown 测试不可用期间注销的服务在恢复后不补发
+func TestMultiRegistry_DeregisterWhileDown(t *testing.T) {
+	primary, secondary := NewMemoryStore(), NewMemoryStore()
+	m, second := newTestMultiRegistry(t, primary, secondary)
+	defer m.Close()
+
+	m.Publisher("127.0.0.1:9001")
+	second.down.Store(true)
+	waitBackendHealth(t, m, "secondary", false)
+
+	m.Deregister()
+	second.down.Store(false)
+	waitBackendHealth(t, m, "secondary", true)
+
+	if kvs, _ := secondary.get("/services/gate/a/", clientv3.WithPrefix()); len(kvs) != 0 {
+		t.Fatalf("注销后不应补发注册: %v", kvs)
+	}
+}
+
+// TestCreateMultiFromViper 测试从配置创建多注册中心
+func TestCreateMultiFromViper(t *testing.T) {
+	v := newTestViper(t, `
+Registry:
+  type: "multi"
+  name: "gate"
+  primary: "memory-2"
+  projectPrefix: "/wsh/moba"
+  serverPrefix: "servers"
+  backends:
+    - type: "memory"
+    - type: "memory"
+      serverPrefix: "mirror"
+`)
+
+	config, err := LoadRegistryConfig(v, "Registry")
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	backends := config.Multi.Backends
+	if len(backends) != 2 || backends[0].Memory.Key != "/wsh/moba/servers/gate" || backends[1].Memory.Key != "/wsh/moba/mirror/gate" {
+		t.Fatalf("各注册中心应继承外层的服务名和层级: %+v", backends)
+	}
+
+	log, err := logger.NewLogger(logger.DefaultConfig())
+	if err != nil {
+		t.Fatalf("创建 logger 失败: %v", err)
+	}
+
+	reg, err := NewRegistryFactory(log).Create(config)
+	if err != nil {
+		t.Fatalf("创建失败: %v", err)
+	}
+	defer reg.Close()
+
+	m, ok := reg.(*MultiRegistry)
+	if !ok {
+		t.Fatalf("期望多注册中心, 实际 %T", reg)
+	}
+	if m.backends[m.primary].Name != "memory-2" || m.backends[1].Prefix != "/wsh/moba/mirror" {
+		t.Errorf("注册中心配置错误: %+v", m.backends)
+	}
+
+	m.Publisher("127.0.0.1:9001")
+	instances, err := m.Discover(context.Background(), "gate")
+	if err != nil || len(instances) != 1 {
+		t.Errorf("期望按各注册中心的层级发现 1 个实例, 实际 %+v, err=%v", instances, err)
+	}
+}
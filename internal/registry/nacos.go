@@ -2,15 +2,18 @@ package registry
 
 import (
 	"context"
+	"crypto/md5"
 	"fmt"
 
 	"github.com/nacos-group/nacos-sdk-go/v2/clients"
+	"github.com/nacos-group/nacos-sdk-go/v2/clients/config_client"
 	"github.com/nacos-group/nacos-sdk-go/v2/clients/naming_client"
 	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
 	"github.com/nacos-group/nacos-sdk-go/v2/model"
 	"github.com/nacos-group/nacos-sdk-go/v2/vo"
 	"github.com/spelens-gud/assert"
 	"github.com/spelens-gud/logger"
+	"github.com/spelens-gud/trunk/internal/config"
 
 	"sync"
 )
@@ -18,11 +21,13 @@ import (
 // NacosRegistry nacos注册中心实现
 type NacosRegistry struct {
 	namingClient naming_client.INamingClient
+	configClient config_client.IConfigClient
 	cnf          *NacosConfig
 	log          logger.ILogger
 	lock         sync.RWMutex
 	ctx          context.Context
 	cancel       context.CancelFunc
+	val          string // 注册的值, 为注册记录时写入实例的元数据
 }
 
 // 确保 NacosRegistry 实现了 Registry 接口
@@ -59,9 +64,29 @@ func (n *NacosRegistry) New() {
 		ServerConfigs: serverConfigs,
 	}, "创建nacos客户端失败")
 
+	n.configClient = assert.ShouldCall1RE(clients.NewConfigClient, vo.NacosClientParam{
+		ClientConfig:  &clientConfig,
+		ServerConfigs: serverConfigs,
+	}, "创建nacos配置客户端失败")
+
 	n.log.Infof("初始化nacos注册中心，服务器: %v", n.cnf.Hosts)
 }
 
+// GetConfigClient 获取配置客户端
+func (n *NacosRegistry) GetConfigClient() config_client.IConfigClient {
+	return n.configClient
+}
+
+// GetGroupName 获取分组名称
+func (n *NacosRegistry) GetGroupName() string {
+	return n.cnf.GetGroupName()
+}
+
+// ConfigSource 复用配置客户端创建配置源, dataId 为 key 转换后的值, 分组为配置的 GroupName
+func (n *NacosRegistry) ConfigSource(key string) (config.Source, error) {
+	return config.NewNacosSource(n.configClient, key, n.cnf.GetGroupName(), n.log), nil
+}
+
 // Publisher 注册服务
 func (n *NacosRegistry) Publisher(value string) {
 	n.lock.Lock()
@@ -69,7 +94,12 @@ func (n *NacosRegistry) Publisher(value string) {
 
 	n.log.Infof("注册nacos服务: %s", n.cnf.ServiceName)
 
-	assert.ShouldCall1RE(n.namingClient.RegisterInstance, vo.RegisterInstanceParam{
+	// 刷新时沿用上次注册的值
+	if value != "" {
+		n.val = value
+	}
+
+	param := vo.RegisterInstanceParam{
 		Ip:          n.cnf.IP,
 		Port:        n.cnf.ServicePort,
 		ServiceName: n.cnf.ServiceName,
@@ -80,7 +110,17 @@ func (n *NacosRegistry) Publisher(value string) {
 		Healthy:     n.cnf.IsHealthy(),
 		Ephemeral:   n.cnf.IsEphemeral(),
 		Metadata:    n.cnf.Metadata,
-	}, "注册nacos服务失败")
+	}
+
+	// 注册记录写入元数据和权重
+	if record, err := ParseRegistration(n.val); err == nil {
+		param.Metadata = mergeMeta(n.cnf.Metadata, record)
+		if record.Weight > 0 {
+			param.Weight = record.Weight
+		}
+	}
+
+	assert.ShouldCall1RE(n.namingClient.RegisterInstance, param, "注册nacos服务失败")
 
 	n.log.Infof("nacos服务注册成功")
 }
@@ -104,18 +144,22 @@ func (n *NacosRegistry) Deregister() {
 	n.log.Infof("nacos服务注销成功")
 }
 
-// GetValue 获取单个服务实例
+// GetValue 获取配置, key 按 NacosDataID 转换为 dataId, 分组为配置的 GroupName
 func (n *NacosRegistry) GetValue(key string, opts ...any) string {
-	n.lock.RLock()
-	defer n.lock.RUnlock()
-
-	instance := assert.ShouldCall1RE(n.namingClient.SelectOneHealthyInstance, vo.SelectOneHealthInstanceParam{
-		ServiceName: key,
-		GroupName:   n.cnf.GetGroupName(),
-		Clusters:    []string{n.cnf.GetClusterName()},
-	}, "获取nacos服务实例失败")
+	if n.configClient == nil {
+		n.log.Errorf("nacos配置客户端为空")
+		return ""
+	}
 
-	return fmt.Sprintf("%s:%d", instance.Ip, instance.Port)
+	content, err := n.configClient.GetConfig(vo.ConfigParam{
+		DataId: config.NacosDataID(key),
+		Group:  n.cnf.GetGroupName(),
+	})
+	if err != nil {
+		n.log.Errorf("获取nacos配置失败，键: %s, 错误: %v", key, err)
+		return ""
+	}
+	return content
 }
 
 // GetValues 获取所有服务实例
@@ -133,30 +177,59 @@ func (n *NacosRegistry) GetValues(key string, opts ...any) any {
 	return instances
 }
 
-// Put 创建或更新服务
+// Put 发布配置, key 按 NacosDataID 转换为 dataId
 func (n *NacosRegistry) Put(ctx context.Context, key string, val string) {
-	// Nacos 使用服务注册而不是键值存储
-	n.log.Errorf("nacos不支持Put操作，请使用Publisher注册服务")
+	n.log.Infof("发布nacos配置: %s = %s", key, val)
+
+	if n.configClient == nil {
+		n.log.Errorf("nacos配置客户端为空")
+		return
+	}
+
+	assert.ShouldCall1RE(n.configClient.PublishConfig, vo.ConfigParam{
+		DataId:  config.NacosDataID(key),
+		Group:   n.cnf.GetGroupName(),
+		Content: val,
+	}, "发布nacos配置失败")
 }
 
-// Watch 监听服务变化
+// Watch 监听 dataId 前缀下的配置变化, 返回 <-chan KVEvent
 func (n *NacosRegistry) Watch(ctx context.Context, prefix string) any {
-	n.log.Infof("开始监听nacos服务变化: %s", prefix)
+	return n.WatchTyped(ctx, prefix)
+}
 
-	assert.ShouldCall1E(n.namingClient.Subscribe, &vo.SubscribeParam{
-		ServiceName: prefix,
-		GroupName:   n.cnf.GetGroupName(),
-		Clusters:    []string{n.cnf.GetClusterName()},
-		SubscribeCallback: func(services []model.Instance, err error) {
-			if err != nil {
-				n.log.Errorf("nacos服务变化回调错误: %v", err)
-				return
-			}
-			n.log.Infof("nacos服务变化: %v", services)
-		},
-	}, "订阅nacos服务失败")
+// WatchTyped 监听 dataId 前缀下的配置变化(类型安全版本), 事件的 Key 为 dataId, ctx 取消后关闭通道
+//
+// 已有配置的修改由 nacos 推送, 新增和删除的配置按 WatchInterval 定期扫描发现
+func (n *NacosRegistry) WatchTyped(ctx context.Context, prefix string) <-chan KVEvent {
+	n.log.Infof("开始监听nacos配置变化，前缀: %s", prefix)
+
+	ch := make(chan KVEvent, 16)
+	if n.configClient == nil {
+		n.log.Errorf("nacos配置客户端为空")
+		close(ch)
+		return ch
+	}
+
+	w := &nacosWatcher{
+		client:  n.configClient,
+		prefix:  config.NacosDataID(prefix),
+		group:   n.cnf.GetGroupName(),
+		log:     n.log,
+		known:   make(map[string][md5.Size]byte),
+		changes: make(chan nacosChange, 16),
+		out:     ch,
+	}
+	// 注册中心关闭时同样停止监听
+	watchCtx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(n.ctx, cancel)
+	go logger.WithRecover(n.log, func() {
+		defer stop()
+		defer cancel()
+		w.run(watchCtx, n.cnf.GetWatchInterval())
+	})
 
-	return nil
+	return ch
 }
 
 // Close 关闭nacos客户端
@@ -170,6 +243,10 @@ func (n *NacosRegistry) Close() {
 	// 注销服务
 	n.Deregister()
 
+	assert.MayTrue(n.configClient != nil, func() {
+		n.configClient.CloseClient()
+	})
+
 	n.log.Infof("nacos注册中心关闭成功")
 }
 
@@ -197,3 +274,80 @@ func (n *NacosRegistry) Refresh() {
 func (n *NacosRegistry) GetLeaseID() uint64 {
 	return 0
 }
+
+// nacosInstances 转换服务实例列表
+func nacosInstances(name string, list []model.Instance) []ServiceInstance {
+	instances := make([]ServiceInstance, 0, len(list))
+	for _, instance := range list {
+		instances = append(instances, nacosInstance(name, instance))
+	}
+	return instances
+}
+
+// Discover 获取服务的全部实例(包括不健康的实例, 通过 Healthy 区分)
+func (n *NacosRegistry) Discover(ctx context.Context, name string) ([]ServiceInstance, error) {
+	list, err := n.namingClient.SelectAllInstances(vo.SelectAllInstancesParam{
+		ServiceName: name,
+		GroupName:   n.cnf.GetGroupName(),
+		Clusters:    []string{n.cnf.GetClusterName()},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取nacos服务实例失败: %w", err)
+	}
+	return nacosInstances(name, list), nil
+}
+
+// Subscribe 订阅服务实例变化, ctx 取消后取消订阅
+func (n *NacosRegistry) Subscribe(ctx context.Context, name string) (<-chan []ServiceInstance, error) {
+	instances, err := n.Discover(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan []ServiceInstance, 1)
+	ch <- instances
+
+	var mu sync.Mutex
+	closed := false
+
+	param := &vo.SubscribeParam{
+		ServiceName: name,
+		GroupName:   n.cnf.GetGroupName(),
+		Clusters:    []string{n.cnf.GetClusterName()},
+		SubscribeCallback: func(services []model.Instance, err error) {
+			if err != nil {
+				n.log.Errorf("订阅nacos服务失败，服务: %s, 错误: %v", name, err)
+				return
+			}
+
+			// 回调与关闭通道可能并发, 持锁推送
+			mu.Lock()
+			defer mu.Unlock()
+			if !closed {
+				sendInstances(ctx, ch, nacosInstances(name, services))
+			}
+		},
+	}
+
+	if err := n.namingClient.Subscribe(param); err != nil {
+		return nil, fmt.Errorf("订阅nacos服务失败: %w", err)
+	}
+
+	go logger.WithRecover(n.log, func() {
+		select {
+		case <-ctx.Done():
+		case <-n.ctx.Done():
+		}
+
+		if err := n.namingClient.Unsubscribe(param); err != nil {
+			n.log.Errorf("取消订阅nacos服务失败，服务: %s, 错误: %v", name, err)
+		}
+
+		mu.Lock()
+		closed = true
+		close(ch)
+		mu.Unlock()
+	})
+
+	return ch, nil
+}
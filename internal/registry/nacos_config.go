@@ -2,6 +2,7 @@ package registry
 
 import (
 	"errors"
+	"time"
 )
 
 var (
@@ -13,25 +14,26 @@ var (
 
 // NacosConfig nacos配置
 type NacosConfig struct {
-	Hosts       []string          `yaml:"hosts"`       // nacos服务器地址列表
-	Port        uint64            `yaml:"port"`        // nacos端口，默认8848
-	NamespaceId string            `yaml:"namespaceId"` // 命名空间ID
-	GroupName   string            `yaml:"groupName"`   // 分组名称，默认DEFAULT_GROUP
-	ClusterName string            `yaml:"clusterName"` // 集群名称，默认DEFAULT
-	ServiceName string            `yaml:"serviceName"` // 服务名称
-	IP          string            `yaml:"ip"`          // 服务IP
-	ServicePort uint64            `yaml:"servicePort"` // 服务端口
-	Weight      float64           `yaml:"weight"`      // 权重，默认1.0
-	Enable      bool              `yaml:"enable"`      // 是否启用，默认true
-	Healthy     bool              `yaml:"healthy"`     // 是否健康，默认true
-	Ephemeral   bool              `yaml:"ephemeral"`   // 是否临时实例，默认true
-	Metadata    map[string]string `yaml:"metadata"`    // 元数据
-	Username    string            `yaml:"username"`    // 用户名
-	Password    string            `yaml:"password"`    // 密码
-	LogLevel    string            `yaml:"logLevel"`    // 日志级别
-	ContextPath string            `yaml:"contextPath"` // 上下文路径
-	CacheDir    string            `yaml:"cacheDir"`    // 缓存目录
-	LogDir      string            `yaml:"logDir"`      // 日志目录
+	Hosts         []string          `yaml:"hosts"`         // nacos服务器地址列表
+	Port          uint64            `yaml:"port"`          // nacos端口，默认8848
+	NamespaceId   string            `yaml:"namespaceId"`   // 命名空间ID
+	GroupName     string            `yaml:"groupName"`     // 分组名称，默认DEFAULT_GROUP
+	ClusterName   string            `yaml:"clusterName"`   // 集群名称，默认DEFAULT
+	ServiceName   string            `yaml:"serviceName"`   // 服务名称
+	IP            string            `yaml:"ip"`            // 服务IP
+	ServicePort   uint64            `yaml:"servicePort"`   // 服务端口
+	Weight        float64           `yaml:"weight"`        // 权重，默认1.0
+	Enable        bool              `yaml:"enable"`        // 是否启用，默认true
+	Healthy       bool              `yaml:"healthy"`       // 是否健康，默认true
+	Ephemeral     bool              `yaml:"ephemeral"`     // 是否临时实例，默认true
+	Metadata      map[string]string `yaml:"metadata"`      // 元数据
+	Username      string            `yaml:"username"`      // 用户名
+	Password      string            `yaml:"password"`      // 密码
+	LogLevel      string            `yaml:"logLevel"`      // 日志级别
+	ContextPath   string            `yaml:"contextPath"`   // 上下文路径
+	CacheDir      string            `yaml:"cacheDir"`      // 缓存目录
+	LogDir        string            `yaml:"logDir"`        // 日志目录
+	WatchInterval int               `yaml:"watchInterval"` // 前缀监听扫描新增、删除配置的间隔（秒），默认10秒
 }
 
 // Validate 验证配置
@@ -96,3 +98,11 @@ func (c *NacosConfig) IsEphemeral() bool {
 func (c *NacosConfig) HasAuth() bool {
 	return c.Username != "" && c.Password != ""
 }
+
+// GetWatchInterval 获取前缀监听的扫描间隔，默认10秒
+func (c *NacosConfig) GetWatchInterval() time.Duration {
+	if c.WatchInterval <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(c.WatchInterval) * time.Second
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/nacos-group/nacos-sdk-go/v2/model"
 	"github.com/spelens-gud/assert"
 	"github.com/spelens-gud/logger"
+	"github.com/spelens-gud/trunk/internal/config"
 )
 
 // 测试用的 nacos 配置
@@ -30,11 +31,12 @@ var testNacosConfig = &NacosConfig{
 		"version": "1.0.0",
 		"env":     "test",
 	},
-	LogLevel: "error",
-	CacheDir: "./cache",
-	LogDir:   "./logs",
-	Username: "nacos",
-	Password: "nacos",
+	LogLevel:      "error",
+	CacheDir:      "./cache",
+	LogDir:        "./logs",
+	Username:      "nacos",
+	Password:      "nacos",
+	WatchInterval: 1,
 }
 
 // 创建测试用的 NacosRegistry 实例
@@ -133,35 +135,24 @@ func TestNacosRegistry_Publisher(t *testing.T) {
 	t.Log("服务注册成功")
 }
 
-// TestNacosRegistry_GetValue 测试获取单个服务实例
+// TestNacosRegistry_GetValue 测试发布和读取配置
 func TestNacosRegistry_GetValue(t *testing.T) {
 	registry := newTestNacosRegistry(t)
 	defer registry.Close()
 
-	// 先注册服务
-	registry.Publisher("")
+	key := "/test/registry/config"
+	registry.Put(context.Background(), key, "value1")
 
-	//// 等待服务就绪
-	//if !waitForServiceReady(t, registry, testNacosConfig.ServiceName, 5*time.Second) {
-	//	t.Fatal("超时：无法获取到服务实例，请检查 Nacos 服务是否正常")
-	//}
-
-	// 等待服务就绪
-	time.Sleep(700 * time.Millisecond)
-
-	// 获取服务实例
-	value := registry.GetValue(testNacosConfig.ServiceName)
-	if value == "" {
-		t.Fatal("服务就绪后仍无法获取实例")
-	}
-
-	t.Logf("获取到服务实例: %s", value)
-
-	// 验证返回格式为 IP:Port
-	expectedFormat := fmt.Sprintf("%s:%d", testNacosConfig.IP, testNacosConfig.ServicePort)
-	if value != expectedFormat {
-		t.Logf("服务实例格式: 期望 %s, 实际 %s", expectedFormat, value)
+	// 发布后服务端需要一点时间生效
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if value := registry.GetValue(key); value == "value1" {
+			t.Logf("获取到配置: %s", value)
+			return
+		}
+		time.Sleep(200 * time.Millisecond)
 	}
+	t.Fatal("发布后仍无法获取配置")
 }
 
 // TestNacosRegistry_GetValues 测试获取所有服务实例
@@ -230,8 +221,7 @@ func TestNacosRegistry_Refresh(t *testing.T) {
 	time.Sleep(500 * time.Millisecond)
 
 	// 验证服务仍然存在
-	value := registry.GetValue(testNacosConfig.ServiceName)
-	if value == "" {
+	if !waitForServiceReady(t, registry, testNacosConfig.ServiceName, 5*time.Second) {
 		t.Error("刷新后服务应该仍然存在")
 	}
 
@@ -265,30 +255,32 @@ func TestNacosRegistry_GetLeaseID(t *testing.T) {
 	t.Log("租约ID检查通过")
 }
 
-// TestNacosRegistry_Watch 测试监听服务变化
+// TestNacosRegistry_Watch 测试监听配置变化
 func TestNacosRegistry_Watch(t *testing.T) {
 	registry := newTestNacosRegistry(t)
 	defer registry.Close()
 
-	// 先注册服务
-	registry.Publisher("")
-
-	// 等待服务就绪
-	//if !waitForServiceReady(t, registry, testNacosConfig.ServiceName, 5*time.Second) {
-	//	t.Fatal("服务注册失败")
-	//}
-	time.Sleep(700 * time.Millisecond)
-
-	// 启动监听
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	registry.Watch(ctx, testNacosConfig.ServiceName)
+	prefix := fmt.Sprintf("/test/registry/watch-%d", time.Now().UnixNano())
+	events := registry.WatchTyped(ctx, prefix)
 
-	// 等待监听启动
+	// 等待首次扫描完成, 新增的配置在下一次扫描时发现
 	time.Sleep(500 * time.Millisecond)
+	registry.Put(ctx, prefix+"/key", "value1")
 
-	t.Log("服务监听启动成功")
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("监听通道被关闭")
+		}
+		if event.Type != KVPut || event.Key != config.NacosDataID(prefix+"/key") || event.Value != "value1" {
+			t.Fatalf("事件不符合预期: %+v", event)
+		}
+	case <-ctx.Done():
+		t.Fatal("等待配置变化超时")
+	}
 }
 
 // TestNacosRegistry_ConcurrentAccess 测试并发访问
@@ -351,8 +343,7 @@ func TestNacosRegistry_ConcurrentPublisher(t *testing.T) {
 	wg.Wait()
 
 	// 验证服务存在
-	value := registry.GetValue(testNacosConfig.ServiceName)
-	if value == "" {
+	if !waitForServiceReady(t, registry, testNacosConfig.ServiceName, 5*time.Second) {
 		t.Error("并发注册后服务应该存在")
 	}
 
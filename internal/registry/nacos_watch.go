@@ -0,0 +1,187 @@
+package registry
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"time"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/clients/config_client"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+	"github.com/spelens-gud/logger"
+)
+
+// nacosSearchPageSize 扫描配置的分页大小
+const nacosSearchPageSize = 100
+
+// nacosChange nacos 推送的配置变化
+type nacosChange struct {
+	dataID  string
+	content string
+}
+
+// nacosWatcher 监听 dataId 前缀下的配置, nacos 只支持按 dataId 监听, 新增和删除的配置靠定期扫描发现
+type nacosWatcher struct {
+	client  config_client.IConfigClient
+	prefix  string
+	group   string
+	log     logger.ILogger
+	known   map[string][md5.Size]byte // dataId -> 内容摘要, 只在 run 协程中访问
+	changes chan nacosChange          // 推送的变化
+	out     chan KVEvent
+}
+
+// run 监听直到 ctx 取消, 退出时取消全部监听并关闭输出通道
+func (w *nacosWatcher) run(ctx context.Context, interval time.Duration) {
+	defer close(w.out)
+	defer w.cancelAll()
+
+	// 首次扫描只记录已有配置, 与 etcd 一样只推送之后的变化
+	contents, err := w.scan()
+	if err != nil {
+		w.log.Errorf("扫描nacos配置失败，前缀: %s, 错误: %v", w.prefix, err)
+	}
+	for dataID, content := range contents {
+		w.known[dataID] = md5.Sum([]byte(content))
+		w.listen(ctx, dataID)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case change := <-w.changes:
+			if !w.handleChange(ctx, change) {
+				return
+			}
+		case <-ticker.C:
+			if !w.rescan(ctx) {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// scan 分页查询前缀下的全部配置
+func (w *nacosWatcher) scan() (map[string]string, error) {
+	contents := make(map[string]string)
+	for page := 1; ; page++ {
+		result, err := w.client.SearchConfig(vo.SearchConfigParam{
+			Search:   "blur",
+			DataId:   w.prefix + "*",
+			Group:    w.group,
+			PageNo:   page,
+			PageSize: nacosSearchPageSize,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("查询nacos配置失败: %w", err)
+		}
+
+		for _, item := range result.PageItems {
+			contents[item.DataId] = item.Content
+		}
+		if len(result.PageItems) == 0 || page >= result.PagesAvailable {
+			return contents, nil
+		}
+	}
+}
+
+// rescan 扫描新增、修改和删除的配置, 扫描失败时等待下一次扫描
+func (w *nacosWatcher) rescan(ctx context.Context) bool {
+	contents, err := w.scan()
+	if err != nil {
+		w.log.Errorf("扫描nacos配置失败，前缀: %s, 错误: %v", w.prefix, err)
+		return true
+	}
+
+	for dataID, content := range contents {
+		_, listening := w.known[dataID]
+		if !w.update(ctx, dataID, content) {
+			return false
+		}
+		if !listening {
+			w.listen(ctx, dataID)
+		}
+	}
+
+	for dataID := range w.known {
+		if _, ok := contents[dataID]; !ok {
+			if !w.remove(ctx, dataID) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// handleChange 处理推送的变化, 配置被删除时推送的内容为空
+func (w *nacosWatcher) handleChange(ctx context.Context, change nacosChange) bool {
+	if _, ok := w.known[change.dataID]; !ok {
+		return true
+	}
+	if change.content == "" {
+		return w.remove(ctx, change.dataID)
+	}
+	return w.update(ctx, change.dataID, change.content)
+}
+
+// update 内容变化时推送更新事件
+func (w *nacosWatcher) update(ctx context.Context, dataID, content string) bool {
+	sum := md5.Sum([]byte(content))
+	if old, ok := w.known[dataID]; ok && old == sum {
+		return true
+	}
+	w.known[dataID] = sum
+	return w.emit(ctx, KVEvent{Type: KVPut, Key: dataID, Value: content})
+}
+
+// remove 取消监听并推送删除事件
+func (w *nacosWatcher) remove(ctx context.Context, dataID string) bool {
+	delete(w.known, dataID)
+	w.cancelListen(dataID)
+	return w.emit(ctx, KVEvent{Type: KVDelete, Key: dataID})
+}
+
+// emit 推送事件, ctx 取消时返回 false
+func (w *nacosWatcher) emit(ctx context.Context, event KVEvent) bool {
+	select {
+	case w.out <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// listen 监听单个配置, 回调在 sdk 的协程中触发, 转发给 run 协程处理
+func (w *nacosWatcher) listen(ctx context.Context, dataID string) {
+	err := w.client.ListenConfig(vo.ConfigParam{
+		DataId: dataID,
+		Group:  w.group,
+		OnChange: func(_, _, dataID, data string) {
+			select {
+			case w.changes <- nacosChange{dataID: dataID, content: data}:
+			case <-ctx.Done():
+			}
+		},
+	})
+	if err != nil {
+		w.log.Errorf("监听nacos配置失败，dataId: %s, 错误: %v", dataID, err)
+	}
+}
+
+// cancelListen 取消监听单个配置
+func (w *nacosWatcher) cancelListen(dataID string) {
+	if err := w.client.CancelListenConfig(vo.ConfigParam{DataId: dataID, Group: w.group}); err != nil {
+		w.log.Warnf("取消监听nacos配置失败，dataId: %s, 错误: %v", dataID, err)
+	}
+}
+
+// cancelAll 取消全部监听
+func (w *nacosWatcher) cancelAll() {
+	for dataID := range w.known {
+		w.cancelListen(dataID)
+	}
+}
@@ -0,0 +1,326 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spelens-gud/logger"
+	"github.com/spelens-gud/trunk/internal/version"
+)
+
+// ServiceKind 服务类型
+type ServiceKind string
+
+const (
+	KindGate   ServiceKind = "gate"   // 网关服
+	KindCenter ServiceKind = "center" // 中心服
+	KindFight  ServiceKind = "fight"  // 战斗服
+	KindFriend ServiceKind = "friend" // 好友服
+	KindNode   ServiceKind = "node"   // 节点服
+)
+
+// InstanceState 实例的生命周期状态
+type InstanceState string
+
+const (
+	StateStarting InstanceState = "starting" // 启动中, 尚未对外服务
+	StateServing  InstanceState = "serving"  // 正常服务
+	StateDraining InstanceState = "draining" // 下线中, 不再接收新的连接
+)
+
+// 注册记录在元数据中的键, 各注册中心使用相同的键
+const (
+	MetaKind       = "kind"
+	MetaVersion    = "version"
+	MetaGitCommit  = "gitCommit"
+	MetaZone       = "zone"
+	MetaState      = "state"
+	MetaTransports = "transports" // 格式为 ws:30999,quic:31000
+)
+
+// errNotRegistration 注册的值不是注册记录
+var errNotRegistration = errors.New("not a registration record")
+
+// Transport 对外暴露的传输协议和端口
+type Transport struct {
+	Protocol string // 协议，如 ws、quic、grpc、tars
+	Port     int    // 端口
+}
+
+// String 格式为 protocol:port
+func (t Transport) String() string {
+	return t.Protocol + ":" + strconv.Itoa(t.Port)
+}
+
+// Registration 服务注册记录, 各注册中心按相同的格式编码:
+// etcd 和内存注册中心的值为 ServiceInstance 的 JSON, consul 和 nacos 写入服务的元数据
+type Registration struct {
+	Kind       ServiceKind       // 服务类型
+	ID         string            // 实例ID
+	Address    string            // 地址
+	Version    string            // 构建版本号
+	GitCommit  string            // 构建的 Git 提交
+	Zone       string            // 可用区
+	Weight     float64           // 权重
+	Transports []Transport       // 对外暴露的传输协议, 第一个为主端口
+	State      InstanceState     // 生命周期状态
+	Metadata   map[string]string // 自定义元数据
+}
+
+// NewRegistration 创建注册记录, 版本号取自构建信息, 初始状态为启动中
+func NewRegistration(kind ServiceKind, id, address string, transports ...Transport) *Registration {
+	return &Registration{
+		Kind:       kind,
+		ID:         id,
+		Address:    address,
+		Version:    version.Version,
+		GitCommit:  version.GitCommit,
+		Weight:     1,
+		Transports: transports,
+		State:      StateStarting,
+	}
+}
+
+// Meta 转换为元数据, 注册记录的字段覆盖自定义元数据中的同名键
+func (r *Registration) Meta() map[string]string {
+	meta := make(map[string]string, len(r.Metadata)+6)
+	maps.Copy(meta, r.Metadata)
+
+	meta[MetaKind] = string(r.Kind)
+	meta[MetaVersion] = r.Version
+	meta[MetaGitCommit] = r.GitCommit
+	meta[MetaState] = string(r.State)
+	if r.Zone != "" {
+		meta[MetaZone] = r.Zone
+	}
+	if len(r.Transports) > 0 {
+		transports := make([]string, 0, len(r.Transports))
+		for _, t := range r.Transports {
+			transports = append(transports, t.String())
+		}
+		meta[MetaTransports] = strings.Join(transports, ",")
+	}
+	return meta
+}
+
+// Instance 转换为服务实例, 端口为第一个传输协议的端口
+func (r *Registration) Instance() ServiceInstance {
+	instance := ServiceInstance{
+		ID:       r.ID,
+		Name:     string(r.Kind),
+		Address:  r.Address,
+		Weight:   r.Weight,
+		Metadata: r.Meta(),
+		Healthy:  true,
+	}
+	if len(r.Transports) > 0 {
+		instance.Port = r.Transports[0].Port
+	}
+	return instance
+}
+
+// Encode 编码为 Publisher 的值
+func (r *Registration) Encode() string {
+	data, _ := json.Marshal(r.Instance())
+	return string(data)
+}
+
+// ParseRegistration 解析 Encode 编码的值, 不是注册记录时返回错误
+func ParseRegistration(value string) (*Registration, error) {
+	var instance ServiceInstance
+	if err := json.Unmarshal([]byte(value), &instance); err != nil {
+		return nil, fmt.Errorf("解析注册记录失败: %w", err)
+	}
+	if instance.Metadata[MetaKind] == "" {
+		return nil, errNotRegistration
+	}
+
+	r := &Registration{
+		Kind:       instance.Kind(),
+		ID:         instance.ID,
+		Address:    instance.Address,
+		Version:    instance.Version(),
+		GitCommit:  instance.Metadata[MetaGitCommit],
+		Zone:       instance.Zone(),
+		Weight:     instance.Weight,
+		Transports: instance.Transports(),
+		State:      instance.State(),
+		Metadata:   make(map[string]string, len(instance.Metadata)),
+	}
+	for key, value := range instance.Metadata {
+		if !isRegistrationMeta(key) {
+			r.Metadata[key] = value
+		}
+	}
+	return r, nil
+}
+
+// isRegistrationMeta 是否为注册记录使用的元数据键
+func isRegistrationMeta(key string) bool {
+	switch key {
+	case MetaKind, MetaVersion, MetaGitCommit, MetaZone, MetaState, MetaTransports:
+		return true
+	}
+	return false
+}
+
+// mergeMeta 合并配置中的元数据和注册记录的元数据, 注册记录优先
+func mergeMeta(config map[string]string, r *Registration) map[string]string {
+	meta := maps.Clone(config)
+	if meta == nil {
+		meta = make(map[string]string)
+	}
+	maps.Copy(meta, r.Meta())
+	return meta
+}
+
+// Kind 服务类型
+func (s ServiceInstance) Kind() ServiceKind {
+	return ServiceKind(s.Metadata[MetaKind])
+}
+
+// Version 构建版本号
+func (s ServiceInstance) Version() string {
+	return s.Metadata[MetaVersion]
+}
+
+// Zone 可用区
+func (s ServiceInstance) Zone() string {
+	return s.Metadata[MetaZone]
+}
+
+// State 生命周期状态, 未使用注册记录注册的实例视为正常服务
+func (s ServiceInstance) State() InstanceState {
+	if state := s.Metadata[MetaState]; state != "" {
+		return InstanceState(state)
+	}
+	return StateServing
+}
+
+// IsDraining 是否正在下线
+func (s ServiceInstance) IsDraining() bool {
+	return s.State() == StateDraining
+}
+
+// Transports 对外暴露的传输协议, 忽略格式错误的项
+func (s ServiceInstance) Transports() []Transport {
+	raw := s.Metadata[MetaTransports]
+	if raw == "" {
+		return nil
+	}
+
+	var transports []Transport
+	for item := range strings.SplitSeq(raw, ",") {
+		protocol, port, ok := strings.Cut(strings.TrimSpace(item), ":")
+		if !ok {
+			continue
+		}
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			continue
+		}
+		transports = append(transports, Transport{Protocol: protocol, Port: p})
+	}
+	return transports
+}
+
+// TransportPort 指定传输协议的端口, 未暴露该协议时返回 0
+func (s ServiceInstance) TransportPort(protocol string) int {
+	for _, t := range s.Transports() {
+		if t.Protocol == protocol {
+			return t.Port
+		}
+	}
+	return 0
+}
+
+// InstanceFilter 实例过滤条件, 返回 true 表示保留
+type InstanceFilter func(instance ServiceInstance) bool
+
+// ByVersion 只保留指定版本的实例
+func ByVersion(versions ...string) InstanceFilter {
+	return func(instance ServiceInstance) bool {
+		return slices.Contains(versions, instance.Version())
+	}
+}
+
+// ByZone 只保留指定可用区的实例
+func ByZone(zones ...string) InstanceFilter {
+	return func(instance ServiceInstance) bool {
+		return slices.Contains(zones, instance.Zone())
+	}
+}
+
+// NotDraining 跳过正在下线的实例
+func NotDraining() InstanceFilter {
+	return func(instance ServiceInstance) bool {
+		return !instance.IsDraining()
+	}
+}
+
+// FilterInstances 返回满足全部条件的实例
+func FilterInstances(instances []ServiceInstance, filters ...InstanceFilter) []ServiceInstance {
+	result := make([]ServiceInstance, 0, len(instances))
+	for _, instance := range instances {
+		if matchAll(instance, filters) {
+			result = append(result, instance)
+		}
+	}
+	return result
+}
+
+// matchAll 是否满足全部条件
+func matchAll(instance ServiceInstance, filters []InstanceFilter) bool {
+	for _, filter := range filters {
+		if !filter(instance) {
+			return false
+		}
+	}
+	return true
+}
+
+// filteredDiscovery 按条件过滤实例的服务发现
+type filteredDiscovery struct {
+	Discovery
+	filters []InstanceFilter
+	log     logger.ILogger
+}
+
+// FilterDiscovery 包装服务发现, Discover 和 Subscribe 只返回满足全部条件的实例
+func FilterDiscovery(d Discovery, log logger.ILogger, filters ...InstanceFilter) Discovery {
+	return &filteredDiscovery{Discovery: d, filters: filters, log: log}
+}
+
+// Discover 获取满足条件的实例
+func (f *filteredDiscovery) Discover(ctx context.Context, name string) ([]ServiceInstance, error) {
+	instances, err := f.Discovery.Discover(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return FilterInstances(instances, f.filters...), nil
+}
+
+// Subscribe 订阅满足条件的实例变化
+func (f *filteredDiscovery) Subscribe(ctx context.Context, name string) (<-chan []ServiceInstance, error) {
+	upstream, err := f.Discovery.Subscribe(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan []ServiceInstance, 1)
+	go logger.WithRecover(f.log, func() {
+		defer close(ch)
+		for instances := range upstream {
+			if !sendInstances(ctx, ch, FilterInstances(instances, f.filters...)) {
+				return
+			}
+		}
+	})
+	return ch, nil
+}
@@ -0,0 +1,109 @@
+package registry
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// TestRegistration_Encode 测试注册记录的编码、解析和服务实例上的字段
+func TestRegistration_Encode(t *testing.T) {
+	r := NewRegistration(KindGate, "gate-1", "10.0.0.1", Transport{Protocol: "ws", Port: 30999}, Transport{Protocol: "quic", Port: 31000})
+	r.Zone = "sh-1"
+	r.Weight = 2
+	r.Metadata = map[string]string{"region": "cn", MetaState: "ignored"}
+
+	instance := parseEtcdInstance("/services/gate", "1", []byte(r.Encode()))
+	if instance.ID != "gate-1" || instance.Port != 30999 || instance.Weight != 2 || !instance.Healthy {
+		t.Fatalf("服务实例错误: %+v", instance)
+	}
+	if instance.Kind() != KindGate || instance.Zone() != "sh-1" || instance.State() != StateStarting {
+		t.Errorf("元数据错误: %+v", instance.Metadata)
+	}
+	if port := instance.TransportPort("quic"); port != 31000 {
+		t.Errorf("quic 端口期望 31000, 实际 %d", port)
+	}
+
+	parsed, err := ParseRegistration(r.Encode())
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	r.Metadata = map[string]string{"region": "cn"}
+	if !reflect.DeepEqual(parsed, r) {
+		t.Errorf("解析结果不一致:\n期望 %+v\n实际 %+v", r, parsed)
+	}
+
+	for _, value := range []string{"127.0.0.1:9001", `{"id":"a","address":"10.0.0.1"}`} {
+		if _, err := ParseRegistration(value); err == nil {
+			t.Errorf("%s 不是注册记录, 应返回错误", value)
+		}
+	}
+
+	// 未使用注册记录注册的实例视为正常服务
+	if state := (ServiceInstance{}).State(); state != StateServing {
+		t.Errorf("期望 serving, 实际 %s", state)
+	}
+}
+
+// TestFilterDiscovery 测试按版本、可用区过滤实例并跳过下线中的实例
+func TestFilterDiscovery(t *testing.T) {
+	store := NewMemoryStore()
+	client := newTestMemoryRegistry(t, store, "")
+	defer client.Close()
+
+	publish := func(id, version, zone string) *InMemoryRegistry {
+		reg := newTestMemoryRegistry(t, store, "/services/gate")
+		r := NewRegistration(KindGate, id, "10.0.0.1", Transport{Protocol: "ws", Port: 9000})
+		r.Version, r.Zone, r.State = version, zone, StateServing
+		reg.Publisher(r.Encode())
+		return reg
+	}
+	a := publish("a", "1.0.0", "sh-1")
+	defer a.Close()
+	b := publish("b", "1.1.0", "sh-1")
+	defer b.Close()
+	c := publish("c", "1.1.0", "sh-2")
+	defer c.Close()
+
+	ctx := context.Background()
+	ids := func(d Discovery) []string {
+		instances, err := d.Discover(ctx, "/services/gate")
+		if err != nil {
+			t.Fatalf("服务发现失败: %v", err)
+		}
+		var result []string
+		for _, instance := range instances {
+			result = append(result, instance.ID)
+		}
+		return result
+	}
+
+	if got := ids(FilterDiscovery(client, client.log, ByVersion("1.1.0"))); !reflect.DeepEqual(got, []string{"b", "c"}) {
+		t.Errorf("按版本过滤期望 [b c], 实际 %v", got)
+	}
+	if got := ids(FilterDiscovery(client, client.log, ByVersion("1.1.0"), ByZone("sh-1"))); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Errorf("按版本和可用区过滤期望 [b], 实际 %v", got)
+	}
+
+	// 在原租约下更新为下线中
+	leaseID := b.GetLeaseID()
+	r := NewRegistration(KindGate, "b", "10.0.0.1", Transport{Protocol: "ws", Port: 9000})
+	r.Version, r.Zone, r.State = "1.1.0", "sh-1", StateDraining
+	b.Publisher(r.Encode())
+	if b.GetLeaseID() != leaseID {
+		t.Error("更新注册的值不应创建新的租约")
+	}
+	if got := ids(FilterDiscovery(client, client.log, NotDraining())); !reflect.DeepEqual(got, []string{"a", "c"}) {
+		t.Errorf("跳过下线中的实例期望 [a c], 实际 %v", got)
+	}
+
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ch, err := FilterDiscovery(client, client.log, NotDraining(), ByZone("sh-1")).Subscribe(subCtx, "/services/gate")
+	if err != nil {
+		t.Fatalf("订阅失败: %v", err)
+	}
+	if instances := <-ch; len(instances) != 1 || instances[0].ID != "a" {
+		t.Errorf("订阅期望 [a], 实际 %+v", instances)
+	}
+}
@@ -2,9 +2,11 @@ package registry
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/spelens-gud/assert"
 	"github.com/spelens-gud/logger"
+	"github.com/spf13/viper"
 )
 
 type IRegistry interface {
@@ -14,10 +16,17 @@ type IRegistry interface {
 	CreateNacosRegistry(config *NacosConfig) (Registry, error)
 	// CreateConsulRegistry 创建Consul注册中心实例
 	CreateConsulRegistry(config *ConsulConfig) (Registry, error)
+	// CreateMemoryRegistry 创建内存注册中心实例
+	CreateMemoryRegistry(config *MemoryConfig) (Registry, error)
+	// CreateMultiRegistry 创建多注册中心镜像实例
+	CreateMultiRegistry(config *MultiConfig) (Registry, error)
+	// CreateFromViper 按配置段中的 type 创建注册中心实例
+	CreateFromViper(v *viper.Viper, key string) (Registry, error)
 }
 
 // Registry 注册中心接口
 type Registry interface {
+	Discovery
 	// New 初始化注册中心客户端
 	New()
 	// Publisher 发布/注册服务
@@ -95,3 +104,54 @@ func (f *GRegistryFactory) CreateConsulRegistry(config *ConsulConfig) (Registry,
 
 	return registry, nil
 }
+
+// CreateMemoryRegistry 创建内存注册中心
+func (f *GRegistryFactory) CreateMemoryRegistry(config *MemoryConfig) (Registry, error) {
+	// 必须验证配置,否则阻断程序
+	assert.MustCall0E(config.Validate)
+	registry := &InMemoryRegistry{
+		log: f.log,
+		cnf: config,
+	}
+
+	registry.New()
+
+	return registry, nil
+}
+
+// CreateMultiRegistry 按 Backends 创建各注册中心并组合为多注册中心, 任一注册中心创建失败时关闭已创建的注册中心
+func (f *GRegistryFactory) CreateMultiRegistry(config *MultiConfig) (Registry, error) {
+	if err := config.validateBackends(); err != nil {
+		return nil, fmt.Errorf("多注册中心配置错误: %w", err)
+	}
+
+	names := config.backendNames()
+	backends := make([]MultiBackend, 0, len(config.Backends))
+	closeAll := func() {
+		for _, b := range backends {
+			b.Registry.Close()
+		}
+	}
+
+	for i, c := range config.Backends {
+		reg, err := f.Create(c)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("创建注册中心 %s 失败: %w", names[i], err)
+		}
+
+		backend := MultiBackend{Name: names[i], Registry: reg}
+		// etcd 和内存注册中心按键的层级发现服务
+		if (c.Type == TypeEtcd || c.Type == TypeMemory) && (c.ProjectPrefix != "" || c.ServerPrefix != "") {
+			backend.Prefix = c.ServerKey("")
+		}
+		backends = append(backends, backend)
+	}
+
+	registry, err := NewMultiRegistry(config, f.log, backends...)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	return registry, nil
+}
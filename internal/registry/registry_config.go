@@ -0,0 +1,258 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/spf13/viper"
+)
+
+// 注册中心类型, 对应配置中的 type
+const (
+	TypeEtcd   = "etcd"
+	TypeConsul = "consul"
+	TypeNacos  = "nacos"
+	TypeMemory = "memory"
+	TypeMulti  = "multi"
+)
+
+// errEmptyRegistryType 注册中心类型为空
+var errEmptyRegistryType = errors.New("empty registry type")
+
+// errNestedMulti 多注册中心中嵌套多注册中心
+var errNestedMulti = errors.New("nested multi registry")
+
+// PrefixConfig 注册中心中键的层级, 如服务 gate 注册在 /wsh/moba/servers/gate 下
+type PrefixConfig struct {
+	ProjectPrefix string `yaml:"projectPrefix"` // 项目前缀，如 /wsh/moba
+	ServerPrefix  string `yaml:"serverPrefix"`  // 服务注册，如 servers
+	CommonPrefix  string `yaml:"commonPrefix"`  // 公共配置，如 common
+	MasterPrefix  string `yaml:"masterPrefix"`  // 选主，如 master
+	FriendPrefix  string `yaml:"friendPrefix"`  // 好友数据，如 friend
+	RoomPrefix    string `yaml:"roomPrefix"`    // 房间数据，如 room
+}
+
+// join 拼接项目前缀、层级和子键
+func (p *PrefixConfig) join(prefix string, parts ...string) string {
+	return path.Join(append([]string{"/", p.ProjectPrefix, prefix}, parts...)...)
+}
+
+// ServerKey 服务的注册键，如 /wsh/moba/servers/gate
+func (p *PrefixConfig) ServerKey(name string) string {
+	return p.join(p.ServerPrefix, name)
+}
+
+// CommonKey 公共配置的键，如 /wsh/moba/common
+func (p *PrefixConfig) CommonKey(parts ...string) string {
+	return p.join(p.CommonPrefix, parts...)
+}
+
+// MasterKey 选主的键，如 /wsh/moba/master/center
+func (p *PrefixConfig) MasterKey(name string) string {
+	return p.join(p.MasterPrefix, name)
+}
+
+// FriendKey 好友数据的键
+func (p *PrefixConfig) FriendKey(parts ...string) string {
+	return p.join(p.FriendPrefix, parts...)
+}
+
+// RoomKey 房间数据的键
+func (p *PrefixConfig) RoomKey(parts ...string) string {
+	return p.join(p.RoomPrefix, parts...)
+}
+
+// RegistryConfig 声明式的注册中心配置, type 选择注册中心, 同一配置段中的其他字段按类型解析为对应的配置
+//
+//	Registry:
+//	  type: "etcd"
+//	  name: "gate"
+//	  hosts: [ "127.0.0.1:2379" ]
+//	  projectPrefix: "/wsh/moba"
+//	  serverPrefix: "servers"
+type RegistryConfig struct {
+	Type         string `yaml:"type"` // 注册中心类型：etcd、consul、nacos、memory、multi
+	Name         string `yaml:"name"` // 服务名，未配置注册键或服务名时按层级生成
+	PrefixConfig `yaml:",inline"`
+	Etcd         *EtcdConfig   `yaml:"-"` // type 为 etcd 时的配置
+	Consul       *ConsulConfig `yaml:"-"` // type 为 consul 时的配置
+	Nacos        *NacosConfig  `yaml:"-"` // type 为 nacos 时的配置
+	Memory       *MemoryConfig `yaml:"-"` // type 为 memory 时的配置
+	Multi        *MultiConfig  `yaml:"-"` // type 为 multi 时的配置
+}
+
+// Validate 验证配置
+func (c *RegistryConfig) Validate() error {
+	var err error
+	switch {
+	case c.Type == "":
+		return errEmptyRegistryType
+	case c.Type == TypeEtcd && c.Etcd != nil:
+		err = c.Etcd.Validate()
+	case c.Type == TypeConsul && c.Consul != nil:
+		err = c.Consul.Validate()
+	case c.Type == TypeNacos && c.Nacos != nil:
+		err = c.Nacos.Validate()
+	case c.Type == TypeMemory && c.Memory != nil:
+		err = c.Memory.Validate()
+	case c.Type == TypeMulti && c.Multi != nil:
+		err = c.Multi.validateBackends()
+	case c.Type == TypeEtcd, c.Type == TypeConsul, c.Type == TypeNacos, c.Type == TypeMemory, c.Type == TypeMulti:
+		return fmt.Errorf("缺少%s注册中心配置", c.Type)
+	default:
+		return fmt.Errorf("不支持的注册中心类型: %s", c.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("%s注册中心配置错误: %w", c.Type, err)
+	}
+	return nil
+}
+
+// decodeYAMLTag 按 yaml 标签解析, 与配置文件中的字段名一致
+func decodeYAMLTag(dc *mapstructure.DecoderConfig) {
+	dc.TagName = "yaml"
+	dc.Squash = true
+}
+
+// LoadRegistryConfig 从 viper 的 key 配置段读取注册中心配置并验证
+func LoadRegistryConfig(v *viper.Viper, key string) (*RegistryConfig, error) {
+	sub := v.Sub(key)
+	if sub == nil {
+		return nil, fmt.Errorf("缺少注册中心配置: %s", key)
+	}
+
+	config, err := loadRegistryConfig(sub)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// loadRegistryConfig 按 type 解析配置段, 不验证
+func loadRegistryConfig(sub *viper.Viper) (*RegistryConfig, error) {
+	config := &RegistryConfig{}
+	if err := sub.Unmarshal(config, decodeYAMLTag); err != nil {
+		return nil, fmt.Errorf("解析注册中心配置失败: %w", err)
+	}
+
+	var target any
+	switch config.Type {
+	case TypeEtcd:
+		config.Etcd = &EtcdConfig{}
+		target = config.Etcd
+	case TypeConsul:
+		config.Consul = &ConsulConfig{}
+		target = config.Consul
+	case TypeNacos:
+		config.Nacos = &NacosConfig{}
+		target = config.Nacos
+	case TypeMemory:
+		config.Memory = &MemoryConfig{}
+		target = config.Memory
+	case TypeMulti:
+		config.Multi = &MultiConfig{}
+		target = config.Multi
+	}
+	if target != nil {
+		if err := sub.Unmarshal(target, decodeYAMLTag); err != nil {
+			return nil, fmt.Errorf("解析%s注册中心配置失败: %w", config.Type, err)
+		}
+	}
+	if config.Multi != nil {
+		backends, err := config.loadBackends(sub)
+		if err != nil {
+			return nil, err
+		}
+		config.Multi.Backends = backends
+	}
+	config.applyName()
+
+	return config, nil
+}
+
+// loadBackends 解析多注册中心的 backends, 未配置服务名和键的层级时继承外层配置
+func (c *RegistryConfig) loadBackends(sub *viper.Viper) ([]*RegistryConfig, error) {
+	items, ok := sub.Get("backends").([]any)
+	if !ok {
+		return nil, nil
+	}
+
+	backends := make([]*RegistryConfig, 0, len(items))
+	for i, item := range items {
+		values, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("多注册中心第%d个配置格式错误", i+1)
+		}
+
+		bv := viper.New()
+		if err := bv.MergeConfigMap(values); err != nil {
+			return nil, fmt.Errorf("解析多注册中心第%d个配置失败: %w", i+1, err)
+		}
+		bv.SetDefault("name", c.Name)
+		bv.SetDefault("projectPrefix", c.ProjectPrefix)
+		bv.SetDefault("serverPrefix", c.ServerPrefix)
+		bv.SetDefault("commonPrefix", c.CommonPrefix)
+		bv.SetDefault("masterPrefix", c.MasterPrefix)
+		bv.SetDefault("friendPrefix", c.FriendPrefix)
+		bv.SetDefault("roomPrefix", c.RoomPrefix)
+
+		backend, err := loadRegistryConfig(bv)
+		if err != nil {
+			return nil, err
+		}
+		backends = append(backends, backend)
+	}
+	return backends, nil
+}
+
+// applyName 未配置注册键或服务名时使用 name, etcd 和内存注册中心的键按层级生成
+func (c *RegistryConfig) applyName() {
+	if c.Name == "" {
+		return
+	}
+
+	switch {
+	case c.Etcd != nil && c.Etcd.Key == "":
+		c.Etcd.Key = c.ServerKey(c.Name)
+	case c.Memory != nil && c.Memory.Key == "":
+		c.Memory.Key = c.ServerKey(c.Name)
+	case c.Consul != nil && c.Consul.ServiceName == "":
+		c.Consul.ServiceName = c.Name
+	case c.Nacos != nil && c.Nacos.ServiceName == "":
+		c.Nacos.ServiceName = c.Name
+	}
+}
+
+// Create 按配置的类型创建注册中心, 配置错误时返回错误
+func (f *GRegistryFactory) Create(config *RegistryConfig) (Registry, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch config.Type {
+	case TypeEtcd:
+		return f.CreateEtcdRegistry(config.Etcd)
+	case TypeConsul:
+		return f.CreateConsulRegistry(config.Consul)
+	case TypeNacos:
+		return f.CreateNacosRegistry(config.Nacos)
+	case TypeMulti:
+		return f.CreateMultiRegistry(config.Multi)
+	default:
+		return f.CreateMemoryRegistry(config.Memory)
+	}
+}
+
+// CreateFromViper 读取 viper 中 key 配置段并创建注册中心, 配置错误时返回错误而不是中断程序
+func (f *GRegistryFactory) CreateFromViper(v *viper.Viper, key string) (Registry, error) {
+	config, err := LoadRegistryConfig(v, key)
+	if err != nil {
+		return nil, err
+	}
+	return f.Create(config)
+}
@@ -0,0 +1,153 @@
+package registry
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spelens-gud/logger"
+	"github.com/spf13/viper"
+)
+
+// newTestViper 读取 yaml 配置
+func newTestViper(t *testing.T, content string) *viper.Viper {
+	t.Helper()
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader(content)); err != nil {
+		t.Fatalf("读取配置失败: %v", err)
+	}
+	return v
+}
+
+// TestLoadRegistryConfig 测试按 type 解析注册中心配置和键的层级
+func TestLoadRegistryConfig(t *testing.T) {
+	v := newTestViper(t, `
+Registry:
+  type: "etcd"
+  name: "gate"
+  hosts: [ "127.0.0.1:2379" ]
+  leaseTTL: 10
+  projectPrefix: "/wsh/moba"
+  serverPrefix: "servers"
+  commonPrefix: "common"
+  masterPrefix: "master"
+  user:
+`)
+
+	config, err := LoadRegistryConfig(v, "Registry")
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if config.Etcd == nil || len(config.Etcd.Hosts) != 1 || config.Etcd.LeaseTTL != 10 {
+		t.Fatalf("etcd 配置解析错误: %+v", config.Etcd)
+	}
+	if config.Etcd.Key != "/wsh/moba/servers/gate" {
+		t.Errorf("注册键期望 /wsh/moba/servers/gate, 实际 %s", config.Etcd.Key)
+	}
+	if key := config.CommonKey(); key != "/wsh/moba/common" {
+		t.Errorf("公共配置键期望 /wsh/moba/common, 实际 %s", key)
+	}
+	if key := config.MasterKey("center"); key != "/wsh/moba/master/center" {
+		t.Errorf("选主键期望 /wsh/moba/master/center, 实际 %s", key)
+	}
+}
+
+// TestLoadRegistryConfig_Errors 测试配置错误时返回错误
+func TestLoadRegistryConfig_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "缺少配置段", content: "Other:\n  type: etcd\n"},
+		{name: "缺少类型", content: "Registry:\n  hosts: [ \"127.0.0.1:2379\" ]\n"},
+		{name: "未知类型", content: "Registry:\n  type: zookeeper\n"},
+		{name: "etcd 缺少地址", content: "Registry:\n  type: etcd\n  key: /services/gate\n"},
+		{name: "consul 缺少服务名", content: "Registry:\n  type: consul\n  address: 127.0.0.1:8500\n"},
+		{name: "内存租约为负数", content: "Registry:\n  type: memory\n  leaseTTL: -1\n"},
+		{name: "多注册中心缺少 backends", content: "Registry:\n  type: multi\n"},
+		{name: "多注册中心主注册中心不存在", content: "Registry:\n  type: multi\n  primary: etcd\n  backends:\n    - type: memory\n"},
+		{name: "多注册中心嵌套", content: "Registry:\n  type: multi\n  backends:\n    - type: multi\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadRegistryConfig(newTestViper(t, tt.content), "Registry"); err == nil {
+				t.Fatal("期望返回错误")
+			} else {
+				t.Log(err)
+			}
+		})
+	}
+}
+
+// TestEtcdConfig_Validate 测试 etcd 配置必须有地址和注册键
+func TestEtcdConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  EtcdConfig
+		wantErr bool
+	}{
+		{name: "完整配置", config: EtcdConfig{Hosts: []string{"127.0.0.1:2379"}, Key: "/services/gate"}},
+		{name: "缺少地址", config: EtcdConfig{Key: "/services/gate"}, wantErr: true},
+		{name: "缺少注册键", config: EtcdConfig{Hosts: []string{"127.0.0.1:2379"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("期望错误 %v, 实际 %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestCreateFromViper 测试按配置创建注册中心
+func TestCreateFromViper(t *testing.T) {
+	log, err := logger.NewLogger(logger.DefaultConfig())
+	if err != nil {
+		t.Fatalf("创建 logger 失败: %v", err)
+	}
+
+	v := newTestViper(t, "Registry:\n  type: memory\n  name: gate\n  projectPrefix: /wsh/moba\n  serverPrefix: servers\n")
+	reg, err := NewRegistryFactory(log).CreateFromViper(v, "Registry")
+	if err != nil {
+		t.Fatalf("创建失败: %v", err)
+	}
+	defer reg.Close()
+
+	memory, ok := reg.(*InMemoryRegistry)
+	if !ok {
+		t.Fatalf("期望内存注册中心, 实际 %T", reg)
+	}
+	if memory.key != "/wsh/moba/servers/gate" {
+		t.Errorf("注册键期望 /wsh/moba/servers/gate, 实际 %s", memory.key)
+	}
+
+	if _, err := NewRegistryFactory(log).CreateFromViper(newTestViper(t, "Registry:\n  type: etcd\n"), "Registry"); err == nil {
+		t.Error("配置错误时应返回错误")
+	}
+}
+
+// TestLoadRegistryConfig_Gate 测试 Gate 服务配置文件中的注册中心配置, 服务注册在 etcd 的 /wsh/moba/servers/gate 下
+func TestLoadRegistryConfig_Gate(t *testing.T) {
+	v := viper.New()
+	v.SetConfigFile("../../config/gate.yaml")
+	if err := v.ReadInConfig(); err != nil {
+		t.Fatalf("读取配置文件失败: %v", err)
+	}
+
+	config, err := LoadRegistryConfig(v, "Registry")
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if config.Etcd == nil || len(config.Etcd.Hosts) != 1 || config.Etcd.Hosts[0] != "192.168.6.54:2379" {
+		t.Fatalf("etcd 配置解析错误: %+v", config.Etcd)
+	}
+	if config.Etcd.Key != "/wsh/moba/servers/gate" {
+		t.Errorf("注册键期望 /wsh/moba/servers/gate, 实际 %s", config.Etcd.Key)
+	}
+	if key := config.CommonKey(); key != "/wsh/moba/common" {
+		t.Errorf("公共配置键期望 /wsh/moba/common, 实际 %s", key)
+	}
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/spelens-gud/logger"
+	"github.com/spelens-gud/trunk/internal/net/grpc"
+	"github.com/spelens-gud/trunk/internal/net/message"
+	"github.com/spelens-gud/trunk/internal/net/quic"
+	webSocket "github.com/spelens-gud/trunk/internal/net/websocket"
+	"github.com/spelens-gud/trunk/internal/registry"
+)
+
+// Drainable 可以优雅下线的服务端
+type Drainable interface {
+	// Drain 停止接受新连接, 已建立的连接不受影响
+	Drain()
+	// WaitIdle 等待已建立的连接全部关闭, ctx 结束时返回 ctx 的错误
+	WaitIdle(ctx context.Context) error
+	// Stop 关闭服务端和剩余的连接
+	Stop()
+}
+
+// broadcaster 可以通知已连接客户端的服务端
+type broadcaster interface {
+	BroadcastMessage(data []byte)
+}
+
+// 确保 WebSocket、QUIC、gRPC 服务端实现了 Drainable 接口
+var (
+	_ Drainable = (*webSocket.NetWsServer)(nil)
+	_ Drainable = (*quic.NetQuicServer)(nil)
+	_ Drainable = (*grpc.NetGrpcServer)(nil)
+)
+
+// ErrNothingToDrain 没有发布注册记录也没有加入服务端, 下线不会执行任何操作
+var ErrNothingToDrain = errors.New("没有发布注册记录也没有加入服务端, 跳过优雅下线")
+
+// Drainer 优雅下线协调器
+//
+// 下线顺序: 在注册中心标记为下线中 -> 等待服务发现同步 -> 服务端停止接受新连接 ->
+// 广播迁移通知 -> 等待会话结束或超时 -> 关闭服务端 -> 注销服务, 注册中心由调用方关闭
+type Drainer struct {
+	cnf          *DrainConfig
+	log          logger.ILogger
+	reg          registry.Registry      // 注册中心, 可为空
+	registration *registry.Registration // 注册记录, 可为空
+	published    bool                   // 是否已发布注册记录
+	servers      []Drainable            // 参与下线的服务端
+	notice       []byte                 // 广播给客户端的迁移通知
+	lock         sync.Mutex
+}
+
+// NewDrainer 创建优雅下线协调器, reg 或 registration 为空时跳过注册中心的步骤
+func NewDrainer(config *DrainConfig, reg registry.Registry, registration *registry.Registration, log logger.ILogger) (*Drainer, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("优雅下线配置错误: %w", err)
+	}
+
+	notice, err := message.EncodeMigrating("")
+	if err != nil {
+		return nil, fmt.Errorf("编码迁移通知失败: %w", err)
+	}
+
+	return &Drainer{
+		cnf:          config,
+		log:          log,
+		reg:          reg,
+		registration: registration,
+		notice:       notice,
+	}, nil
+}
+
+// Timeout 整个下线流程的最长时间, 用于设置 Drain 的 ctx
+func (d *Drainer) Timeout() time.Duration {
+	return d.cnf.GetTimeout()
+}
+
+// AddServer 添加参与下线的服务端, 在服务端启动后调用
+func (d *Drainer) AddServer(servers ...Drainable) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.servers = append(d.servers, servers...)
+}
+
+// SetNotice 设置下线时广播给客户端的消息, 默认为空原因的迁移通知
+func (d *Drainer) SetNotice(data []byte) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.notice = data
+}
+
+// Publish 以指定的生命周期状态发布注册记录, 启动时为 starting, 服务端启动后为 serving
+func (d *Drainer) Publish(state registry.InstanceState) {
+	if d.reg == nil || d.registration == nil {
+		return
+	}
+
+	d.lock.Lock()
+	d.registration.State = state
+	value := d.registration.Encode()
+	d.published = true
+	d.lock.Unlock()
+
+	d.reg.Publisher(value)
+	d.log.Infof("发布注册记录，实例: %s, 状态: %s", d.registration.ID, state)
+}
+
+// Drain 执行优雅下线, ctx 结束时跳过剩余的等待, 仍然关闭服务端并注销服务后返回 ctx 的错误;
+// 没有发布注册记录也没有加入服务端时返回 ErrNothingToDrain
+func (d *Drainer) Drain(ctx context.Context) error {
+	d.lock.Lock()
+	servers := slices.Clone(d.servers)
+	notice := d.notice
+	published := d.published
+	d.lock.Unlock()
+
+	if !published && len(servers) == 0 {
+		return ErrNothingToDrain
+	}
+
+	d.log.Infof("开始优雅下线，服务端数量: %d", len(servers))
+
+	// 标记为下线中, 服务发现方不再选择该实例
+	if published {
+		d.Publish(registry.StateDraining)
+		d.log.Infof("等待服务发现同步: %s", d.cnf.GetPropagationDelay())
+		sleepContext(ctx, d.cnf.GetPropagationDelay())
+	}
+
+	for _, s := range servers {
+		s.Drain()
+	}
+
+	// 通知已连接的客户端在会话结束后迁移到其他实例
+	for _, s := range servers {
+		if b, ok := s.(broadcaster); ok && len(notice) > 0 {
+			b.BroadcastMessage(notice)
+		}
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, d.cnf.GetDeadline())
+	for _, s := range servers {
+		if err := s.WaitIdle(waitCtx); err != nil {
+			d.log.Warnf("等待会话结束超时，关闭剩余连接: %v", err)
+			break
+		}
+	}
+	cancel()
+
+	for _, s := range servers {
+		s.Stop()
+	}
+
+	if d.reg != nil {
+		d.reg.Deregister()
+	}
+
+	d.log.Infof("优雅下线完成")
+	return ctx.Err()
+}
+
+// sleepContext 等待 duration 或 ctx 结束
+func sleepContext(ctx context.Context, duration time.Duration) {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
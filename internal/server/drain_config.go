@@ -0,0 +1,47 @@
+package server
+
+import (
+	"errors"
+	"time"
+)
+
+// errNegativeDrainTime 下线等待时间为负数
+var errNegativeDrainTime = errors.New("negative drain delay or deadline")
+
+// stopTimeout 等待会话结束后关闭服务端和注销服务的时间
+const stopTimeout = 10 * time.Second
+
+// DrainConfig 优雅下线配置
+type DrainConfig struct {
+	PropagationDelay int `yaml:"propagationDelay"` // 标记为下线中后等待服务发现同步的时间（秒），默认5秒
+	Deadline         int `yaml:"deadline"`         // 等待已连接的会话结束的最长时间（秒），默认60秒
+}
+
+// Validate 验证配置
+func (c *DrainConfig) Validate() error {
+	if c.PropagationDelay < 0 || c.Deadline < 0 {
+		return errNegativeDrainTime
+	}
+	return nil
+}
+
+// GetPropagationDelay 获取等待服务发现同步的时间，默认5秒
+func (c *DrainConfig) GetPropagationDelay() time.Duration {
+	if c.PropagationDelay <= 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(c.PropagationDelay) * time.Second
+}
+
+// GetDeadline 获取等待会话结束的最长时间，默认60秒
+func (c *DrainConfig) GetDeadline() time.Duration {
+	if c.Deadline <= 0 {
+		return 60 * time.Second
+	}
+	return time.Duration(c.Deadline) * time.Second
+}
+
+// GetTimeout 获取整个下线流程的最长时间
+func (c *DrainConfig) GetTimeout() time.Duration {
+	return c.GetPropagationDelay() + c.GetDeadline() + stopTimeout
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spelens-gud/assert"
+	"github.com/spelens-gud/logger"
+	"github.com/spelens-gud/trunk/internal/net/message"
+	"github.com/spelens-gud/trunk/internal/registry"
+)
+
+// fakeServer 记录下线步骤的服务端, idle 为空时会话一直不结束
+type fakeServer struct {
+	mu    sync.Mutex
+	steps []string
+	idle  chan struct{}
+}
+
+func (f *fakeServer) record(step string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.steps = append(f.steps, step)
+}
+
+func (f *fakeServer) Drain() { f.record("drain") }
+
+func (f *fakeServer) BroadcastMessage(data []byte) {
+	if _, ok := message.IsMigrating(data); ok {
+		f.record("notice")
+	}
+}
+
+func (f *fakeServer) WaitIdle(ctx context.Context) error {
+	select {
+	case <-f.idle:
+		f.record("idle")
+		return nil
+	case <-ctx.Done():
+		f.record("timeout")
+		return ctx.Err()
+	}
+}
+
+func (f *fakeServer) Stop() { f.record("stop") }
+
+// TestDrainer_Drain 测试下线顺序: 标记下线中、等待同步、停止接受连接、通知、等待会话、关闭、注销
+func TestDrainer_Drain(t *testing.T) {
+	log, err := logger.NewLogger(logger.DefaultConfig())
+	if err != nil {
+		t.Fatalf("创建 logger 失败: %v", err)
+	}
+	assert.SetLogger(log)
+
+	store := registry.NewMemoryStore()
+	factory := registry.NewRegistryFactory(log)
+	reg, _ := factory.CreateMemoryRegistry(&registry.MemoryConfig{Key: "/services/fight", Store: store})
+	defer reg.Close()
+	client, _ := factory.CreateMemoryRegistry(&registry.MemoryConfig{Store: store})
+	defer client.Close()
+
+	registration := registry.NewRegistration(registry.KindFight, "fight-1", "10.0.0.1", registry.Transport{Protocol: "quic", Port: 9000})
+	d, err := NewDrainer(&DrainConfig{PropagationDelay: 1, Deadline: 1}, reg, registration, log)
+	if err != nil {
+		t.Fatalf("创建失败: %v", err)
+	}
+
+	idle := &fakeServer{idle: make(chan struct{})}
+	busy := &fakeServer{}
+	d.AddServer(idle, busy)
+	d.Publish(registry.StateServing)
+
+	ctx := context.Background()
+	done := make(chan error, 1)
+	go func() { done <- d.Drain(ctx) }()
+
+	// 等待同步期间服务仍然注册, 状态为下线中
+	time.Sleep(300 * time.Millisecond)
+	instances, _ := client.Discover(ctx, "/services/fight")
+	if len(instances) != 1 || !instances[0].IsDraining() {
+		t.Fatalf("期望 1 个下线中的实例, 实际 %+v", instances)
+	}
+	close(idle.idle)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("下线失败: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("下线超时")
+	}
+
+	if want := []string{"drain", "notice", "idle", "stop"}; !reflect.DeepEqual(idle.steps, want) {
+		t.Errorf("期望 %v, 实际 %v", want, idle.steps)
+	}
+	if want := []string{"drain", "notice", "timeout", "stop"}; !reflect.DeepEqual(busy.steps, want) {
+		t.Errorf("会话未结束时期望 %v, 实际 %v", want, busy.steps)
+	}
+	if instances, _ := client.Discover(ctx, "/services/fight"); len(instances) != 0 {
+		t.Errorf("下线后应注销实例, 实际 %+v", instances)
+	}
+}
+
+// TestDrainer_NothingToDrain 测试没有发布注册记录也没有加入服务端时返回错误
+func TestDrainer_NothingToDrain(t *testing.T) {
+	log, err := logger.NewLogger(logger.DefaultConfig())
+	if err != nil {
+		t.Fatalf("创建 logger 失败: %v", err)
+	}
+
+	d, err := NewDrainer(&DrainConfig{}, nil, nil, log)
+	if err != nil {
+		t.Fatalf("创建失败: %v", err)
+	}
+	if err := d.Drain(context.Background()); !errors.Is(err, ErrNothingToDrain) {
+		t.Errorf("期望 ErrNothingToDrain, 实际 %v", err)
+	}
+
+	d.AddServer(&fakeServer{idle: closedChan()})
+	if err := d.Drain(context.Background()); err != nil {
+		t.Errorf("加入服务端后下线失败: %v", err)
+	}
+}
+
+// closedChan 已关闭的通道, 会话立即结束
+func closedChan() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}